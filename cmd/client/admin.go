@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const adminUsage = `usage: client admin [-addr host:port] [-reason text] [-fallback] <command> [args]
+
+gateway (server) commands:
+  usage [tenant]       tenant별 사용량과 한도
+  streams              진행 중인 업로드 목록
+  cancel-stream <id>   업로드 취소
+
+transcoder (internal) commands:
+  jobs                 변환 작업 목록
+  watch [id]           변환 진행률 실시간 표시 (id를 지정하면 해당 작업이 끝날 때까지)
+  retry <id>           원본이 보관된 작업의 실패한 화질만 재변환 (-fallback: H.264/AAC 호환 설정)
+  cancel-job <id>      변환 작업 취소
+  pause                작업 큐 일시정지 (진행 중인 변환은 계속)
+  resume               작업 큐 재개
+  queue                작업 큐 상태
+
+environment:
+  ADMIN_ADDR       관리 API 주소 (-addr로 덮어쓰기)
+  ADMIN_API_KEY    운영자 API 키
+  ADMIN_TLS_*      TLS 설정 (ENABLED, CA_FILE, CERT_FILE, KEY_FILE, SERVER_NAME)
+`
+
+// runAdmin은 관리 API를 호출하는 admin 서브커맨드를 실행합니다.
+func runAdmin(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, adminUsage) }
+	addr := fs.String("addr", os.Getenv("ADMIN_ADDR"), "admin API address")
+	reason := fs.String("reason", "", "reason recorded in the audit log when cancelling")
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	fallback := fs.Bool("fallback", false, "retry failed renditions with the H.264/AAC fallback preset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing command")
+	}
+	if *addr == "" {
+		return fmt.Errorf("admin address not set (use -addr or ADMIN_ADDR)")
+	}
+
+	conn, err := dialAdmin(*addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	gateway := pb.NewAdminServiceClient(conn)
+	transcoder := pb.NewTranscoderAdminServiceClient(conn)
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+
+	switch cmd {
+	case "usage":
+		req := &pb.TenantUsageRequest{}
+		if len(rest) > 0 {
+			req.Tenant = rest[0]
+		}
+		resp, err := gateway.GetTenantUsage(ctx, req)
+		if err != nil {
+			return err
+		}
+		printUsage(resp)
+	case "streams":
+		resp, err := gateway.ListStreams(ctx, &pb.ListStreamsRequest{})
+		if err != nil {
+			return err
+		}
+		printStreams(resp)
+	case "cancel-stream", "cancel-job":
+		if len(rest) != 1 {
+			return fmt.Errorf("%s requires an id", cmd)
+		}
+		req := &pb.CancelRequest{Id: rest[0], Reason: *reason}
+		var resp *pb.CancelResponse
+		if cmd == "cancel-stream" {
+			resp, err = gateway.CancelStream(ctx, req)
+		} else {
+			resp, err = transcoder.CancelJob(ctx, req)
+		}
+		if err != nil {
+			return err
+		}
+		if !resp.Cancelled {
+			return fmt.Errorf("%s not found", rest[0])
+		}
+		fmt.Printf("cancelled %s\n", rest[0])
+	case "jobs":
+		resp, err := transcoder.ListJobs(ctx, &pb.ListJobsRequest{})
+		if err != nil {
+			return err
+		}
+		printJobs(resp)
+	case "retry":
+		if len(rest) != 1 {
+			return fmt.Errorf("retry requires an id")
+		}
+		resp, err := transcoder.RetryJob(ctx, &pb.RetryJobRequest{Id: rest[0], Fallback: *fallback})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("retrying %s: %s\n", rest[0], strings.Join(resp.Qualities, ","))
+	case "watch":
+		req := &pb.WatchJobsRequest{}
+		if len(rest) > 0 {
+			req.Id = rest[0]
+		}
+		// 스트림은 -timeout 없이 Ctrl+C까지 유지
+		watchCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watchJobs(watchCtx, transcoder, req)
+	case "pause", "resume", "queue":
+		var st *pb.QueueStatus
+		switch cmd {
+		case "pause":
+			st, err = transcoder.PauseQueue(ctx, &pb.QueueRequest{})
+		case "resume":
+			st, err = transcoder.ResumeQueue(ctx, &pb.QueueRequest{})
+		default:
+			st, err = transcoder.GetQueueStatus(ctx, &pb.QueueRequest{})
+		}
+		if err != nil {
+			return err
+		}
+		printQueue(st)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func dialAdmin(addr string) (*grpc.ClientConn, error) {
+	transportCreds := insecure.NewCredentials()
+	tlsCfg, err := tlsconfig.ClientFromEnv("ADMIN_TLS_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %v", err)
+	}
+	if tlsCfg != nil {
+		transportCreds = credentials.NewTLS(tlsCfg)
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(transportCreds)}
+	if key := os.Getenv("ADMIN_API_KEY"); key != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(auth.APIKey(key)))
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect admin API %s: %v", addr, err)
+	}
+	return conn, nil
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printUsage(resp *pb.TenantUsageResponse) {
+	w := newTable()
+	fmt.Fprintln(w, "TENANT\tACTIVE\tBYTES TODAY\tREQ/MIN\tTOTAL BYTES\tUPLOADS\tREJECTED")
+	for _, t := range resp.Tenants {
+		l := t.Limits
+		fmt.Fprintf(w, "%s\t%d/%s\t%s/%s\t%d/%s\t%s\t%d\t%d\n", t.Tenant,
+			t.ActiveStreams, limitString(int64(l.GetMaxConcurrentStreams()), false),
+			formatBytes(t.BytesToday), limitString(l.GetMaxBytesPerDay(), true),
+			t.RequestsLastMinute, limitString(int64(l.GetRequestsPerMinute()), false),
+			formatBytes(t.TotalBytes), t.TotalUploads, t.Rejected)
+	}
+	w.Flush()
+}
+
+func printStreams(resp *pb.ListStreamsResponse) {
+	w := newTable()
+	fmt.Fprintln(w, "ID\tTENANT\tBACKEND\tFORMAT\tCHUNKS\tBYTES\tTHROUGHPUT\tAGE\tREQUEST ID")
+	for _, st := range resp.Streams {
+		backend := st.Backend
+		if st.Spooled {
+			backend = "(spool)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s/s\t%s\t%s\n", st.Id, st.Tenant, backend, st.Format,
+			st.Chunks, formatBytes(st.Bytes), formatBytes(int64(st.ThroughputBps)), formatAge(st.AgeSeconds), st.RequestId)
+	}
+	w.Flush()
+}
+
+func printJobs(resp *pb.ListJobsResponse) {
+	w := newTable()
+	fmt.Fprintln(w, "ID\tSTATE\tFORMAT\tBYTES\tTHROUGHPUT\tAGE\tCURRENT\tPROGRESS\tCONVERTED\tREQUEST ID")
+	for _, job := range resp.Jobs {
+		state := job.State
+		if job.Resumed {
+			state += " (resumed)"
+		}
+		throughput := "-"
+		if job.ThroughputBps > 0 {
+			throughput = formatBytes(int64(job.ThroughputBps)) + "/s"
+		}
+		progress := "-"
+		switch job.State {
+		case "converting":
+			progress = fmt.Sprintf("%.0f%% (total %.0f%%)", job.Progress, job.OverallProgress)
+		case "live":
+			progress = fmt.Sprintf("%.0ffps %.2fx", job.Fps, job.Speed)
+		case "retained":
+			progress = fmt.Sprintf("failed %s, until %s", strings.Join(job.Failed, ","), job.RetainedUntil)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", job.Id, state, job.Format, formatBytes(job.Bytes),
+			throughput, formatAge(job.AgeSeconds), dash(job.Current), progress, dash(strings.Join(job.Converted, ",")), job.RequestId)
+	}
+	w.Flush()
+}
+
+func watchJobs(ctx context.Context, transcoder pb.TranscoderAdminServiceClient, req *pb.WatchJobsRequest) error {
+	stream, err := transcoder.WatchJobs(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		job, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line := fmt.Sprintf("%s %s %-10s overall %5.1f%%", time.Now().Format("15:04:05"), job.Id, job.State, job.OverallProgress)
+		if job.Current != "" {
+			line += fmt.Sprintf("  %s %5.1f%% %.0ffps %.2fx", job.Current, job.Progress, job.Fps, job.Speed)
+		} else if job.State == "live" {
+			line += fmt.Sprintf("  %.0ffps %.2fx", job.Fps, job.Speed)
+		}
+		fmt.Println(line)
+	}
+}
+
+func printQueue(st *pb.QueueStatus) {
+	capacity := "unlimited"
+	if st.Capacity > 0 {
+		capacity = fmt.Sprint(st.Capacity)
+	}
+	fmt.Printf("paused: %v\nrunning: %d\nqueued: %d\ncapacity: %s\n", st.Paused, st.Running, st.Queued, capacity)
+}
+
+func limitString(v int64, bytes bool) string {
+	if v <= 0 {
+		return "-"
+	}
+	if bytes {
+		return formatBytes(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatAge(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).Round(time.Second).String()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
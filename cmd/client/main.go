@@ -4,18 +4,52 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/client/streamer"
+	"github.com/ket0825/grpc-streaming/internal/lifecycle"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/metrics"
+	"github.com/ket0825/grpc-streaming/internal/ops"
+	"github.com/ket0825/grpc-streaming/internal/tlsconfig"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
-func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL string) error {
+// continuousStreamVideo는 stopCtx가 취소될 때까지 영상을 받아 전송합니다.
+// stopCtx가 취소되면 새 데이터 수신을 멈추고 스트림을 정상 종료하여 서버 응답을 기다립니다.
+// ctx가 취소되면 스트림 자체가 중단됩니다.
+func continuousStreamVideo(ctx, stopCtx context.Context, conn *grpc.ClientConn, videoURL string, flowCfg streamer.FlowConfig) (err error) {
+	// 업로드 전체를 하나의 span으로 기록. gRPC 호출 시 trace context가 server, internal로 전파됨
+	ctx, span := tracing.Start(ctx, "upload", trace.WithAttributes(attribute.String("video.url", videoURL)))
+	defer func() { tracing.End(span, err) }()
+
+	// 업로드마다 요청 ID를 발급하여 server, internal, ffmpeg 로그까지 같은 ID로 추적
+	ctx = logging.OutgoingContext(ctx, logging.NewRequestID())
+	// CALLBACK_URL이 있으면 변환 이벤트(job.accepted, rendition.completed 등)를 해당 URL로 받음
+	if callbackURL := os.Getenv("CALLBACK_URL"); callbackURL != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, webhook.CallbackURLHeader, callbackURL)
+	}
+	// LIVE=true면 internal 서버가 EOF를 기다리지 않고 받는 즉시 HLS로 변환 (ts, flv, mkv, webm)
+	if os.Getenv("LIVE") == "true" {
+		ctx = metadata.AppendToOutgoingContext(ctx, mediatype.LiveHeader, "true")
+	}
+	logger := logging.FromContext(ctx)
+	logger.Info("starting upload", "url", videoURL)
+
 	// gRPC 클라이언트 설정
 	grpcClient := pb.NewVideoStreamingServiceClient(conn)
 
@@ -27,6 +61,10 @@ func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL
 	// 시퀀스 번호 관리
 	sequence := 0
 
+	// 업로드 속도 제한 및 청크 크기 조정
+	flow := streamer.NewFlowController(flowCfg)
+	buffer := flow.NewBuffer()
+
 	// gRPC 스트림 시작
 	stream, err := grpcClient.StreamVideo(ctx)
 	if err != nil {
@@ -39,12 +77,38 @@ func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-stopCtx.Done():
+			// 종료 신호: 지금까지 보낸 데이터로 스트림을 마무리
+			logger.Info("stopping upload, waiting for server response", "chunks", sequence)
+			response, err := stream.CloseAndRecv()
+			if err != nil {
+				return fmt.Errorf("failed to close stream: %w", err)
+			}
+			logger.Info("server response", "success", response.Success, "job_id", response.JobId, "message", response.Message)
+			if src := response.Source; src != nil && len(src.Video) > 0 {
+				v := src.Video[0]
+				logger.Info("source metadata", "container", src.Container, "duration", src.DurationSeconds,
+					"video_codec", v.Codec, "width", v.Width, "height", v.Height, "fps", v.FrameRate, "audio_streams", len(src.Audio))
+			}
+			if t := response.Thumbnails; t != nil {
+				logger.Info("thumbnails", "poster", t.Poster, "images", len(t.Images), "sprite", t.Sprite, "sprite_vtt", t.SpriteVtt)
+			}
+			if live := response.Live; live != nil {
+				logger.Info("live output", "playlist", live.MasterPlaylist, "dvr_playlist", live.DvrPlaylist,
+					"vod_playlist", live.VodPlaylist, "segments", live.Segments, "duration", live.DurationSeconds)
+			}
+			if response.RetainedUntil != "" {
+				logger.Warn("some renditions failed, source retained for retry", "until", response.RetainedUntil)
+			}
+			return nil
 		default:
 			// HTTP 요청 생성
-			req, err := http.NewRequestWithContext(ctx, "GET", videoURL, nil)
+			req, err := http.NewRequestWithContext(stopCtx, "GET", videoURL, nil)
 			if err != nil {
 				return fmt.Errorf("failed to create request: %w", err)
 			}
+			_, fetchSpan := tracing.Start(ctx, "fetch", trace.WithAttributes(attribute.String("http.url", videoURL)))
+			var fetchErr error
 
 			// Range 헤더를 사용하여 부분 요청 가능
 			// req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
@@ -52,14 +116,12 @@ func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL
 			// HTTP 응답 받기
 			resp, err := httpClient.Do(req)
 			if err != nil {
-				log.Printf("Error getting response: %v, retrying...", err)
+				tracing.End(fetchSpan, err)
+				logger.Warn("fetch failed, retrying", "error", err)
 				time.Sleep(time.Second) // 에러 시 잠시 대기
 				continue
 			}
 
-			// 버퍼 설정
-			buffer := make([]byte, 32*1024) // 32KB 버퍼
-
 			// 헤더 정보 변환
 			headers := make(map[string]string)
 			for k, v := range resp.Header {
@@ -70,13 +132,13 @@ func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL
 
 			// 청크 단위로 읽어서 바로 전송
 			for {
-				n, err := resp.Body.Read(buffer)
+				n, err := flow.ReadChunk(resp.Body, buffer)
 				if err == io.EOF {
 					break // 현재 응답 데이터를 모두 읽음
 				}
 				if err != nil {
-					resp.Body.Close()
-					log.Printf("Error reading chunk: %v, reconnecting...", err)
+					fetchErr = err
+					logger.Warn("read failed, reconnecting", "error", err)
 					break // 다음 요청으로 넘어감
 				}
 
@@ -88,16 +150,22 @@ func continuousStreamVideo(ctx context.Context, conn *grpc.ClientConn, videoURL
 					Sequence:    int32(sequence),
 				}
 
-				if err := stream.Send(chunk); err != nil {
+				err = flow.Send(ctx, n, func() error {
+					return stream.Send(chunk)
+				})
+				if err != nil {
 					resp.Body.Close()
+					tracing.End(fetchSpan, err)
 					return fmt.Errorf("failed to send chunk: %w", err)
 				}
 
 				sequence++
-				log.Printf("Sent chunk %d, size: %d bytes", sequence, n)
+				logger.Debug("sent chunk", "sequence", sequence, "bytes", n)
 			}
 
 			resp.Body.Close()
+			fetchSpan.SetAttributes(attribute.Int("chunks.sent", sequence))
+			tracing.End(fetchSpan, fetchErr)
 		}
 	}
 }
@@ -108,6 +176,15 @@ func main() {
 	// 	log.Fatal("Error loading .env file")
 	// }
 
+	// client admin <command>: 관리 API 호출 (admin.go)
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "admin:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	videoURL := os.Getenv("VIDEO_URL")
 	serverHost := os.Getenv("SERVER_HOST")
 	serverPort := os.Getenv("SERVER_PORT")
@@ -116,30 +193,89 @@ func main() {
 
 	maxMsgSize := 10 * 1024 * 1024 // 10MB (서버와 동일하게)
 
-	conn, err := grpc.Dial(
-		grpcAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	// LOG_LEVEL, LOG_FORMAT으로 로그 레벨과 형식 설정
+	logging.Setup("grpc-client")
+
+	// OpenTelemetry tracing (OTEL_EXPORTER_OTLP_ENDPOINT가 있으면 span 전송)
+	shutdownTracing, err := tracing.Setup(context.Background(), "grpc-client")
+	if err != nil {
+		logging.Fatal("failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// TLS_CA_FILE 또는 TLS_ENABLED=true이면 TLS로 연결 (TLS_CERT_FILE, TLS_KEY_FILE: mTLS 클라이언트 인증서)
+	transportCreds := insecure.NewCredentials()
+	tlsCfg, err := tlsconfig.ClientFromEnv("TLS_")
+	if err != nil {
+		logging.Fatal("failed to configure TLS", "error", err)
+	}
+	if tlsCfg != nil {
+		transportCreds = credentials.NewTLS(tlsCfg)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+		tracing.DialOption(),
 		grpc.WithBlock(),
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallSendMsgSize(maxMsgSize),
 			grpc.MaxCallRecvMsgSize(maxMsgSize),
 		),
-	)
+	}
+	// API_KEY, AUTH_TOKEN, AUTH_TOKEN_FILE 중 하나로 업로드 인증
+	if creds := auth.CredentialsFromEnv(); creds != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(creds))
+	}
+
+	conn, err := grpc.Dial(grpcAddr, dialOpts...)
 	if err != nil {
-		log.Fatalf("Failed to connect server: %v", err)
+		logging.Fatal("failed to connect server", "addr", grpcAddr, "error", err)
 	}
 	defer conn.Close()
 
-	// 컨텍스트 설정 (필요한 경우 타임아웃 설정)
+	// 운영용 HTTP 서버 (OPS_ADDR이 있으면 /metrics로 업로드 처리량 노출)
+	opsServer := ops.New(os.Getenv("OPS_ADDR"))
+	opsServer.Handle("/metrics", metrics.Handler())
+	opsServer.Start()
+	opsServer.SetReady(true)
+	defer opsServer.Shutdown(context.Background())
+
+	// 컨텍스트 설정 (UPLOAD_TIMEOUT이 있으면 deadline으로 설정되어 server, internal까지 전파됨)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if v := os.Getenv("UPLOAD_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			logging.Fatal("invalid UPLOAD_TIMEOUT", "value", v, "error", err)
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// SIGTERM을 받으면 업로드를 마무리하고, drain timeout이 지나면 강제로 취소
+	stopCtx, stop := lifecycle.SignalContext(ctx)
+	defer stop()
+	drainTimeout := lifecycle.ConfigFromEnv().DrainTimeout
+	go func() {
+		<-stopCtx.Done()
+		if ctx.Err() == nil {
+			slog.Info("shutdown signal received, finishing upload", "timeout", drainTimeout)
+			time.AfterFunc(drainTimeout, cancel)
+		}
+	}()
+
+	// 업로드 속도 제한 및 청크 크기 설정
+	flowCfg := streamer.FlowConfigFromEnv(maxMsgSize)
+	slog.Info("upload flow config", "rate_limit", flowCfg.RateLimit, "adaptive", flowCfg.Adaptive,
+		"min_chunk", flowCfg.MinChunk, "max_chunk", flowCfg.MaxChunk)
 
 	// 스트리밍 시작
-	if err := continuousStreamVideo(ctx, conn, videoURL); err != nil {
+	if err := continuousStreamVideo(ctx, stopCtx, conn, videoURL, flowCfg); err != nil {
 		if err == context.Canceled {
-			log.Println("Streaming was canceled")
+			slog.Warn("streaming was canceled")
+		} else if err == context.DeadlineExceeded {
+			slog.Warn("streaming deadline exceeded")
 		} else {
-			log.Fatalf("Streaming failed: %v", err)
+			logging.Fatal("streaming failed", "error", err)
 		}
 	}
 
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errCancelledByAdmin은 관리 API로 작업을 취소할 때 context cause로 사용됩니다.
+var errCancelledByAdmin = errors.New("job cancelled by operator")
+
+// cancelStatus는 취소된 작업의 gRPC 오류를 반환합니다. 관리 API로 취소된 경우 Aborted입니다.
+func cancelStatus(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), errCancelledByAdmin) {
+		return status.Error(codes.Aborted, errCancelledByAdmin.Error())
+	}
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// adminServer는 internal 서버의 관리 API입니다.
+type adminServer struct {
+	pb.UnimplementedTranscoderAdminServiceServer
+	jobs *server
+}
+
+func (a *adminServer) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	s := a.jobs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &pb.ListJobsResponse{}
+	for id, info := range s.activeProcessings {
+		resp.Jobs = append(resp.Jobs, jobStatus(id, info))
+	}
+	// 재변환을 기다리는 보관 작업
+	resp.Jobs = append(resp.Jobs, s.retainedJobs()...)
+	sort.Slice(resp.Jobs, func(i, j int) bool { return resp.Jobs[i].Id < resp.Jobs[j].Id })
+	return resp, nil
+}
+
+// WatchJobs는 현재 작업 상태를 먼저 보내고, 이후 상태와 진행률이 바뀔 때마다 전송합니다.
+// id를 지정하면 해당 작업만 전송하고, 작업이 끝나면(finished) 스트림을 종료합니다.
+func (a *adminServer) WatchJobs(req *pb.WatchJobsRequest, stream pb.TranscoderAdminService_WatchJobsServer) error {
+	s := a.jobs
+	sub := s.watchers.subscribe(req.Id)
+	defer s.watchers.unsubscribe(sub)
+
+	snapshot, _ := a.ListJobs(stream.Context(), &pb.ListJobsRequest{})
+	found := false
+	for _, job := range snapshot.Jobs {
+		if req.Id != "" && job.Id != req.Id {
+			continue
+		}
+		found = true
+		if err := stream.Send(job); err != nil {
+			return err
+		}
+	}
+	if req.Id != "" && !found {
+		return status.Errorf(codes.NotFound, "job %s not found", req.Id)
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case job, ok := <-sub.updates:
+			if !ok {
+				if sub.lagged {
+					return status.Error(codes.ResourceExhausted, "watcher fell behind job updates, reconnect to resume")
+				}
+				return stream.Send(sub.final)
+			}
+			if err := stream.Send(job); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (a *adminServer) CancelJob(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	s := a.jobs
+	s.mu.Lock()
+	var cancel func()
+	if info := s.activeProcessings[req.Id]; info != nil {
+		cancel = info.cancel
+	}
+	s.mu.Unlock()
+	if cancel == nil {
+		return &pb.CancelResponse{}, nil
+	}
+	logging.FromContext(ctx).Warn("cancelling job", "session_id", req.Id, "reason", req.Reason)
+	cancel()
+	return &pb.CancelResponse{Cancelled: true}, nil
+}
+
+// RetryJob은 부분 실패로 원본이 보관된 작업의 실패한 화질만 백그라운드에서 다시 변환합니다.
+// 진행 상황은 WatchJobs로, 결과는 webhook으로 확인합니다.
+func (a *adminServer) RetryJob(ctx context.Context, req *pb.RetryJobRequest) (*pb.RetryJobResponse, error) {
+	task, pending, err := a.jobs.claimRetained(req.Id, req.Fallback)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("retrying job", "session_id", req.Id, "qualities", pending, "fallback", req.Fallback)
+	if err := a.jobs.enqueueRetry(ctx, task); err != nil {
+		return nil, err
+	}
+	return &pb.RetryJobResponse{Qualities: pending}, nil
+}
+
+func (a *adminServer) PauseQueue(ctx context.Context, req *pb.QueueRequest) (*pb.QueueStatus, error) {
+	a.jobs.setPaused(true)
+	logging.FromContext(ctx).Warn("transcoding queue paused")
+	return a.jobs.queueStatus(), nil
+}
+
+func (a *adminServer) ResumeQueue(ctx context.Context, req *pb.QueueRequest) (*pb.QueueStatus, error) {
+	a.jobs.setPaused(false)
+	logging.FromContext(ctx).Info("transcoding queue resumed")
+	return a.jobs.queueStatus(), nil
+}
+
+func (a *adminServer) GetQueueStatus(ctx context.Context, req *pb.QueueRequest) (*pb.QueueStatus, error) {
+	return a.jobs.queueStatus(), nil
+}
+
+// state는 작업 상태를 관리 API에 표시할 문자열로 반환합니다.
+func (info *ProcessingInfo) state() string {
+	switch {
+	case info.live:
+		return "live"
+	case !info.converting:
+		return "receiving"
+	case info.queued:
+		return "queued"
+	default:
+		return "converting"
+	}
+}
+
+// setPaused는 작업 큐를 멈추거나 재개합니다. 멈춘 동안 진행 중인 변환은 계속되고 새 변환만 대기합니다.
+func (s *server) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == paused {
+		return
+	}
+	s.paused = paused
+	if paused {
+		s.resumeCh = make(chan struct{})
+	} else {
+		close(s.resumeCh)
+	}
+	slog.Info("queue state changed", "paused", paused)
+}
+
+func (s *server) queueStatus() *pb.QueueStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &pb.QueueStatus{Paused: s.paused, Capacity: int32(cap(s.workers))}
+	for _, info := range s.activeProcessings {
+		switch info.state() {
+		case "queued":
+			st.Queued++
+		case "converting":
+			st.Running++
+		}
+	}
+	return st
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"github.com/ket0825/grpc-streaming/internal/probe"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// audioConfig는 음성 트랙 선택, 음량 정규화, 음성 전용 화질 설정입니다.
+type audioConfig struct {
+	Tracks     string         // all: 모든 음성 트랙, default: 기본(없으면 첫 번째) 트랙만
+	Loudnorm   bool           // EBU R128 2-pass 음량 정규화
+	Target     loudnessTarget // 정규화 목표
+	Renditions []AudioRendition
+}
+
+// loudnessTarget은 loudnorm 목표 값입니다 (integrated LUFS, true peak dBTP, loudness range LU).
+type loudnessTarget struct {
+	I   float64
+	TP  float64
+	LRA float64
+}
+
+// AudioRendition은 음성 전용 출력 하나입니다. HLS에서는 이름이 audio group ID가 됩니다.
+type AudioRendition struct {
+	Name      string // aac_128k
+	Codec     string // aac, opus
+	Bitrate   string
+	Container string // mp4 (.m4a), webm
+	Directory string // OUTPUT_DIR 아래 출력 디렉토리 (audio/<이름>)
+
+	encoder string // 시작 시 ffmpeg -encoders 결과로 결정
+}
+
+// audioConfigFromEnv는 AUDIO_TRACKS(default 기본, all), LOUDNORM, LOUDNORM_I, LOUDNORM_TP, LOUDNORM_LRA,
+// AUDIO_RENDITIONS(예: aac:128k,aac:64k,opus:96k)를 읽습니다.
+func audioConfigFromEnv() (audioConfig, error) {
+	cfg := audioConfig{
+		Tracks:   os.Getenv("AUDIO_TRACKS"),
+		Loudnorm: os.Getenv("LOUDNORM") == "true",
+		Target: loudnessTarget{
+			I:   env.Float("LOUDNORM_I", -23),
+			TP:  env.Float("LOUDNORM_TP", -1),
+			LRA: env.Float("LOUDNORM_LRA", 7),
+		},
+	}
+	switch cfg.Tracks {
+	case "":
+		cfg.Tracks = "default"
+	case "all", "default":
+	default:
+		return cfg, fmt.Errorf("unknown AUDIO_TRACKS %q (all, default)", cfg.Tracks)
+	}
+	renditions, err := parseAudioRenditions(os.Getenv("AUDIO_RENDITIONS"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Renditions = renditions
+	return cfg, nil
+}
+
+// parseAudioRenditions는 "코덱:비트레이트" 목록을 읽습니다.
+func parseAudioRenditions(s string) ([]AudioRendition, error) {
+	var renditions []AudioRendition
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		codec, bitrate, ok := strings.Cut(entry, ":")
+		codec = strings.ToLower(codec)
+		if _, valid := parseBitrate(bitrate); !ok || !valid {
+			return nil, fmt.Errorf("invalid AUDIO_RENDITIONS entry %q (codec:bitrate)", entry)
+		}
+		if _, ok := audioEncoders[codec]; !ok {
+			return nil, fmt.Errorf("unknown audio codec %q in AUDIO_RENDITIONS", codec)
+		}
+		r := AudioRendition{Name: codec + "_" + bitrate, Codec: codec, Bitrate: bitrate, Container: "mp4"}
+		if codec == "opus" {
+			r.Container = "webm"
+		}
+		r.Directory = filepath.Join("audio", r.Name)
+		if seen[r.Name] {
+			return nil, fmt.Errorf("duplicate AUDIO_RENDITIONS entry %q", entry)
+		}
+		seen[r.Name] = true
+		renditions = append(renditions, r)
+	}
+	return renditions, nil
+}
+
+// resolveAudioRenditions는 음성 전용 화질에 사용할 encoder를 고릅니다 (resolveLadder와 같은 규칙).
+func resolveAudioRenditions(renditions []AudioRendition, encoders map[string]bool, skipUnsupported bool) ([]AudioRendition, error) {
+	var resolved []AudioRendition
+	var unsupported []string
+	for _, r := range renditions {
+		r.encoder = pickEncoder(audioEncoders[r.Codec], encoders)
+		if r.encoder == "" {
+			unsupported = append(unsupported, fmt.Sprintf("%s (audio codec %s needs one of %v)", r.Name, r.Codec, audioEncoders[r.Codec]))
+			continue
+		}
+		resolved = append(resolved, r)
+	}
+	if len(unsupported) > 0 {
+		if !skipUnsupported {
+			return nil, fmt.Errorf("ffmpeg does not support audio renditions: %s", strings.Join(unsupported, "; "))
+		}
+		slog.Warn("skipping audio renditions unsupported by ffmpeg", "renditions", unsupported)
+	}
+	return resolved, nil
+}
+
+// extension은 출력 파일 확장자입니다.
+func (r AudioRendition) extension() string {
+	if r.Container == "mp4" {
+		return "m4a"
+	}
+	return r.Container
+}
+
+// audioTrack은 출력에 포함할 원본 음성 트랙입니다.
+type audioTrack struct {
+	Index    int                  `json:"index"` // 원본 stream index
+	Label    string               `json:"label"` // 파일 이름에 쓰는 구분자 (언어 코드, 없으면 a1, a2, ...)
+	Language string               `json:"language,omitempty"`
+	Title    string               `json:"title,omitempty"`
+	Default  bool                 `json:"default"`
+	Loudness *loudnessMeasurement `json:"loudness,omitempty"` // 1차 분석 결과 (정규화하지 않으면 nil)
+}
+
+// selectAudioTracks는 AUDIO_TRACKS 설정에 따라 출력에 포함할 음성 트랙을 고릅니다.
+// 같은 언어의 트랙이 여럿이면 두 번째부터 label에 번호를 붙입니다 (eng, eng2).
+func selectAudioTracks(streams []probe.AudioStream, mode string) []audioTrack {
+	if mode == "default" && len(streams) > 0 {
+		chosen := streams[0]
+		for _, a := range streams {
+			if a.Default {
+				chosen = a
+				break
+			}
+		}
+		streams = []probe.AudioStream{chosen}
+	}
+	var tracks []audioTrack
+	counts := make(map[string]int)
+	for i, a := range streams {
+		t := audioTrack{Index: a.Index, Language: a.Language, Title: a.Title, Default: a.Default}
+		if t.Language == "und" {
+			t.Language = ""
+		}
+		t.Label = fmt.Sprintf("a%d", i+1)
+		if t.Language != "" {
+			counts[t.Language]++
+			t.Label = t.Language
+			if n := counts[t.Language]; n > 1 {
+				t.Label += strconv.Itoa(n)
+			}
+		}
+		tracks = append(tracks, t)
+	}
+	// 기본 트랙 표시가 없으면 첫 번째 트랙을 기본으로
+	hasDefault := false
+	for _, t := range tracks {
+		hasDefault = hasDefault || t.Default
+	}
+	if !hasDefault && len(tracks) > 0 {
+		tracks[0].Default = true
+	}
+	return tracks
+}
+
+// loudnessMeasurement는 loudnorm 1차 분석 결과입니다 (ffmpeg print_format=json 출력).
+type loudnessMeasurement struct {
+	InputI       float64 `json:"input_i"`
+	InputTP      float64 `json:"input_tp"`
+	InputLRA     float64 `json:"input_lra"`
+	InputThresh  float64 `json:"input_thresh"`
+	TargetOffset float64 `json:"target_offset"`
+}
+
+// parseLoudnorm은 ffmpeg 출력에서 마지막 loudnorm JSON 블록을 읽습니다. ffmpeg는 값을 문자열로 출력합니다.
+func parseLoudnorm(output string) (*loudnessMeasurement, error) {
+	end := strings.LastIndex(output, "}")
+	start := strings.LastIndex(output[:max(end, 0)], "{")
+	if start < 0 || end < 0 {
+		return nil, errors.New("loudnorm output not found")
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(output[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid loudnorm output: %v", err)
+	}
+	var m loudnessMeasurement
+	for key, dst := range map[string]*float64{
+		"input_i": &m.InputI, "input_tp": &m.InputTP, "input_lra": &m.InputLRA,
+		"input_thresh": &m.InputThresh, "target_offset": &m.TargetOffset,
+	} {
+		v, err := strconv.ParseFloat(raw[key], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loudnorm %s %q", key, raw[key])
+		}
+		*dst = v
+	}
+	return &m, nil
+}
+
+// filter는 분석 결과로 2차(linear) loudnorm filter를 만듭니다.
+// 무음 등으로 측정값이 유한하지 않으면 정규화하지 않도록 빈 문자열을 반환합니다.
+func (m *loudnessMeasurement) filter(target loudnessTarget) string {
+	if m == nil {
+		return ""
+	}
+	for _, v := range []float64{m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset} {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f:linear=true",
+		target.I, target.TP, target.LRA, m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+}
+
+// MarshalJSON은 무음 트랙의 -inf 측정값을 null로 씁니다 (JSON은 무한대를 표현할 수 없음).
+func (m loudnessMeasurement) MarshalJSON() ([]byte, error) {
+	finite := func(v float64) *float64 {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+		return &v
+	}
+	return json.Marshal(struct {
+		InputI       *float64 `json:"input_i"`
+		InputTP      *float64 `json:"input_tp"`
+		InputLRA     *float64 `json:"input_lra"`
+		InputThresh  *float64 `json:"input_thresh"`
+		TargetOffset *float64 `json:"target_offset"`
+	}{finite(m.InputI), finite(m.InputTP), finite(m.InputLRA), finite(m.InputThresh), finite(m.TargetOffset)})
+}
+
+// audioTrackArgs는 영상 화질 변환에서 영상 스트림과 음성 트랙을 지정하는 ffmpeg 옵션을 반환합니다.
+// 트랙마다 정규화 filter, 언어, 제목, 기본 트랙 여부를 설정합니다. 트랙이 없으면 ffmpeg 기본 선택을 따릅니다.
+func audioTrackArgs(videoStream int, tracks []audioTrack, target loudnessTarget) []string {
+	if len(tracks) == 0 {
+		return nil
+	}
+	args := []string{"-map", fmt.Sprintf("0:%d", videoStream)}
+	for _, t := range tracks {
+		args = append(args, "-map", fmt.Sprintf("0:%d", t.Index))
+	}
+	for i, t := range tracks {
+		args = append(args, trackArgs(strconv.Itoa(i), t, target)...)
+	}
+	return args
+}
+
+// trackArgs는 출력 음성 스트림 spec(a:<spec>) 하나의 filter와 metadata 옵션입니다.
+func trackArgs(spec string, t audioTrack, target loudnessTarget) []string {
+	var args []string
+	if filter := t.Loudness.filter(target); filter != "" {
+		// loudnorm은 192kHz로 출력하므로 다시 48kHz로 맞춤
+		args = append(args, "-filter:a:"+spec, filter, "-ar:a:"+spec, "48000")
+	}
+	if t.Language != "" {
+		args = append(args, "-metadata:s:a:"+spec, "language="+t.Language)
+	}
+	if t.Title != "" {
+		args = append(args, "-metadata:s:a:"+spec, "title="+t.Title)
+	}
+	disposition := "0"
+	if t.Default {
+		disposition = "default"
+	}
+	return append(args, "-disposition:a:"+spec, disposition)
+}
+
+// audioEncoderArgs는 음성 전용 화질의 encoder와 컨테이너 옵션입니다.
+func audioEncoderArgs(r AudioRendition) []string {
+	args := []string{"-c:a", r.encoder, "-b:a", r.Bitrate}
+	if r.encoder == "opus" {
+		// ffmpeg 내장 opus encoder는 experimental
+		args = append(args, "-strict", "-2")
+	}
+	switch r.Container {
+	case "mp4":
+		args = append(args, "-movflags", "+faststart", "-f", "mp4")
+	case "webm":
+		args = append(args, "-f", "webm")
+	}
+	return args
+}
+
+// audioOutputPath는 음성 전용 화질 출력 경로입니다 (OUTPUT_DIR/audio/<화질>/<원본 이름>_<트랙>.<확장자>).
+func audioOutputPath(filename string, r AudioRendition, t audioTrack) string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return filepath.Join(os.Getenv("OUTPUT_DIR"), r.Directory, base+"_"+t.Label+"."+r.extension())
+}
+
+// audioKey는 음성 전용 화질 출력을 converted에 기록하는 키입니다.
+func audioKey(r AudioRendition, t audioTrack) string {
+	return r.Name + "/" + t.Label
+}
+
+// prepareAudio는 원본 음성 트랙을 고르고, LOUDNORM이면 트랙마다 1차 loudnorm 분석을 실행합니다.
+// 분석이 실패한 트랙은 정규화 없이 변환합니다. 작업마다 한 번만 실행합니다.
+func (s *server) prepareAudio(ctx context.Context, logger *slog.Logger, sessionID string, info *ProcessingInfo) error {
+	s.mu.Lock()
+	if info.audioReady || info.metadata == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	tracks := selectAudioTracks(info.metadata.Audio, s.audio.Tracks)
+	s.mu.Unlock()
+
+	if s.audio.Loudnorm {
+		s.setCurrent(sessionID, info, "loudnorm")
+		// 분석도 트랙 전체를 decode하므로 트랙마다 화질 하나와 같은 제한 시간 적용
+		duration := s.sourceDuration(info)
+		for i := range tracks {
+			t := &tracks[i]
+			started := time.Now()
+			measureCtx, span := tracing.Start(ctx, "loudnorm", trace.WithAttributes(attribute.Int("stream", t.Index)))
+			cancelTimeout := context.CancelFunc(func() {})
+			if timeout := s.timeouts.For(duration); timeout > 0 {
+				measureCtx, cancelTimeout = context.WithTimeout(measureCtx, timeout)
+			}
+			m, err := s.transcoder.MeasureLoudness(measureCtx, logger, LoudnessJob{Input: info.tempPath, Stream: t.Index, Target: s.audio.Target})
+			cancelTimeout()
+			tracing.End(span, err)
+			if ctx.Err() != nil {
+				s.setCurrent(sessionID, info, "")
+				return ctx.Err()
+			}
+			if err != nil {
+				loudnormAnalyses.WithLabelValues("failed").Inc()
+				logger.Warn("loudness analysis failed, track will not be normalized", "stream", t.Index, "error", err)
+				continue
+			}
+			loudnormAnalyses.WithLabelValues("measured").Inc()
+			t.Loudness = m
+			logger.Info("loudness measured", "stream", t.Index, "language", t.Language, "integrated", m.InputI,
+				"true_peak", m.InputTP, "range", m.InputLRA, "elapsed", time.Since(started))
+		}
+		s.setCurrent(sessionID, info, "")
+	}
+
+	s.mu.Lock()
+	info.audio = tracks
+	info.audioReady = true
+	s.mu.Unlock()
+	return nil
+}
+
+// runAudioRenditions는 아직 완료되지 않은 음성 전용 화질을 트랙마다 변환합니다.
+// 실패한 출력은 재시도하지 않고 오류 목록에 추가합니다 (RetryJob으로 다시 변환).
+func (s *server) runAudioRenditions(ctx context.Context, logger *slog.Logger, sessionID string, info *ProcessingInfo) ([]string, error) {
+	var conversionErrors []string
+	for _, r := range s.audio.Renditions {
+		for _, t := range info.audio {
+			key := audioKey(r, t)
+			if s.isConverted(info, key) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return conversionErrors, ctx.Err()
+			}
+			outputPath := audioOutputPath(info.filename, r, t)
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+				logger.Error("failed to create output directory", "quality", key, "error", err)
+				conversionErrors = append(conversionErrors, fmt.Sprintf("%s: directory creation failed", key))
+				continue
+			}
+
+			s.setCurrent(sessionID, info, key)
+			encodeStarted := time.Now()
+			encodeCtx, span := tracing.Start(ctx, "encode_audio", trace.WithAttributes(
+				attribute.String("quality", key),
+				attribute.String("output.path", outputPath),
+			))
+			duration := s.sourceDuration(info)
+			cancelTimeout := context.CancelFunc(func() {})
+			if timeout := s.timeouts.For(duration); timeout > 0 {
+				encodeCtx, cancelTimeout = context.WithTimeout(encodeCtx, timeout)
+			}
+			err := s.transcoder.EncodeAudio(encodeCtx, logger, AudioJob{
+				Input:     info.tempPath,
+				Output:    outputPath,
+				Rendition: r,
+				Track:     t,
+				Target:    s.audio.Target,
+				Progress: newProgressParser(duration, func(p encodeProgress) {
+					s.setProgress(sessionID, info, key, p)
+				}),
+			})
+			cancelTimeout()
+			tracing.End(span, err)
+			s.setCurrent(sessionID, info, "")
+			encodeProgressGauge.DeleteLabelValues(sessionID, key)
+			if err != nil {
+				os.Remove(outputPath)
+				if ctx.Err() != nil {
+					return conversionErrors, ctx.Err()
+				}
+				encodeFailures.WithLabelValues(r.Name).Inc()
+				logger.Error("audio conversion failed", "quality", key, "error", err)
+				conversionErrors = append(conversionErrors, fmt.Sprintf("%s: conversion failed", key))
+				continue
+			}
+			encodeDuration.WithLabelValues(r.Name).Observe(time.Since(encodeStarted).Seconds())
+			s.markConverted(info, key)
+			logger.Info("audio conversion finished", "quality", key, "output", outputPath)
+		}
+	}
+	return conversionErrors, nil
+}
+
+// audioTrackName은 재생기에 보여 줄 트랙 이름입니다 (제목, 없으면 언어 코드 또는 label).
+func audioTrackName(t audioTrack) string {
+	switch {
+	case t.Title != "":
+		return t.Title
+	case t.Language != "":
+		return t.Language
+	}
+	return t.Label
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+)
+
+// checkpoint는 종료 시 끝나지 않은 변환 작업을 공유 디렉토리(CHECKPOINT_DIR)에 넘겨서
+// 다른 internal 인스턴스가 남은 화질만 이어서 변환할 수 있게 합니다.
+//
+// 디렉토리 구조: <CHECKPOINT_DIR>/<job id>/{원본 파일, checkpoint.json}
+// 재개하는 인스턴스는 checkpoint.json을 checkpoint.json.claimed로 rename하여 작업을 선점합니다.
+// 선점한 인스턴스는 claimed 파일에 자신(claimOwnerID)을 기록하고 변환하는 동안 mtime을 갱신하므로,
+// 인스턴스가 죽어 claimLease 동안 갱신되지 않은 선점은 다른 인스턴스가 풀어서 다시 가져갑니다 (live 녹화도 같은 방식).
+const (
+	manifestName  = "checkpoint.json"
+	claimedSuffix = ".claimed"
+
+	claimLease = 5 * time.Minute
+)
+
+// claimOwnerID는 선점에 기록하는 이 프로세스의 식별자입니다 (호스트 이름/PID).
+// container가 재시작되면 같은 값이 되므로 재시작 전에 선점한 작업은 lease를 기다리지 않고 풀 수 있습니다.
+var claimOwnerID = claimOwner()
+
+func claimOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+type checkpointManifest struct {
+	JobID     string `json:"job_id"`
+	Filename  string `json:"filename"`
+	RequestID string `json:"request_id,omitempty"`
+	// webhook 이벤트를 재개 후에도 같은 곳으로 보내기 위해 보관
+	GatewayJobID string    `json:"gateway_job_id,omitempty"`
+	Tenant       string    `json:"tenant,omitempty"`
+	CallbackURL  string    `json:"callback_url,omitempty"`
+	Converted    []string  `json:"converted"`
+	CreatedAt    time.Time `json:"created_at"`
+	ClaimedBy    string    `json:"claimed_by,omitempty"` // 선점한 인스턴스 (claimOwnerID)
+}
+
+// checkpointAll은 변환 중인 모든 작업의 원본과 진행 상황을 checkpoint 디렉토리에 저장합니다.
+// 업로드가 끝나지 않은 세션은 재개할 수 없으므로 제외됩니다.
+// 원본 이동은 다른 파일시스템이면 복사가 되므로 s.mu를 잡지 않고 진행 상황만 잡은 상태에서 복사해 둡니다.
+func (s *server) checkpointAll() {
+	if s.checkpointDir == "" {
+		return
+	}
+
+	var pending []checkpointTask
+	s.mu.Lock()
+	for sessionID, info := range s.activeProcessings {
+		if !info.converting || info.checkpointed {
+			continue
+		}
+		// 이동 중에 작업이 끝나더라도 원본을 지우지 않도록 먼저 표시
+		info.checkpointed = true
+		task := checkpointTask{
+			dir:  info.resumedFrom,
+			info: info,
+			manifest: checkpointManifest{
+				JobID:     sessionID,
+				Filename:  info.filename,
+				RequestID: info.requestID,
+				CreatedAt: time.Now(),
+
+				GatewayJobID: info.jobID,
+				Tenant:       info.tenant,
+				CallbackURL:  info.callbackURL,
+			},
+		}
+		for quality := range info.converted {
+			task.manifest.Converted = append(task.manifest.Converted, quality)
+		}
+		pending = append(pending, task)
+	}
+	s.mu.Unlock()
+
+	for _, task := range pending {
+		sessionID := task.manifest.JobID
+		if err := s.writeCheckpoint(task); err != nil {
+			slog.Error("failed to checkpoint session", "session_id", sessionID, "request_id", task.manifest.RequestID, "error", err)
+			s.mu.Lock()
+			task.info.checkpointed = false
+			s.mu.Unlock()
+			continue
+		}
+		slog.Info("checkpointed session", "session_id", sessionID, "request_id", task.manifest.RequestID,
+			"done", len(task.manifest.Converted), "total", len(qualities))
+	}
+}
+
+// checkpointTask는 s.mu를 잡은 상태에서 복사한 작업 하나의 checkpoint 내용입니다.
+type checkpointTask struct {
+	dir      string // checkpoint에서 재개된 작업이면 원래 디렉토리 (원본 이동 불필요)
+	info     *ProcessingInfo
+	manifest checkpointManifest
+}
+
+// writeCheckpoint는 원본을 checkpoint 디렉토리로 옮기고 manifest를 씁니다. s.mu를 잡지 않고 호출합니다.
+func (s *server) writeCheckpoint(task checkpointTask) error {
+	dir := task.dir
+	if dir == "" {
+		dir = filepath.Join(s.checkpointDir, task.manifest.JobID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		// tempPath와 filename은 업로드가 끝난 후 바뀌지 않음
+		if err := moveFile(task.info.tempPath, filepath.Join(dir, task.manifest.Filename)); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to move source: %v", err)
+		}
+	}
+	// manifest가 생기는 순간 다른 인스턴스가 가져갈 수 있으므로 원본 이동 후 마지막에 기록
+	return writeManifest(dir, manifestName, task.manifest)
+}
+
+// writeManifest는 임시 파일에 쓴 후 rename하여 dir/name을 만들고, 선점 표시(name.claimed)를 지웁니다.
+func writeManifest(dir, name string, manifest any) error {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, name), raw); err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(dir, name+claimedSuffix))
+	return nil
+}
+
+// claimManifest는 dir/name을 name.claimed로 rename하여 작업을 선점하고 manifest를 읽습니다.
+// rename이 성공한 인스턴스만 작업을 가져가며, 읽을 수 없는 manifest는 디렉토리째 버립니다.
+func claimManifest(dir, name string, manifest any) bool {
+	manifestPath := filepath.Join(dir, name)
+	claimedPath := manifestPath + claimedSuffix
+	if err := os.Rename(manifestPath, claimedPath); err != nil {
+		return false
+	}
+	raw, err := os.ReadFile(claimedPath)
+	if err != nil {
+		slog.Error("failed to read claimed manifest", "dir", dir, "error", err)
+		releaseClaim(dir, name)
+		return false
+	}
+	if err := json.Unmarshal(raw, manifest); err != nil {
+		slog.Warn("discarding corrupt manifest", "dir", dir, "error", err)
+		os.RemoveAll(dir)
+		return false
+	}
+	return true
+}
+
+// recordClaim은 선점한 인스턴스를 기록한 manifest로 claimed 파일을 바꿉니다 (mtime도 갱신되어 lease 시작).
+func recordClaim(dir, name string, manifest any) {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(dir, name+claimedSuffix), raw)
+	}
+	if err != nil {
+		slog.Warn("failed to record claim owner", "dir", dir, "error", err)
+	}
+}
+
+// renewClaim은 ctx가 끝날 때까지 claimed 파일의 mtime을 갱신하여 선점 lease를 연장합니다.
+func renewClaim(ctx context.Context, dir, name string) {
+	claimedPath := filepath.Join(dir, name+claimedSuffix)
+	ticker := time.NewTicker(claimLease / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(claimedPath, now, now)
+		}
+	}
+}
+
+// sweepClaim은 죽은 인스턴스가 남긴 선점을 풀어 다시 처리할 수 있게 합니다.
+// lease 동안 갱신되지 않았거나, 이 프로세스 이름으로 선점되었지만 실행 중이 아닌(재시작 전) 선점이 대상입니다.
+func (s *server) sweepClaim(dir, name string) {
+	s.mu.Lock()
+	running := s.claims[dir]
+	s.mu.Unlock()
+	if running {
+		return
+	}
+	claimedPath := filepath.Join(dir, name+claimedSuffix)
+	st, err := os.Stat(claimedPath)
+	if err != nil {
+		return
+	}
+	raw, err := os.ReadFile(claimedPath)
+	if err != nil {
+		return
+	}
+	// 읽을 수 없는 manifest는 lease만 확인하고, 풀린 후 claimManifest가 버림
+	var manifest checkpointManifest
+	json.Unmarshal(raw, &manifest)
+	expired := time.Since(st.ModTime()) > claimLease
+	if !expired && manifest.ClaimedBy != claimOwnerID {
+		return
+	}
+	slog.Warn("releasing stale claim", "dir", dir, "owner", manifest.ClaimedBy, "claimed_at", st.ModTime())
+	releaseClaim(dir, name)
+}
+
+// releaseClaim은 선점을 풀어 다른 인스턴스(또는 재시작 후)가 다시 가져갈 수 있게 합니다.
+func releaseClaim(dir, name string) {
+	manifestPath := filepath.Join(dir, name)
+	os.Rename(manifestPath+claimedSuffix, manifestPath)
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	// 다른 파일시스템(공유 볼륨)으로는 rename이 불가능하므로 복사
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// resumeLoop는 주기적으로 checkpoint 디렉토리를 확인하여 남은 작업을 이어서 처리합니다.
+func (s *server) resumeLoop(ctx context.Context, interval time.Duration) {
+	if s.checkpointDir == "" {
+		return
+	}
+	if err := os.MkdirAll(s.checkpointDir, 0755); err != nil {
+		slog.Error("failed to create checkpoint directory", "dir", s.checkpointDir, "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.resumeCheckpoints(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *server) resumeCheckpoints(ctx context.Context) {
+	entries, err := os.ReadDir(s.checkpointDir)
+	if err != nil {
+		slog.Error("failed to read checkpoint directory", "dir", s.checkpointDir, "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || ctx.Err() != nil {
+			continue
+		}
+		dir := filepath.Join(s.checkpointDir, entry.Name())
+		s.sweepClaim(dir, manifestName)
+		var manifest checkpointManifest
+		if !claimManifest(dir, manifestName, &manifest) {
+			continue
+		}
+		manifest.ClaimedBy = claimOwnerID
+		recordClaim(dir, manifestName, manifest)
+
+		info := &ProcessingInfo{
+			filename:    manifest.Filename,
+			tempPath:    filepath.Join(dir, manifest.Filename),
+			requestID:   manifest.RequestID,
+			jobID:       manifest.GatewayJobID,
+			tenant:      manifest.Tenant,
+			callbackURL: manifest.CallbackURL,
+			started:     time.Now(),
+			converting:  true,
+			queued:      true,
+			converted:   make(map[string]bool),
+			resumedFrom: dir,
+		}
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		info.cancel = func() { cancel(errCancelledByAdmin) }
+		for _, quality := range manifest.Converted {
+			info.converted[quality] = true
+		}
+
+		s.mu.Lock()
+		s.activeProcessings[manifest.JobID] = info
+		s.claims[dir] = true
+		s.mu.Unlock()
+
+		slog.Info("resuming checkpointed session", "session_id", manifest.JobID, "request_id", manifest.RequestID,
+			"done", len(manifest.Converted), "total", len(qualities))
+
+		s.jobs.Add(1)
+		go func(sessionID string) {
+			defer s.jobs.Done()
+			defer cancel(nil)
+			renewCtx, stopRenew := context.WithCancel(jobCtx)
+			go renewClaim(renewCtx, dir, manifestName)
+			s.runResumed(jobCtx, sessionID, info)
+			stopRenew()
+			s.mu.Lock()
+			delete(s.claims, dir)
+			s.mu.Unlock()
+		}(manifest.JobID)
+	}
+}
+
+func (s *server) runResumed(ctx context.Context, sessionID string, info *ProcessingInfo) {
+	if info.requestID != "" {
+		ctx = logging.WithRequestID(ctx, info.requestID)
+	}
+	logger := logging.FromContext(ctx).With("session_id", sessionID)
+	successCount, conversionErrors, err := s.convertQueued(ctx, logger, sessionID, info, true)
+
+	s.finishJob(sessionID, info)
+	s.mu.Lock()
+	checkpointed := info.checkpointed
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("resumed session interrupted", "error", err, "cause", context.Cause(ctx))
+		if errors.Is(context.Cause(ctx), errCancelledByAdmin) {
+			// 관리 API로 취소된 작업은 다시 재개하지 않음
+			os.RemoveAll(info.resumedFrom)
+			s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: errCancelledByAdmin.Error(), Errors: conversionErrors})
+			return
+		}
+		if !checkpointed {
+			// checkpoint를 갱신하지 못했으면 선점을 풀어서 다른 인스턴스가 다시 가져가게 함
+			releaseClaim(info.resumedFrom, manifestName)
+		}
+		return
+	}
+
+	if len(conversionErrors) > 0 {
+		s.retainSource(logger, sessionID, info, conversionErrors)
+	}
+	os.RemoveAll(info.resumedFrom)
+	s.notifyResult(sessionID, info, successCount, conversionErrors)
+	logger.Info("resumed session finished", "result", resultMessage(successCount, conversionErrors))
+}
+
+// convertQueued는 작업 슬롯을 얻어 남은 화질을 변환합니다 (checkpoint 재개, 재변환, live 녹화).
+// 메타데이터는 저장하지 않으므로 아직 분석하지 않았으면 먼저 원본을 다시 분석합니다 (처음 수신 시 이미 검증된 원본).
+func (s *server) convertQueued(ctx context.Context, logger *slog.Logger, sessionID string, info *ProcessingInfo, thumbnails bool) (int, []string, error) {
+	s.mu.Lock()
+	probed := info.metadata != nil
+	s.mu.Unlock()
+	if !probed {
+		if err := s.probeSource(ctx, logger, info); err != nil {
+			logger.Warn("failed to probe source", "error", err)
+		}
+	}
+	slot, err := s.acquireWorker(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer slot.release()
+
+	s.updateJob(sessionID, info, func() { info.queued = false })
+	successCount, conversionErrors, err := s.runConversions(ctx, sessionID, info, slot)
+	if err == nil && successCount > 0 && thumbnails {
+		s.runThumbnails(ctx, logger, info)
+	}
+	return successCount, conversionErrors, err
+}
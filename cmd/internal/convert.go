@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type VideoQuality struct {
+	Name         string `json:"name"`
+	Height       int    `json:"height"`
+	Bitrate      string `json:"bitrate"`
+	Directory    string `json:"directory,omitempty"` // 기본값: Name
+	Codec        string `json:"codec,omitempty"`     // h264, hevc, vp9, av1 (기본 h264)
+	Container    string `json:"container,omitempty"` // mp4, webm (기본 mp4, vp9는 webm)
+	AudioCodec   string `json:"audio_codec,omitempty"`
+	AudioBitrate string `json:"audio_bitrate,omitempty"`
+
+	// 시작 시 ffmpeg -encoders 결과로 결정
+	encoder      string
+	audioEncoder string
+	preset       string // 비어 있으면 encoder 기본 preset (fallbackQuality에서 사용)
+}
+
+// qualities는 변환할 화질 목록으로, 시작 시 loadLadder와 resolveLadder로 설정됩니다.
+var qualities []VideoQuality
+
+// runConversions는 아직 완료되지 않은 화질을 순서대로 변환합니다.
+// 실패한 화질은 retryPolicy에 따라 backoff 후 다시 시도합니다 (제한 시간 초과는 재시도하지 않음).
+// backoff 동안에는 slot을 내려놓아 다른 작업이 변환할 수 있게 합니다.
+// ctx가 취소되면 진행 중인 ffmpeg를 종료하고 ctx.Err()를 반환합니다.
+func (s *server) runConversions(ctx context.Context, sessionID string, info *ProcessingInfo, slot *workerSlot) (int, []string, error) {
+	baseDir := os.Getenv("OUTPUT_DIR")
+	logger := logging.FromContext(ctx).With("session_id", sessionID)
+	successCount := 0
+	var conversionErrors []string
+
+	// 모든 화질에 같은 음성 트랙과 loudnorm 분석 결과 사용
+	if err := s.prepareAudio(ctx, logger, sessionID, info); err != nil {
+		logger.Warn("session cancelled during loudness analysis")
+		return successCount, conversionErrors, err
+	}
+
+	// 각 화질별로 변환
+	for _, rung := range qualities {
+		if s.isConverted(info, rung.Name) {
+			successCount++
+			continue
+		}
+		if ctx.Err() != nil {
+			logger.Warn("session cancelled, aborting remaining conversions")
+			return successCount, conversionErrors, ctx.Err()
+		}
+		// RetryJob에서 fallback을 요청한 화질은 호환성 높은 설정으로 변환
+		quality := info.rendition(rung)
+
+		qualityDir := filepath.Join(baseDir, quality.Directory)
+		if err := os.MkdirAll(qualityDir, 0755); err != nil {
+			logger.Error("failed to create output directory", "quality", quality.Name, "error", err)
+			conversionErrors = append(conversionErrors,
+				fmt.Sprintf("%s: directory creation failed", quality.Name))
+			continue
+		}
+
+		outputPath := filepath.Join(qualityDir, outputName(info.filename, quality.Container))
+		encodeStarted := time.Now()
+		var err error
+		var timeout time.Duration
+		for attempt := 1; ; attempt++ {
+			timeout, err = s.encodeRendition(ctx, logger, sessionID, info, quality, outputPath)
+			if err == nil || ctx.Err() != nil || timeout > 0 || attempt >= s.retries.MaxAttempts {
+				break
+			}
+			backoff := s.retries.backoff(attempt)
+			encodeRetries.WithLabelValues(quality.Name).Inc()
+			logger.Warn("conversion failed, retrying", "quality", quality.Name, "attempt", attempt, "backoff", backoff, "error", err)
+			slot.release()
+			s.updateJob(sessionID, info, func() { info.queued = true })
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			if slot.acquire(ctx) != nil {
+				break
+			}
+			s.updateJob(sessionID, info, func() { info.queued = false })
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				// 취소로 중단된 ffmpeg의 불완전한 출력 파일 정리
+				os.Remove(outputPath)
+				logger.Warn("session cancelled during conversion, ffmpeg killed", "quality", quality.Name)
+				return successCount, conversionErrors, ctx.Err()
+			}
+			encodeFailures.WithLabelValues(quality.Name).Inc()
+			if timeout > 0 {
+				os.Remove(outputPath)
+				encodeTimeoutsTotal.WithLabelValues(quality.Name).Inc()
+				logger.Error("conversion timed out, ffmpeg killed", "quality", quality.Name, "timeout", timeout, "source_duration", s.sourceDuration(info))
+				conversionErrors = append(conversionErrors,
+					fmt.Sprintf("%s: conversion timed out after %s", quality.Name, timeout))
+				continue
+			}
+			logger.Error("conversion failed", "quality", quality.Name, "error", err)
+			conversionErrors = append(conversionErrors,
+				fmt.Sprintf("%s: conversion failed", quality.Name))
+			continue
+		}
+
+		s.markConverted(info, quality.Name)
+		s.notify(sessionID, info, webhook.RenditionCompleted, renditionOutput(info, quality, time.Since(encodeStarted).Seconds()))
+		successCount++
+		logger.Info("conversion finished", "quality", quality.Name, "output", outputPath)
+	}
+
+	// 음성 전용 화질 (AUDIO_RENDITIONS)
+	audioErrors, err := s.runAudioRenditions(ctx, logger, sessionID, info)
+	conversionErrors = append(conversionErrors, audioErrors...)
+	if err != nil {
+		logger.Warn("session cancelled during audio conversion")
+		return successCount, conversionErrors, err
+	}
+
+	return successCount, conversionErrors, nil
+}
+
+// encodeRendition은 화질 하나를 한 번 변환합니다.
+// 원본 길이에 비례한 제한 시간을 넘겨 중단되었으면 그 제한 시간을 함께 반환합니다.
+func (s *server) encodeRendition(ctx context.Context, logger *slog.Logger, sessionID string, info *ProcessingInfo, quality VideoQuality, outputPath string) (time.Duration, error) {
+	s.setCurrent(sessionID, info, quality.Name)
+	encodeStarted := time.Now()
+	encodeCtx, span := tracing.Start(ctx, "encode", trace.WithAttributes(
+		attribute.String("quality", quality.Name),
+		attribute.String("output.path", outputPath),
+	))
+	duration := s.sourceDuration(info)
+	progress := newProgressParser(duration, func(p encodeProgress) {
+		s.setProgress(sessionID, info, quality.Name, p)
+	})
+	// 원본 길이에 비례한 제한 시간이 지나면 ffmpeg를 종료하고 이 화질만 실패 처리
+	timeout := s.timeouts.For(duration)
+	cancelTimeout := context.CancelFunc(func() {})
+	if timeout > 0 {
+		encodeCtx, cancelTimeout = context.WithTimeout(encodeCtx, timeout)
+	}
+	s.mu.Lock()
+	job := EncodeJob{
+		Input:    info.tempPath,
+		Output:   outputPath,
+		Quality:  quality,
+		Progress: progress,
+		Audio:    info.audio,
+		Target:   s.audio.Target,
+	}
+	if info.metadata != nil {
+		job.VideoStream = info.metadata.PrimaryVideo().Index
+	}
+	s.mu.Unlock()
+	err := s.transcoder.Encode(encodeCtx, logger, job)
+	timedOut := errors.Is(encodeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	cancelTimeout()
+	s.setCurrent(sessionID, info, "")
+	encodeProgressGauge.DeleteLabelValues(sessionID, quality.Name)
+	tracing.End(span, err)
+	encodeDuration.WithLabelValues(quality.Name).Observe(time.Since(encodeStarted).Seconds())
+	if err != nil && timedOut {
+		return timeout, err
+	}
+	return 0, err
+}
+
+// outputName은 원본 파일 이름의 확장자를 출력 컨테이너로 바꿉니다.
+func outputName(filename, container string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + container
+}
+
+func (s *server) isConverted(info *ProcessingInfo, quality string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return info.converted[quality]
+}
+
+func (s *server) setCurrent(sessionID string, info *ProcessingInfo, quality string) {
+	s.updateJob(sessionID, info, func() {
+		info.current = quality
+		info.progress = encodeProgress{}
+	})
+}
+
+// setProgress는 ffmpeg 진행 상황을 작업 상태와 메트릭에 반영합니다.
+func (s *server) setProgress(sessionID string, info *ProcessingInfo, quality string, p encodeProgress) {
+	s.updateJob(sessionID, info, func() { info.progress = p })
+	encodeProgressGauge.WithLabelValues(sessionID, quality).Set(p.Percent)
+	if p.Done && p.Speed > 0 {
+		encodeSpeed.WithLabelValues(quality).Observe(p.Speed)
+	}
+}
+
+// sourceDuration은 ffprobe로 얻은 원본 길이(초)를 반환합니다. 모르면 0입니다.
+func (s *server) sourceDuration(info *ProcessingInfo) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info.metadata == nil {
+		return 0
+	}
+	return info.metadata.Duration
+}
+
+func (s *server) markConverted(info *ProcessingInfo, quality string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info.converted[quality] = true
+}
+
+// resultMessage는 변환 결과 메시지를 생성합니다.
+func resultMessage(successCount int, conversionErrors []string) string {
+	if successCount == len(qualities) {
+		return fmt.Sprintf("Successfully converted video to all %d qualities", successCount)
+	} else if successCount > 0 {
+		return fmt.Sprintf("Partially converted video to %d/%d qualities. Errors: %v",
+			successCount, len(qualities), conversionErrors)
+	}
+	return fmt.Sprintf("Failed to convert video. Errors: %v", conversionErrors)
+}
+
+// runFFmpeg는 ffmpeg를 자원 제한을 적용하여 실행합니다. ctx가 취소되면 프로세스 그룹을 종료합니다.
+// stdin이 nil이 아니면 ffmpeg 표준 입력(pipe:0)으로 연결합니다.
+// progress가 nil이 아니면 -progress pipe:1 출력(key=value 줄)을 progress로 보냅니다.
+func (t ffmpegTranscoder) runFFmpeg(ctx context.Context, logger *slog.Logger, stdin io.Reader, progress io.Writer, args ...string) error {
+	// ffmpeg 출력은 줄 단위로 debug 로그에 남기고, 실패 시 마지막 몇 줄만 오류에 포함
+	return t.run(ctx, logger, stdin, progress, newFFmpegLog(logger, 20), args...)
+}
+
+// run은 ffmpeg 출력을 output에 기록하며 실행합니다. 출력 내용이 필요한 경우(loudnorm 분석) 직접 호출합니다.
+func (t ffmpegTranscoder) run(ctx context.Context, logger *slog.Logger, stdin io.Reader, progress io.Writer, output *ffmpegLog, args ...string) error {
+	if progress != nil {
+		args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
+	}
+	cmd, limitErr := limitedCommand(ctx, t.limits, "ffmpeg", args...)
+	if limitErr != nil {
+		logger.Warn("failed to apply ffmpeg resource limits", "error", limitErr)
+	}
+	cmd.Stdin = stdin
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if progress != nil {
+		cmd.Stdout = progress
+	}
+
+	configureCommand(cmd)
+	// 종료 후에도 하위 프로세스가 pipe를 잡고 있으면 이 시간 후 Wait 반환
+	cmd.WaitDelay = 5 * time.Second
+
+	// ctx가 취소되면 Wait가 반환되기 전에 cmd.Cancel이 프로세스 그룹을 종료
+	err := cmd.Run()
+	output.Flush()
+	recordFFmpegExit(cmd.ProcessState.ExitCode())
+	if err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, output.Tail())
+	}
+	return nil
+}
+
+// ffmpegLog는 ffmpeg 출력을 줄 단위로 로거에 기록하고 마지막 tailSize 줄을 보관하는 io.Writer입니다.
+type ffmpegLog struct {
+	logger   *slog.Logger
+	tailSize int
+
+	mu   sync.Mutex
+	buf  []byte
+	tail []string
+}
+
+func newFFmpegLog(logger *slog.Logger, tailSize int) *ffmpegLog {
+	return &ffmpegLog{logger: logger.With("source", "ffmpeg"), tailSize: tailSize}
+}
+
+func (l *ffmpegLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		// ffmpeg 진행 상황은 \r로 같은 줄을 덮어쓰므로 둘 다 줄 구분으로 처리
+		i := bytes.IndexAny(l.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		l.line(string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush는 줄바꿈 없이 남은 출력을 기록합니다.
+func (l *ffmpegLog) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.line(string(l.buf))
+	l.buf = nil
+}
+
+func (l *ffmpegLog) line(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	l.logger.Debug(s)
+	l.tail = append(l.tail, s)
+	if len(l.tail) > l.tailSize {
+		l.tail = l.tail[len(l.tail)-l.tailSize:]
+	}
+}
+
+// Tail은 보관 중인 마지막 출력 줄들을 반환합니다.
+func (l *ffmpegLog) Tail() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.tail, "\n")
+}
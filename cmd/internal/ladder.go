@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// 코덱별 ffmpeg encoder 후보 (앞에 있을수록 우선)
+var videoEncoders = map[string][]string{
+	"h264": {"libx264"},
+	"hevc": {"libx265"},
+	"vp9":  {"libvpx-vp9"},
+	"av1":  {"libsvtav1", "libaom-av1"},
+}
+
+var audioEncoders = map[string][]string{
+	"aac":  {"aac", "libfdk_aac"},
+	"opus": {"libopus", "opus"},
+}
+
+// 컨테이너별 허용 코덱
+var containerCodecs = map[string]struct{ video, audio []string }{
+	"mp4":  {video: []string{"h264", "hevc", "vp9", "av1"}, audio: []string{"aac", "opus"}},
+	"webm": {video: []string{"vp9", "av1"}, audio: []string{"opus"}},
+}
+
+// 같은 화질에서 H.264 대비 비트레이트 비율 (LADDER_CODECS로 기본 ladder를 만들 때 사용)
+var codecEfficiency = map[string]float64{"h264": 1, "hevc": 0.6, "vp9": 0.65, "av1": 0.5}
+
+// defaultLadder는 LADDER_FILE이 없을 때 사용하는 화질별 높이와 H.264 기준 비트레이트입니다.
+var defaultLadder = []struct {
+	name    string
+	height  int
+	bitrate int // kbps
+}{
+	{"1080p", 1080, 5000},
+	{"720p", 720, 2500},
+	{"480p", 480, 1000},
+	{"360p", 360, 750},
+}
+
+// loadLadder는 변환할 화질 목록을 읽습니다.
+//
+//	LADDER_FILE    화질 목록 JSON ([{"name": "1080p_av1", "height": 1080, "bitrate": "2500k", "codec": "av1",
+//	               "container": "webm", "audio_codec": "opus", "audio_bitrate": "128k"}, ...])
+//	LADDER_CODECS  LADDER_FILE이 없을 때 기본 화질별로 만들 코덱 목록 (기본 h264, 예: h264,hevc,av1)
+func loadLadder() ([]VideoQuality, error) {
+	if path := os.Getenv("LADDER_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ladder file: %v", err)
+		}
+		var ladder []VideoQuality
+		if err := json.Unmarshal(raw, &ladder); err != nil {
+			return nil, fmt.Errorf("invalid ladder file %s: %v", path, err)
+		}
+		return normalizeLadder(ladder)
+	}
+
+	codecs := []string{"h264"}
+	if v := os.Getenv("LADDER_CODECS"); v != "" {
+		codecs = strings.Split(v, ",")
+	}
+	var ladder []VideoQuality
+	for _, codec := range codecs {
+		codec = strings.ToLower(strings.TrimSpace(codec))
+		factor, ok := codecEfficiency[codec]
+		if !ok {
+			return nil, fmt.Errorf("unknown codec %q in LADDER_CODECS", codec)
+		}
+		for _, rung := range defaultLadder {
+			q := VideoQuality{
+				Name:    rung.name,
+				Height:  rung.height,
+				Bitrate: fmt.Sprintf("%dk", int(float64(rung.bitrate)*factor)),
+				Codec:   codec,
+			}
+			// 기본 H.264 ladder는 기존 출력 경로(OUTPUT_DIR/<화질>)를 유지
+			if codec != "h264" {
+				q.Name += "_" + codec
+			}
+			ladder = append(ladder, q)
+		}
+	}
+	return normalizeLadder(ladder)
+}
+
+// normalizeLadder는 기본값을 채우고 코덱과 컨테이너 조합을 검사합니다.
+func normalizeLadder(ladder []VideoQuality) ([]VideoQuality, error) {
+	if len(ladder) == 0 {
+		return nil, errors.New("ladder is empty")
+	}
+	seen := make(map[string]bool)
+	for i := range ladder {
+		q := &ladder[i]
+		if q.Name == "" || q.Height <= 0 || q.Bitrate == "" {
+			return nil, fmt.Errorf("ladder entry %d: name, height and bitrate are required", i)
+		}
+		if seen[q.Name] {
+			return nil, fmt.Errorf("ladder entry %d: duplicate name %q", i, q.Name)
+		}
+		seen[q.Name] = true
+
+		if q.Codec == "" {
+			q.Codec = "h264"
+		}
+		if q.Container == "" {
+			q.Container = "mp4"
+			if q.Codec == "vp9" {
+				q.Container = "webm"
+			}
+		}
+		if q.AudioCodec == "" {
+			q.AudioCodec = "aac"
+			if q.Container == "webm" {
+				q.AudioCodec = "opus"
+			}
+		}
+		if q.AudioBitrate == "" {
+			q.AudioBitrate = "128k"
+		}
+		if q.Directory == "" {
+			q.Directory = q.Name
+		}
+
+		if _, ok := videoEncoders[q.Codec]; !ok {
+			return nil, fmt.Errorf("%s: unknown video codec %q", q.Name, q.Codec)
+		}
+		if _, ok := audioEncoders[q.AudioCodec]; !ok {
+			return nil, fmt.Errorf("%s: unknown audio codec %q", q.Name, q.AudioCodec)
+		}
+		allowed, ok := containerCodecs[q.Container]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown container %q", q.Name, q.Container)
+		}
+		if !contains(allowed.video, q.Codec) || !contains(allowed.audio, q.AudioCodec) {
+			return nil, fmt.Errorf("%s: %s/%s cannot be stored in %s", q.Name, q.Codec, q.AudioCodec, q.Container)
+		}
+	}
+	return ladder, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// detectEncoders는 `ffmpeg -encoders` 출력에서 사용 가능한 encoder 이름을 읽습니다.
+func detectEncoders(ctx context.Context) (map[string]bool, error) {
+	out, err := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ffmpeg encoders: %v", err)
+	}
+	return parseEncoders(out), nil
+}
+
+// parseEncoders는 " V....D libx264   libx264 H.264 ..." 형식의 줄에서 encoder 이름을 모읍니다.
+func parseEncoders(out []byte) map[string]bool {
+	encoders := make(map[string]bool)
+	started := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !started {
+			// 범례 다음의 " ------" 줄부터 목록
+			started = strings.HasPrefix(line, "---")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// resolveLadder는 각 화질에 사용할 encoder를 고릅니다.
+// 지원하지 않는 화질이 있으면 오류를 반환하고, skipUnsupported이면 경고 후 제외합니다.
+func resolveLadder(ladder []VideoQuality, encoders map[string]bool, skipUnsupported bool) ([]VideoQuality, error) {
+	var resolved []VideoQuality
+	var unsupported []string
+	for _, q := range ladder {
+		q.encoder = pickEncoder(videoEncoders[q.Codec], encoders)
+		q.audioEncoder = pickEncoder(audioEncoders[q.AudioCodec], encoders)
+		switch {
+		case q.encoder == "":
+			unsupported = append(unsupported, fmt.Sprintf("%s (video codec %s needs one of %v)", q.Name, q.Codec, videoEncoders[q.Codec]))
+			continue
+		case q.audioEncoder == "":
+			unsupported = append(unsupported, fmt.Sprintf("%s (audio codec %s needs one of %v)", q.Name, q.AudioCodec, audioEncoders[q.AudioCodec]))
+			continue
+		}
+		resolved = append(resolved, q)
+	}
+
+	if len(unsupported) > 0 {
+		if !skipUnsupported {
+			return nil, fmt.Errorf("ffmpeg does not support renditions: %s", strings.Join(unsupported, "; "))
+		}
+		slog.Warn("skipping renditions unsupported by ffmpeg", "renditions", unsupported)
+	}
+	if len(resolved) == 0 {
+		return nil, errors.New("no rendition in the ladder is supported by ffmpeg")
+	}
+	return resolved, nil
+}
+
+func pickEncoder(candidates []string, available map[string]bool) string {
+	for _, name := range candidates {
+		if available[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// fallbackQuality는 재시도할 때 사용할 호환성 높은 설정(H.264/AAC, mp4, 빠른 preset)을 반환합니다.
+// 이름과 출력 디렉토리는 그대로 두고, 비트레이트는 codecEfficiency로 H.264 기준으로 환산합니다.
+func fallbackQuality(q VideoQuality) VideoQuality {
+	if kbps, ok := parseBitrate(q.Bitrate); ok && q.Codec != "h264" {
+		q.Bitrate = fmt.Sprintf("%dk", int(float64(kbps)/codecEfficiency[q.Codec]))
+	}
+	q.Codec, q.Container, q.AudioCodec = "h264", "mp4", "aac"
+	q.encoder, q.audioEncoder, q.preset = "libx264", "aac", "veryfast"
+	return q
+}
+
+// parseBitrate는 "2500k", "5M" 형식의 비트레이트를 kbps로 변환합니다.
+func parseBitrate(s string) (int, bool) {
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		s = s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		s, unit = s[:len(s)-1], 1000
+	default:
+		n, err := strconv.Atoi(s)
+		return n / 1000, err == nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return int(n * float64(unit)), err == nil
+}
+
+// encoderArgs는 encoder별 ffmpeg 옵션을 반환합니다.
+func encoderArgs(q VideoQuality) []string {
+	args := []string{"-c:v", q.encoder, "-b:v", q.Bitrate, "-pix_fmt", "yuv420p"}
+	switch q.encoder {
+	case "libx264", "libx265":
+		preset := "medium"
+		if q.preset != "" {
+			preset = q.preset
+		}
+		args = append(args, "-preset", preset)
+	case "libvpx-vp9":
+		args = append(args, "-deadline", "good", "-cpu-used", "2", "-row-mt", "1")
+	case "libsvtav1":
+		args = append(args, "-preset", "8")
+	case "libaom-av1":
+		args = append(args, "-cpu-used", "6", "-row-mt", "1")
+	}
+	if q.Codec == "hevc" && q.Container == "mp4" {
+		// Apple 기기 재생을 위해 hvc1 태그 사용
+		args = append(args, "-tag:v", "hvc1")
+	}
+
+	args = append(args, "-c:a", q.audioEncoder, "-b:a", q.AudioBitrate)
+	if q.audioEncoder == "opus" {
+		// ffmpeg 내장 opus encoder는 experimental
+		args = append(args, "-strict", "-2")
+	}
+
+	switch q.Container {
+	case "mp4":
+		args = append(args, "-movflags", "+faststart", "-f", "mp4")
+	case "webm":
+		args = append(args, "-f", "webm")
+	}
+	return args
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+)
+
+// encodeTimeouts는 원본 길이로 화질 하나의 변환 제한 시간을 정합니다.
+// 손상되었거나 비정상적으로 긴 원본 때문에 ffmpeg가 작업 슬롯을 계속 점유하지 않도록 합니다.
+type encodeTimeouts struct {
+	Factor float64       // 원본 길이 대비 배수 (0이면 제한 없음)
+	Min    time.Duration // 짧은 원본도 최소한 이만큼은 허용
+	Max    time.Duration // 길이를 모르거나 계산 값이 클 때의 상한
+}
+
+// encodeTimeoutsFromEnv는 ENCODE_TIMEOUT_FACTOR, ENCODE_TIMEOUT_MIN, ENCODE_TIMEOUT_MAX를 읽습니다.
+func encodeTimeoutsFromEnv() encodeTimeouts {
+	return encodeTimeouts{
+		Factor: env.Float("ENCODE_TIMEOUT_FACTOR", 10),
+		Min:    env.Duration("ENCODE_TIMEOUT_MIN", 2*time.Minute),
+		Max:    env.Duration("ENCODE_TIMEOUT_MAX", 6*time.Hour),
+	}
+}
+
+// For는 길이가 duration초인 원본의 변환 제한 시간을 반환합니다. 0이면 제한 없음입니다.
+func (t encodeTimeouts) For(duration float64) time.Duration {
+	if t.Factor <= 0 {
+		return 0
+	}
+	if duration <= 0 {
+		return t.Max
+	}
+	timeout := time.Duration(duration * t.Factor * float64(time.Second))
+	timeout = max(timeout, t.Min)
+	if t.Max > 0 {
+		timeout = min(timeout, t.Max)
+	}
+	return timeout
+}
+
+// processLimits는 ffmpeg 프로세스에 적용할 자원 제한입니다.
+type processLimits struct {
+	Threads   int   // ffmpeg -threads (0이면 ffmpeg 기본값)
+	Nice      int   // CPU 우선순위 (0~19, 클수록 낮음)
+	MaxMemory int64 // 주소 공간 상한(bytes, RLIMIT_AS), Linux에서만 적용
+}
+
+// processLimitsFromEnv는 FFMPEG_THREADS, FFMPEG_NICE, FFMPEG_MAX_MEMORY_BYTES를 읽습니다.
+func processLimitsFromEnv() processLimits {
+	return processLimits{
+		Threads:   env.Int("FFMPEG_THREADS", 0),
+		Nice:      env.Int("FFMPEG_NICE", 0),
+		MaxMemory: int64(env.Int("FFMPEG_MAX_MEMORY_BYTES", 0)),
+	}
+}
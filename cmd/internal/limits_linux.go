@@ -0,0 +1,101 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// limitShimEnv가 설정된 채로 실행되면 이 바이너리는 자원 제한을 적용한 후 ffmpeg로 exec합니다.
+// 값은 "nice,최대 메모리,ffmpeg 경로"입니다.
+const limitShimEnv = "TRANSCODER_LIMIT_SHIM"
+
+// configureCommand는 ffmpeg를 새 프로세스 그룹으로 실행하고, 취소 시 그룹 전체를 종료하도록 설정합니다.
+// 서버 프로세스가 먼저 죽어도 ffmpeg가 남지 않도록 Pdeathsig도 설정합니다.
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+}
+
+// killProcessGroup은 cmd가 속한 프로세스 그룹 전체에 SIGKILL을 보냅니다.
+// Wait가 반환된 후에는 pgid가 재사용되었을 수 있으므로 실행 중에만(cmd.Cancel) 호출합니다.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
+
+// limitedCommand는 name을 실행하는 cmd를 만듭니다. 자원 제한이 있으면 이 바이너리를 shim으로 먼저 실행하여
+// exec 전에 CPU 우선순위와 메모리 상한을 적용하므로 ffmpeg 초기화 단계의 할당에도 제한이 적용됩니다.
+func limitedCommand(ctx context.Context, limits processLimits, name string, args ...string) (*exec.Cmd, error) {
+	if limits.Nice == 0 && limits.MaxMemory <= 0 {
+		return exec.CommandContext(ctx, name, args...), nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return exec.CommandContext(ctx, name, args...), err
+	}
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = append([]string{name}, args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d,%d,%s", limitShimEnv, limits.Nice, limits.MaxMemory, path))
+	return cmd, nil
+}
+
+// runLimitShim은 limitedCommand로 실행된 경우 자원 제한을 적용하고 ffmpeg로 exec합니다. 그 외에는 아무 동작도 하지 않습니다.
+// main에서 가장 먼저 호출해야 합니다.
+func runLimitShim() {
+	spec, ok := os.LookupEnv(limitShimEnv)
+	if !ok {
+		return
+	}
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "ffmpeg limit shim: %v\n", err)
+		os.Exit(127)
+	}
+	parts := strings.SplitN(spec, ",", 3)
+	if len(parts) != 3 {
+		fail(fmt.Errorf("invalid %s %q", limitShimEnv, spec))
+	}
+	nice, err1 := strconv.Atoi(parts[0])
+	maxMemory, err2 := strconv.ParseUint(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		fail(fmt.Errorf("invalid %s %q", limitShimEnv, spec))
+	}
+
+	// Linux의 nice 값은 스레드 단위이므로 같은 스레드에서 설정 후 exec
+	runtime.LockOSThread()
+	if nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice); err != nil {
+			fail(fmt.Errorf("setpriority: %v", err))
+		}
+	}
+	if maxMemory > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, &unix.Rlimit{Cur: maxMemory, Max: maxMemory}); err != nil {
+			fail(fmt.Errorf("setrlimit: %v", err))
+		}
+	}
+
+	environ := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, limitShimEnv+"=") {
+			environ = append(environ, kv)
+		}
+	}
+	fail(syscall.Exec(parts[2], os.Args, environ))
+}
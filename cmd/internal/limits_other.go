@@ -0,0 +1,23 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// configureCommand는 Linux 외의 환경에서는 기본 동작(ffmpeg 프로세스만 종료)을 사용합니다.
+func configureCommand(cmd *exec.Cmd) {}
+
+// limitedCommand는 Linux에서만 자원 제한을 지원합니다. 제한이 있으면 제한 없이 실행하는 cmd와 오류를 반환합니다.
+func limitedCommand(ctx context.Context, limits processLimits, name string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if limits.Nice != 0 || limits.MaxMemory > 0 {
+		return cmd, errors.New("process limits are only supported on linux")
+	}
+	return cmd, nil
+}
+
+func runLimitShim() {}
@@ -0,0 +1,721 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// live 출력 파일 이름 (LIVE_DIR/<session>/<화질 디렉토리>/)
+const (
+	liveSourcePlaylist = "source.m3u8" // transcoder가 쓰고 livePlaylists가 읽는 재생목록
+	liveSegmentPattern = "seg_%06d.ts"
+	liveMediaPlaylist  = "index.m3u8" // sliding window
+	liveDVRPlaylist    = "dvr.m3u8"   // DVR window
+	liveVODPlaylist    = "vod.m3u8"   // 업로드 종료 후 방송 전체 (KeepSegments일 때만)
+
+	// liveSourceListSize는 source 재생목록에 유지할 segment 수로, 폴링 간격(segment 절반) 사이에 놓치지 않을 만큼이면 됩니다.
+	liveSourceListSize = 10
+	// liveProbeBytes는 audio group을 만들 때 음성 트랙을 분석하기 위해 먼저 받아 두는 업로드 앞부분 크기입니다.
+	liveProbeBytes = 1 << 20
+)
+
+var errLiveEncoderStopped = errors.New("live encoder stopped")
+
+// liveConfig는 live 업로드(x-live metadata)의 HLS 출력 설정입니다.
+type liveConfig struct {
+	Dir             string
+	SegmentDuration time.Duration
+	Window          time.Duration // index.m3u8에 포함할 길이
+	DVRWindow       time.Duration // dvr.m3u8에 포함할 길이 (0이면 만들지 않음)
+	KeepSegments    bool          // true면 window 밖의 segment도 지우지 않고 종료 후 방송 전체 VOD 재생목록을 씀 (false면 VOD 재생목록 없음)
+	RecordInterval  time.Duration // 녹화 파일 하나의 길이 (0이면 녹화하지 않음, recording.go)
+	RecordAligned   bool          // true면 시계 기준(정각 등)으로 자름
+	AudioGroups     bool          // true면 AUDIO_RENDITIONS의 AAC 화질을 HLS audio group으로 분리
+	AudioLanguage   string        // 입력의 음성 트랙을 분석하지 못했을 때 audio group의 LANGUAGE
+	MaxSessions     int           // 동시에 받을 live 업로드 수 (0: 제한 없음, VOD 작업 슬롯과 별개)
+}
+
+// liveConfigFromEnv는 LIVE_DIR(기본 OUTPUT_DIR/live), LIVE_SEGMENT_DURATION, LIVE_WINDOW,
+// LIVE_DVR_WINDOW, LIVE_KEEP_SEGMENTS, LIVE_RECORD_INTERVAL, LIVE_RECORD_ALIGN, LIVE_AUDIO_GROUPS, LIVE_AUDIO_LANGUAGE,
+// LIVE_MAX_SESSIONS를 읽습니다.
+func liveConfigFromEnv() liveConfig {
+	cfg := liveConfig{
+		Dir:             os.Getenv("LIVE_DIR"),
+		SegmentDuration: env.Duration("LIVE_SEGMENT_DURATION", 4*time.Second),
+		Window:          env.Duration("LIVE_WINDOW", 30*time.Second),
+		DVRWindow:       env.Duration("LIVE_DVR_WINDOW", 0),
+		KeepSegments:    os.Getenv("LIVE_KEEP_SEGMENTS") == "true",
+		RecordInterval:  env.Duration("LIVE_RECORD_INTERVAL", 0),
+		RecordAligned:   os.Getenv("LIVE_RECORD_ALIGN") != "false",
+		AudioGroups:     os.Getenv("LIVE_AUDIO_GROUPS") == "true",
+		AudioLanguage:   os.Getenv("LIVE_AUDIO_LANGUAGE"),
+		MaxSessions:     env.Int("LIVE_MAX_SESSIONS", 0),
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.Getenv("OUTPUT_DIR"), "live")
+	}
+	if cfg.SegmentDuration <= 0 {
+		cfg.SegmentDuration = 4 * time.Second
+	}
+	return cfg
+}
+
+// segments는 window 길이를 segment 수로 바꿉니다. HLS 권장에 따라 최소 3개입니다.
+func (c liveConfig) segments(window time.Duration) int {
+	n := int(math.Ceil(window.Seconds() / c.SegmentDuration.Seconds()))
+	return max(n, 3)
+}
+
+// liveLadder는 HLS(MPEG-TS)로 내보낼 H.264/AAC 화질 목록을 만듭니다.
+// 다른 코덱 화질은 fallbackQuality로 바꾸고, 높이가 같은 화질은 처음 것만 사용합니다.
+func liveLadder(ladder []VideoQuality) []VideoQuality {
+	seen := make(map[int]bool)
+	var renditions []VideoQuality
+	for _, q := range ladder {
+		if seen[q.Height] {
+			continue
+		}
+		seen[q.Height] = true
+		if q.Codec != "h264" || q.AudioCodec != "aac" {
+			q = fallbackQuality(q)
+		}
+		// 실시간보다 빠르게 변환해야 하므로 항상 빠른 preset 사용
+		q.preset = "veryfast"
+		renditions = append(renditions, q)
+	}
+	return renditions
+}
+
+// liveAudioRenditions는 audio group으로 내보낼 음성 전용 화질입니다. MPEG-TS segment에 넣을 수 있는 AAC만 사용합니다.
+func liveAudioRenditions(cfg liveConfig, renditions []AudioRendition) []AudioRendition {
+	if !cfg.AudioGroups {
+		return nil
+	}
+	var audio []AudioRendition
+	for _, r := range renditions {
+		if r.Codec == "aac" {
+			audio = append(audio, r)
+		}
+	}
+	return audio
+}
+
+// liveH264Level은 화질 높이에 맞는 H.264 level(×10)로, Live 변환에서 지정하고 master 재생목록 CODECS에 씁니다.
+func liveH264Level(height int) int {
+	switch {
+	case height <= 480:
+		return 30
+	case height <= 720:
+		return 31
+	case height <= 1080:
+		return 40
+	case height <= 1440:
+		return 50
+	default:
+		return 51
+	}
+}
+
+// liveCodecs는 master 재생목록의 CODECS 값입니다 (H.264 High profile, AAC-LC).
+func liveCodecs(q VideoQuality) string {
+	return fmt.Sprintf("avc1.6400%02x,mp4a.40.2", liveH264Level(q.Height))
+}
+
+// liveResolution은 master 재생목록의 RESOLUTION 값입니다.
+// live 입력은 미리 분석하지 않으므로 16:9로 가정하여 높이에서 너비를 구합니다 (짝수로 올림).
+func liveResolution(q VideoQuality) string {
+	width := (q.Height*16/9 + 1) &^ 1
+	return fmt.Sprintf("%dx%d", width, q.Height)
+}
+
+// liveAudioDir은 audio group 출력 디렉토리입니다 (audio/<화질>/<트랙>).
+func liveAudioDir(r AudioRendition, t audioTrack) string {
+	return filepath.Join(r.Directory, t.Label)
+}
+
+// liveAudioNames는 #EXT-X-MEDIA의 NAME으로, 제목, 언어, label 순으로 쓰되 group 안에서 겹치면 label을 씁니다.
+func liveAudioNames(tracks []audioTrack) []string {
+	names := make([]string, len(tracks))
+	seen := make(map[string]bool)
+	for i, t := range tracks {
+		name := t.Title
+		if name == "" {
+			name = t.Language
+		}
+		if name == "" || seen[name] {
+			name = t.Label
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// liveBandwidth는 master 재생목록의 BANDWIDTH 값으로, 영상과 음성 비트레이트에 MPEG-TS overhead 10%를 더합니다.
+func liveBandwidth(videoBitrate, audioBitrate string) int {
+	video, _ := parseBitrate(videoBitrate)
+	audio, _ := parseBitrate(audioBitrate)
+	return (video + audio) * 1100
+}
+
+// liveSegment는 재생목록의 segment 하나입니다.
+type liveSegment struct {
+	Sequence        int64
+	Duration        float64
+	URI             string
+	ProgramDateTime string // #EXT-X-PROGRAM-DATE-TIME 값 (없으면 비어 있음)
+}
+
+// liveVariant는 화질(영상 화질 또는 audio group) 하나의 segment 목록입니다.
+type liveVariant struct {
+	name           string
+	quality        VideoQuality
+	audio          AudioRendition // audio group이면 quality 대신 사용
+	track          audioTrack     // audio group의 원본 음성 트랙
+	trackName      string         // #EXT-X-MEDIA NAME
+	dir            string
+	segments       []liveSegment // 아직 지우지 않은 segment
+	next           int64         // 다음에 추가할 sequence
+	produced       int64         // 지금까지 만들어진 segment 수
+	duration       float64       // 지금까지 만들어진 길이(초)
+	targetDuration int           // 재생목록을 갱신하는 동안 줄어들면 안 되므로 최댓값 유지
+}
+
+// livePlaylists는 transcoder가 쓰는 source 재생목록을 읽어 공개 재생목록(index, dvr, vod)을 만들고,
+// window 밖으로 밀려난 segment를 지웁니다.
+type livePlaylists struct {
+	cfg      liveConfig
+	dir      string
+	window   int // index.m3u8의 segment 수
+	dvr      int // dvr.m3u8의 segment 수 (0이면 만들지 않음)
+	variants []*liveVariant
+	groups   []AudioRendition // audio group (비어 있으면 영상 화질에 음성 포함)
+	audio    []*liveVariant   // audio group별, 음성 트랙별 출력
+}
+
+// newLivePlaylists는 화질별 디렉토리를 만들고 master 재생목록을 씁니다.
+// audio group마다 tracks의 음성 트랙을 하나씩 출력합니다.
+func newLivePlaylists(cfg liveConfig, dir string, renditions []VideoQuality, audio []AudioRendition, tracks []audioTrack) (*livePlaylists, error) {
+	p := &livePlaylists{cfg: cfg, dir: dir, window: cfg.segments(cfg.Window)}
+	if cfg.DVRWindow > 0 {
+		p.dvr = cfg.segments(cfg.DVRWindow)
+	}
+	targetDuration := int(math.Ceil(cfg.SegmentDuration.Seconds()))
+	for _, q := range renditions {
+		p.variants = append(p.variants, &liveVariant{name: q.Name, quality: q, dir: filepath.Join(dir, q.Directory), targetDuration: targetDuration})
+	}
+	names := liveAudioNames(tracks)
+	for _, r := range audio {
+		p.groups = append(p.groups, r)
+		for i, t := range tracks {
+			p.audio = append(p.audio, &liveVariant{name: audioKey(r, t), audio: r, track: t, trackName: names[i],
+				dir: filepath.Join(dir, liveAudioDir(r, t)), targetDuration: targetDuration})
+		}
+	}
+	for _, v := range p.all() {
+		if err := os.MkdirAll(v.dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.writeMaster(p.masterPath(liveMediaPlaylist), liveMediaPlaylist); err != nil {
+		return nil, err
+	}
+	if p.dvr > 0 {
+		if err := p.writeMaster(p.masterPath(liveDVRPlaylist), liveDVRPlaylist); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// masterPath는 화질별 재생목록 이름에 대응하는 master 재생목록 경로입니다 (index → master.m3u8, dvr → master_dvr.m3u8).
+func (p *livePlaylists) masterPath(playlist string) string {
+	name := "master.m3u8"
+	if playlist != liveMediaPlaylist {
+		name = "master_" + playlist
+	}
+	return filepath.Join(p.dir, name)
+}
+
+// all은 영상 화질과 audio group 전체입니다.
+func (p *livePlaylists) all() []*liveVariant {
+	return append(append([]*liveVariant(nil), p.variants...), p.audio...)
+}
+
+// writeMaster는 master 재생목록을 씁니다. audio group이 있으면 group마다 음성 트랙별 #EXT-X-MEDIA를 쓰고
+// 영상 화질을 group별로 한 번씩 나열하여 player가 대역폭에 맞는 음성 화질을 고르게 합니다.
+func (p *livePlaylists) writeMaster(path, playlist string) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if len(p.audio) == 0 {
+		for _, v := range p.variants {
+			fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=%q,RESOLUTION=%s\n%s/%s\n",
+				liveBandwidth(v.quality.Bitrate, v.quality.AudioBitrate), liveCodecs(v.quality), liveResolution(v.quality), v.quality.Directory, playlist)
+		}
+		return writeFileAtomic(path, []byte(b.String()))
+	}
+
+	for _, a := range p.audio {
+		var language string
+		if a.track.Language != "" {
+			language = fmt.Sprintf(",LANGUAGE=%q", a.track.Language)
+		}
+		isDefault := "NO"
+		if a.track.Default {
+			isDefault = "YES"
+		}
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=%q,NAME=%q%s,DEFAULT=%s,AUTOSELECT=YES,URI=\"%s/%s\"\n",
+			a.audio.Name, a.trackName, language, isDefault, filepath.ToSlash(liveAudioDir(a.audio, a.track)), playlist)
+	}
+	for _, g := range p.groups {
+		for _, v := range p.variants {
+			fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=%q,RESOLUTION=%s,AUDIO=%q\n%s/%s\n",
+				liveBandwidth(v.quality.Bitrate, g.Bitrate), liveCodecs(v.quality), liveResolution(v.quality), g.Name, v.quality.Directory, playlist)
+		}
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// run은 ctx가 끝날 때까지 segment 길이의 절반마다 재생목록을 갱신합니다.
+func (p *livePlaylists) run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(p.cfg.SegmentDuration / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.sync(false); err != nil {
+				logger.Warn("failed to update live playlists", "error", err)
+			}
+		}
+	}
+}
+
+// finish는 마지막 segment까지 반영하여 모든 재생목록을 #EXT-X-ENDLIST로 마무리합니다.
+// KeepSegments이면 방송 전체 VOD 재생목록도 씁니다.
+// run이 끝난 후 호출해야 합니다.
+func (p *livePlaylists) finish() (*pb.LiveOutput, error) {
+	if err := p.sync(true); err != nil {
+		return nil, err
+	}
+	out := &pb.LiveOutput{MasterPlaylist: p.masterPath(liveMediaPlaylist)}
+	if p.cfg.KeepSegments {
+		if err := p.writeMaster(p.masterPath(liveVODPlaylist), liveVODPlaylist); err != nil {
+			return nil, err
+		}
+		out.VodPlaylist = p.masterPath(liveVODPlaylist)
+	}
+	if p.dvr > 0 {
+		out.DvrPlaylist = p.masterPath(liveDVRPlaylist)
+	}
+	if len(p.variants) > 0 {
+		out.Segments = p.variants[0].produced
+		out.DurationSeconds = p.variants[0].duration
+	}
+	return out, nil
+}
+
+// sync는 화질마다 source 재생목록의 새 segment를 추가하고 공개 재생목록을 다시 씁니다.
+func (p *livePlaylists) sync(ended bool) error {
+	var errs []error
+	for _, v := range p.all() {
+		if err := p.syncVariant(v, ended); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", v.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *livePlaylists) syncVariant(v *liveVariant, ended bool) error {
+	data, err := os.ReadFile(filepath.Join(v.dir, liveSourcePlaylist))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	added := 0
+	for _, seg := range parseMediaPlaylist(data) {
+		if seg.Sequence < v.next {
+			continue
+		}
+		v.segments = append(v.segments, seg)
+		v.next = seg.Sequence + 1
+		v.produced++
+		v.duration += seg.Duration
+		v.targetDuration = max(v.targetDuration, int(math.Ceil(seg.Duration)))
+		added++
+	}
+	if added > 0 {
+		liveSegmentsTotal.WithLabelValues(v.name).Add(float64(added))
+	}
+
+	// 재생목록에서 빠진 segment도 다운로드 중인 player를 위해 2개 더 남겨 둠
+	if !p.cfg.KeepSegments {
+		keep := max(p.window, p.dvr) + 2
+		if len(v.segments) > keep {
+			for _, seg := range v.segments[:len(v.segments)-keep] {
+				os.Remove(filepath.Join(v.dir, seg.URI))
+			}
+			v.segments = append([]liveSegment(nil), v.segments[len(v.segments)-keep:]...)
+		}
+	}
+
+	if len(v.segments) == 0 && !ended {
+		return nil
+	}
+	if err := writeMediaPlaylist(filepath.Join(v.dir, liveMediaPlaylist), lastSegments(v.segments, p.window), v.targetDuration, "", ended); err != nil {
+		return err
+	}
+	if p.dvr > 0 {
+		if err := writeMediaPlaylist(filepath.Join(v.dir, liveDVRPlaylist), lastSegments(v.segments, p.dvr), v.targetDuration, "", ended); err != nil {
+			return err
+		}
+	}
+	// window 밖 segment를 지웠다면 VOD 재생목록이 방송 일부만 담게 되므로 쓰지 않음
+	if ended && p.cfg.KeepSegments {
+		return writeMediaPlaylist(filepath.Join(v.dir, liveVODPlaylist), v.segments, v.targetDuration, "VOD", true)
+	}
+	return nil
+}
+
+func lastSegments(segments []liveSegment, n int) []liveSegment {
+	return segments[max(len(segments)-n, 0):]
+}
+
+// parseMediaPlaylist는 HLS media 재생목록에서 segment 목록을 읽습니다.
+func parseMediaPlaylist(data []byte) []liveSegment {
+	var segments []liveSegment
+	var sequence int64
+	var seg liveSegment
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			sequence, _ = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+		case strings.HasPrefix(line, "#EXTINF:"):
+			duration, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			seg.Duration, _ = strconv.ParseFloat(duration, 64)
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			seg.ProgramDateTime = strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:")
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			seg.URI = line
+			seg.Sequence = sequence + int64(len(segments))
+			segments = append(segments, seg)
+			seg = liveSegment{}
+		}
+	}
+	return segments
+}
+
+// writeMediaPlaylist는 segments로 media 재생목록을 씁니다. playlistType이 비어 있으면 live 재생목록입니다.
+func writeMediaPlaylist(path string, segments []liveSegment, targetDuration int, playlistType string, ended bool) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", targetDuration)
+	var sequence int64
+	if len(segments) > 0 {
+		sequence = segments[0].Sequence
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", sequence)
+	if playlistType != "" {
+		fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", playlistType)
+	}
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, seg := range segments {
+		if seg.ProgramDateTime != "" {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", seg.ProgramDateTime)
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", seg.Duration, seg.URI)
+	}
+	if ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// writeFileAtomic은 임시 파일에 쓴 후 rename하여 읽는 쪽이 쓰다 만 파일을 보지 않도록 합니다.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// streamLive는 live 업로드를 임시 파일 없이 받는 즉시 transcoder로 보내 화질별 HLS segment를 만듭니다.
+// 업로드가 끝나거나 중단되면 재생목록을 #EXT-X-ENDLIST로 마무리합니다 (LIVE_KEEP_SEGMENTS이면 VOD 재생목록도 씀).
+// 변환이 끝날 때까지 VOD 작업 슬롯과 별개인 live 슬롯 하나를 점유합니다 (LIVE_MAX_SESSIONS).
+func (s *server) streamLive(ctx context.Context, cancel context.CancelCauseFunc, stream pb.VideoStreamingService_StreamVideoServer,
+	sessionID string, logger *slog.Logger, format mediatype.Format, recv func() (*pb.VideoChunk, error), callbackURL string) error {
+	if !format.Streamable() {
+		logger.Warn("live upload rejected", "reason", "format cannot be streamed")
+		return status.Errorf(codes.InvalidArgument, "%s cannot be ingested live (use ts, flv, mkv or webm)", format.Name)
+	}
+
+	// live는 실시간으로 변환해야 하므로 VOD 작업 슬롯을 쓰지 않고(녹화 변환 작업이 슬롯을 얻을 수 있도록)
+	// 별도 live 슬롯이 없으면 기다리지 않고 바로 거절
+	release, ok := s.acquireLive()
+	if !ok {
+		logger.Warn("live upload rejected, all live slots busy", "max_sessions", s.live.MaxSessions)
+		return status.Errorf(codes.ResourceExhausted, "all %d live sessions busy", s.live.MaxSessions)
+	}
+	defer release()
+
+	renditions := liveLadder(qualities)
+	audio := liveAudioRenditions(s.live, s.audio.Renditions)
+	dir := filepath.Join(s.live.Dir, sessionID)
+	logger = logger.With("live_dir", dir)
+
+	info := &ProcessingInfo{
+		filename:    sessionID,
+		requestID:   logging.RequestID(ctx),
+		jobID:       incomingValue(ctx, webhook.JobIDHeader),
+		tenant:      incomingValue(ctx, tenantHeader),
+		callbackURL: callbackURL,
+		format:      format.Name,
+		started:     time.Now(),
+		cancel:      func() { cancel(errCancelledByAdmin) },
+		converted:   make(map[string]bool),
+		live:        true,
+	}
+	s.mu.Lock()
+	s.activeProcessings[sessionID] = info
+	s.mu.Unlock()
+	activeSessionsGauge.Inc()
+	liveStreamsGauge.Inc()
+	defer func() {
+		s.finishJob(sessionID, info)
+		activeSessionsGauge.Dec()
+		liveStreamsGauge.Dec()
+	}()
+
+	var tracks []audioTrack
+	if len(audio) > 0 {
+		tracks, recv = s.probeLiveAudio(ctx, logger, sessionID, format, recv)
+	}
+	playlists, err := newLivePlaylists(s.live, dir, renditions, audio, tracks)
+	if err != nil {
+		return fmt.Errorf("failed to create live output directory: %v", err)
+	}
+	var recordDir string
+	if s.live.RecordInterval > 0 {
+		recordDir = filepath.Join(dir, "recordings")
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			return fmt.Errorf("failed to create live recording directory: %v", err)
+		}
+	}
+
+	names := make([]string, len(renditions))
+	for i, q := range renditions {
+		names[i] = q.Name
+	}
+	started := liveStartedData{Format: format.Name, Qualities: names, Playlist: playlists.masterPath(liveMediaPlaylist)}
+	for _, r := range audio {
+		started.AudioGroups = append(started.AudioGroups, r.Name)
+	}
+	if playlists.dvr > 0 {
+		started.DVRPlaylist = playlists.masterPath(liveDVRPlaylist)
+	}
+	s.notify(sessionID, info, webhook.LiveStarted, started)
+	logger.Info("live stream started", "renditions", strings.Join(names, ","), "audio_groups", len(audio), "audio_tracks", len(tracks), "segment", s.live.SegmentDuration,
+		"window", playlists.window, "dvr", playlists.dvr)
+
+	// transcoder가 먼저 끝나면 pipe를 닫아 업로드 쓰기가 실패하도록 함
+	input, output := io.Pipe()
+	encodeDone := make(chan error, 1)
+	go func() {
+		err := s.transcoder.Live(ctx, logger, LiveJob{
+			Input:           input,
+			Format:          format.Name,
+			Dir:             dir,
+			Renditions:      renditions,
+			Audio:           audio,
+			AudioTracks:     tracks,
+			SegmentDuration: s.live.SegmentDuration,
+			ListSize:        liveSourceListSize,
+			RecordDir:       recordDir,
+			RecordInterval:  s.live.RecordInterval,
+			RecordAligned:   s.live.RecordAligned,
+			Progress: newProgressParser(0, func(p encodeProgress) {
+				s.updateJob(sessionID, info, func() { info.progress = p })
+			}),
+		})
+		input.CloseWithError(errLiveEncoderStopped)
+		encodeDone <- err
+	}()
+	recorder := &liveRecorder{s: s, dir: recordDir, sessionID: sessionID, info: info}
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	pollDone := make(chan struct{})
+	go func() {
+		playlists.run(pollCtx, logger)
+		close(pollDone)
+	}()
+	recordDone := make(chan struct{})
+	go func() {
+		if recordDir != "" {
+			recorder.run(pollCtx, logger, 10*time.Second)
+		}
+		close(recordDone)
+	}()
+
+	// 업로드가 EOF로 끝나면 transcoder가 남은 입력을 마저 변환하고 종료
+	recvErr := s.receiveLive(ctx, info, recv, output)
+	output.CloseWithError(recvErr)
+	encodeErr := <-encodeDone
+	stopPolling()
+	<-pollDone
+	<-recordDone
+
+	// 마지막 녹화는 업로드가 중단된 경우에도 변환 (transcoder가 segment list에 추가한 경우)
+	if recordDir != "" {
+		if err := recorder.sync(logger); err != nil {
+			logger.Error("failed to queue live recordings", "error", err)
+		}
+	}
+
+	result, err := playlists.finish()
+	if err != nil {
+		logger.Error("failed to finalize live playlists", "error", err)
+		result = &pb.LiveOutput{}
+	}
+	ended := liveEndedData{VODPlaylist: result.VodPlaylist, Segments: result.Segments, DurationSeconds: result.DurationSeconds}
+	logger = logger.With("segments", result.Segments, "duration", result.DurationSeconds)
+
+	if ctx.Err() != nil {
+		logger.Warn("live stream cancelled", "cause", context.Cause(ctx))
+		ended.Message = context.Cause(ctx).Error()
+		s.notify(sessionID, info, webhook.LiveEnded, ended)
+		return cancelStatus(ctx)
+	}
+	switch {
+	case encodeErr != nil:
+		err = encodeErr
+	case recvErr != nil:
+		err = fmt.Errorf("error receiving chunk: %v", recvErr)
+	}
+	if err != nil {
+		logger.Error("live stream failed", "error", err)
+		ended.Message = err.Error()
+		s.notify(sessionID, info, webhook.LiveEnded, ended)
+		return err
+	}
+
+	ended.Message = fmt.Sprintf("Live stream ended after %.0fs (%d segments)", result.DurationSeconds, result.Segments)
+	logger.Info("live stream ended", "vod_playlist", result.VodPlaylist)
+	s.notify(sessionID, info, webhook.LiveEnded, ended)
+	return stream.SendAndClose(&pb.StreamResponse{Success: true, Message: ended.Message, Live: result})
+}
+
+// probeLiveAudio는 업로드 앞부분(liveProbeBytes)을 받아 음성 트랙을 분석하고, AUDIO_TRACKS에 따라 audio group에 넣을 트랙을 고릅니다.
+// 받아 둔 청크는 반환하는 recv가 먼저 돌려줍니다. 분석하지 못하면 첫 번째 음성 트랙 하나를 LIVE_AUDIO_LANGUAGE로 씁니다.
+func (s *server) probeLiveAudio(ctx context.Context, logger *slog.Logger, sessionID string, format mediatype.Format,
+	recv func() (*pb.VideoChunk, error)) ([]audioTrack, func() (*pb.VideoChunk, error)) {
+	var chunks []*pb.VideoChunk
+	var recvErr error
+	var size int
+	for size < liveProbeBytes {
+		chunk, err := recv()
+		if err != nil {
+			recvErr = err
+			break
+		}
+		chunks = append(chunks, chunk)
+		size += len(chunk.Data)
+	}
+	replay := func() (*pb.VideoChunk, error) {
+		if len(chunks) > 0 {
+			chunk := chunks[0]
+			chunks = chunks[1:]
+			return chunk, nil
+		}
+		if recvErr != nil {
+			return nil, recvErr
+		}
+		return recv()
+	}
+
+	var tracks []audioTrack
+	path := filepath.Join(os.Getenv("TEMP_DIR"), "live_probe_"+sessionID+"."+format.Name)
+	if err := writeChunks(path, chunks); err != nil {
+		logger.Warn("failed to write live probe data", "error", err)
+	} else {
+		// PROBE_TIMEOUT: ffprobe 1회 실행 제한 시간
+		probeCtx, cancel := context.WithTimeout(ctx, env.Duration("PROBE_TIMEOUT", 30*time.Second))
+		meta, err := s.transcoder.Probe(probeCtx, path)
+		cancel()
+		os.Remove(path)
+		if meta != nil {
+			// 앞부분만으로는 영상 스트림을 찾지 못할 수 있으므로 ErrNoVideo여도 음성 트랙은 사용
+			tracks = selectAudioTracks(meta.Audio, s.audio.Tracks)
+		} else {
+			logger.Warn("failed to probe live audio tracks", "error", err)
+		}
+	}
+	if len(tracks) == 0 {
+		label := s.live.AudioLanguage
+		if label == "" {
+			label = "a1"
+		}
+		return []audioTrack{{Index: -1, Label: label, Language: s.live.AudioLanguage, Default: true}}, replay
+	}
+	return tracks, replay
+}
+
+// writeChunks는 청크를 순서대로 path에 씁니다.
+func writeChunks(path string, chunks []*pb.VideoChunk) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := f.Write(chunk.Data); err != nil {
+			f.Close()
+			os.Remove(path)
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// receiveLive는 업로드가 끝날 때까지 청크를 w로 보냅니다. EOF면 nil을 반환합니다.
+func (s *server) receiveLive(ctx context.Context, info *ProcessingInfo, recv func() (*pb.VideoChunk, error), w io.Writer) error {
+	for {
+		chunk, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil && ctx.Err() != nil {
+			// 관리 API로 취소된 경우 gateway 스트림은 계속 열려 있으므로 다음 청크에서 중단
+			err = ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		n, err := w.Write(chunk.Data)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		info.totalBytes += int64(n)
+		s.mu.Unlock()
+		bytesReceived.Add(float64(n))
+		chunksReceived.Inc()
+	}
+}
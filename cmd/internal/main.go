@@ -1,197 +1,95 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
 	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/admin"
+	"github.com/ket0825/grpc-streaming/internal/healthcheck"
+	"github.com/ket0825/grpc-streaming/internal/lifecycle"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/metrics"
+	"github.com/ket0825/grpc-streaming/internal/ops"
+	"github.com/ket0825/grpc-streaming/internal/tlsconfig"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
 )
 
-type VideoQuality struct {
-	Name      string
-	Height    int
-	Bitrate   string
-	Directory string
-}
-
-var qualities = []VideoQuality{
-	{Name: "1080p", Height: 1080, Bitrate: "5000k", Directory: "1080p"},
-	{Name: "720p", Height: 720, Bitrate: "2500k", Directory: "720p"},
-	{Name: "480p", Height: 480, Bitrate: "1000k", Directory: "480p"},
-	{Name: "360p", Height: 360, Bitrate: "750k", Directory: "360p"},
-}
+func main() {
+	// FFMPEG_NICE, FFMPEG_MAX_MEMORY_BYTES를 적용하여 ffmpeg를 실행하는 shim으로 실행된 경우 여기서 ffmpeg로 exec
+	runLimitShim()
 
-type server struct {
-	pb.UnimplementedVideoStreamingServiceServer
-	mu                sync.Mutex
-	activeProcessings map[string]*ProcessingInfo
-}
+	// if err := godotenv.Load("../../.env"); err != nil {
+	// 	log.Fatal("Error loading .env file")
+	// }
 
-type ProcessingInfo struct {
-	file       *os.File
-	totalBytes int64
-	filename   string
-}
+	// LOG_LEVEL, LOG_FORMAT으로 로그 레벨과 형식 설정 (ffmpeg 출력은 debug 레벨)
+	logging.Setup("grpc-internal")
 
-func NewInternalServer() *server {
-	return &server{
-		activeProcessings: make(map[string]*ProcessingInfo),
+	// TRANSCODER=fake면 ffmpeg 없이 결정적인 출력을 만드는 backend 사용 (로컬 실행, 테스트)
+	transcoder, err := transcoderFromEnv()
+	if err != nil {
+		logging.Fatal("invalid transcoder", "error", err)
 	}
-}
-
-func (s *server) StreamVideo(stream pb.VideoStreamingService_StreamVideoServer) error {
-	sessionID := fmt.Sprintf("process_%d", time.Now().UnixNano())
-	log.Printf("Starting new processing session: %s", sessionID)
-
-	// 임시 디렉토리 생성
-	tempDir := os.Getenv("TEMP_DIR")
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		return fmt.Errorf("failed to create temp directory: %v", err)
+	if err := transcoder.Check(context.Background()); err != nil {
+		logging.Fatal("FFmpeg is not installed. Please install FFmpeg first.", "error", err)
+	}
+	if _, ok := transcoder.(*fakeTranscoder); ok {
+		slog.Warn("using fake transcoder, outputs are placeholders")
 	}
 
-	// 임시 파일 생성
-	fileName := fmt.Sprintf("video_%s.mp4", sessionID)
-	tempPath := filepath.Join(tempDir, fileName)
-	file, err := os.Create(tempPath)
+	// 화질 목록을 읽고 ffmpeg가 지원하는 encoder로 확인 (지원하지 않는 화질은 작업마다 실패하지 않도록 시작 시 거절)
+	ladder, err := loadLadder()
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %v", err)
+		logging.Fatal("invalid rendition ladder", "error", err)
 	}
-
-	// 처리 정보 저장
-	s.mu.Lock()
-	s.activeProcessings[sessionID] = &ProcessingInfo{
-		file:     file,
-		filename: fileName,
+	encoders, err := transcoder.Encoders(context.Background())
+	if err != nil {
+		logging.Fatal("failed to detect ffmpeg capabilities", "error", err)
 	}
-	s.mu.Unlock()
-
-	defer func() {
-		file.Close()
-		s.mu.Lock()
-		delete(s.activeProcessings, sessionID)
-		s.mu.Unlock()
-		// 임시 파일 삭제
-		os.Remove(tempPath)
-	}()
-
-	// 청크 수신 및 파일 저장
-	totalBytes := int64(0)
-	chunks := 0
-	for {
-		chunk, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("error receiving chunk: %v", err)
-		}
-
-		n, err := file.Write(chunk.Data)
-		if err != nil {
-			return fmt.Errorf("failed to write chunk: %v", err)
-		}
-
-		totalBytes += int64(n)
-		chunks++
-
-		if chunks%1000 == 0 {
-			log.Printf("Session %s: Received %d chunks, %d bytes",
-				sessionID, chunks, totalBytes)
-		}
+	// LADDER_SKIP_UNSUPPORTED=true면 지원하지 않는 화질을 제외하고 시작
+	qualities, err = resolveLadder(ladder, encoders, os.Getenv("LADDER_SKIP_UNSUPPORTED") == "true")
+	if err != nil {
+		logging.Fatal("unsupported rendition ladder", "error", err)
 	}
-
-	log.Printf("Received complete video for %s: %d bytes in %d chunks",
-		sessionID, totalBytes, chunks)
-
-	// 파일을 닫고 다시 열어서 변환 시작
-	file.Close()
-
-	// 화질별 변환 시작
-	baseDir := os.Getenv("OUTPUT_DIR")
-	successCount := 0
-	var conversionErrors []string
-
-	// 각 화질별로 변환
-	for _, quality := range qualities {
-		qualityDir := filepath.Join(baseDir, quality.Directory)
-		if err := os.MkdirAll(qualityDir, 0755); err != nil {
-			log.Printf("Failed to create directory for %s: %v", quality.Name, err)
-			conversionErrors = append(conversionErrors,
-				fmt.Sprintf("%s: directory creation failed", quality.Name))
-			continue
-		}
-
-		outputPath := filepath.Join(qualityDir, fileName)
-		if err := convertVideo(tempPath, outputPath, quality); err != nil {
-			log.Printf("Failed to convert to %s: %v", quality.Name, err)
-			conversionErrors = append(conversionErrors,
-				fmt.Sprintf("%s: conversion failed", quality.Name))
-			continue
-		}
-
-		successCount++
-		log.Printf("Successfully converted to %s: %s", quality.Name, outputPath)
+	for _, q := range qualities {
+		slog.Info("rendition", "name", q.Name, "height", q.Height, "bitrate", q.Bitrate,
+			"encoder", q.encoder, "container", q.Container, "audio_encoder", q.audioEncoder)
 	}
 
-	// 결과 메시지 생성
-	var message string
-	if successCount == len(qualities) {
-		message = fmt.Sprintf("Successfully converted video to all %d qualities", successCount)
-	} else if successCount > 0 {
-		message = fmt.Sprintf("Partially converted video to %d/%d qualities. Errors: %v",
-			successCount, len(qualities), conversionErrors)
-	} else {
-		message = fmt.Sprintf("Failed to convert video. Errors: %v", conversionErrors)
+	// 음성 트랙 선택, EBU R128 음량 정규화(LOUDNORM), 음성 전용 화질(AUDIO_RENDITIONS)
+	audio, err := audioConfigFromEnv()
+	if err != nil {
+		logging.Fatal("invalid audio configuration", "error", err)
 	}
-
-	return stream.SendAndClose(&pb.StreamResponse{
-		Success: successCount > 0,
-		Message: message,
-	})
-}
-
-func convertVideo(inputPath, outputPath string, quality VideoQuality) error {
-	log.Printf("Converting to %s: %s", quality.Name, outputPath)
-
-	cmd := exec.Command("ffmpeg",
-		"-i", inputPath,
-		"-vf", fmt.Sprintf("scale=-2:%d", quality.Height),
-		"-b:v", quality.Bitrate,
-		"-c:v", "libx264",
-		"-preset", "medium",
-		"-c:a", "aac",
-		"-b:a", "128k",
-		"-y",
-		outputPath,
-	)
-
-	output, err := cmd.CombinedOutput()
+	audio.Renditions, err = resolveAudioRenditions(audio.Renditions, encoders, os.Getenv("LADDER_SKIP_UNSUPPORTED") == "true")
 	if err != nil {
-		return fmt.Errorf("conversion failed: %v\nOutput: %s", err, string(output))
+		logging.Fatal("unsupported audio renditions", "error", err)
+	}
+	for _, r := range audio.Renditions {
+		slog.Info("audio rendition", "name", r.Name, "bitrate", r.Bitrate, "encoder", r.encoder, "container", r.Container)
+	}
+	if audio.Loudnorm {
+		slog.Info("loudness normalization enabled", "integrated", audio.Target.I, "true_peak", audio.Target.TP, "range", audio.Target.LRA)
 	}
 
-	return nil
-}
-
-func main() {
-	// if err := godotenv.Load("../../.env"); err != nil {
-	// 	log.Fatal("Error loading .env file")
-	// }
-
-	// FFmpeg 설치 확인
-	_, err := exec.LookPath("ffmpeg")
+	// OpenTelemetry tracing (OTEL_EXPORTER_OTLP_ENDPOINT가 있으면 span 전송)
+	shutdownTracing, err := tracing.Setup(context.Background(), "grpc-internal")
 	if err != nil {
-		log.Fatal("FFmpeg is not installed. Please install FFmpeg first.")
+		logging.Fatal("failed to set up tracing", "error", err)
 	}
+	defer shutdownTracing(context.Background())
 
 	INTERNAL_PORT := os.Getenv("INTERNAL_PORT")
 	INTERNAL_HOST := os.Getenv("INTERNAL_HOST")
@@ -199,7 +97,7 @@ func main() {
 
 	lis, err := net.Listen("tcp", internalAddr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("failed to listen", "addr", internalAddr, "error", err)
 	}
 
 	opts := []grpc.ServerOption{
@@ -211,13 +109,183 @@ func main() {
 		}),
 		grpc.MaxRecvMsgSize(1024 * 1024 * 50), // 50MB
 		grpc.MaxSendMsgSize(1024 * 1024 * 50), // 50MB
+		tracing.ServerOption(),
+	}
+
+	// TLS_CERT_FILE, TLS_KEY_FILE이 있으면 TLS로 수신 (TLS_CLIENT_CA_FILE: gateway 인증서 검증)
+	serverTLS, err := tlsconfig.ServerFromEnv("TLS_")
+	if err != nil {
+		logging.Fatal("failed to configure TLS", "error", err)
+	}
+	if serverTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLS)))
+		slog.Info("TLS enabled", "client_ca", os.Getenv("TLS_CLIENT_CA_FILE"))
 	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor(), logging.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor(), logging.StreamServerInterceptor()),
+	)
+
+	maxJobs := 0
+	if v := os.Getenv("MAX_CONCURRENT_JOBS"); v != "" {
+		if maxJobs, err = strconv.Atoi(v); err != nil {
+			logging.Fatal("invalid MAX_CONCURRENT_JOBS", "value", v, "error", err)
+		}
+	}
+
+	// 대표 이미지, 썸네일, sprite sheet
+	thumbnails, err := thumbnailConfigFromEnv()
+	if err != nil {
+		logging.Fatal("invalid thumbnail configuration", "error", err)
+	}
+
+	// 작업 이벤트 webhook (WEBHOOK_URL 또는 업로드별 x-callback-url)
+	webhookCfg, err := webhook.ConfigFromEnv()
+	if err != nil {
+		logging.Fatal("failed to configure webhooks", "error", err)
+	}
+	notifier := webhook.New(webhookCfg, func(eventType, result string) {
+		webhookDeliveries.WithLabelValues(eventType, result).Inc()
+	})
+	notifier.Start()
+
+	internalServer := NewInternalServer(serverOptions{
+		checkpointDir: os.Getenv("CHECKPOINT_DIR"), // 종료 시 끝나지 않은 변환 작업을 넘겨줄 공유 디렉토리
+		maxJobs:       maxJobs,
+		policy:        mediatype.PolicyFromEnv(), // 허용 컨테이너, content_type, 최대 크기
+		notifier:      notifier,
+		thumbnails:    thumbnails,
+		transcoder:    transcoder,
+		timeouts:      encodeTimeoutsFromEnv(), // 원본 길이 기반 화질별 변환 제한 시간
+		retries:       retryPolicyFromEnv(),    // 실패한 화질 자동 재시도
+		retention:     retentionFromEnv(),      // 부분 실패 시 원본 보관 (관리 API RetryJob)
+		live:          liveConfigFromEnv(),     // live 업로드(x-live)의 HLS sliding window, DVR window
+		audio:         audio,
+	})
 
 	s := grpc.NewServer(opts...)
-	pb.RegisterVideoStreamingServiceServer(s, NewInternalServer())
+	pb.RegisterVideoStreamingServiceServer(s, internalServer)
+
+	// grpc.health.v1
+	// "" (서버 전체): ffmpeg 설치(transcoder 사용 가능) 및 디스크 쓰기 가능 여부
+	// streaming.VideoStreamingService: 위 조건 + 작업 큐가 멈춰 있지 않음 (gateway가 백엔드 선택에 사용)
+	// 작업 슬롯이 모두 사용 중인 것은 장애가 아니므로 반영하지 않고, 부하는 gateway의 least-active 선택과 작업 큐가 분산
+	checker := healthcheck.New(5 * time.Second)
+	serviceName := pb.VideoStreamingService_ServiceDesc.ServiceName
+	for _, service := range []string{"", serviceName} {
+		checker.Add(service, "ffmpeg", transcoder.Check)
+		checker.Add(service, "temp dir", checkWritable(os.Getenv("TEMP_DIR")))
+		checker.Add(service, "output dir", checkWritable(os.Getenv("OUTPUT_DIR")))
+	}
+	checker.Add(serviceName, "queue", internalServer.checkQueue) // 큐가 멈춰 있으면 NOT_SERVING
+	checker.Register(s)
+
+	// 관리 API (ADMIN_ADDR이 있으면 별도 포트, 운영자 키로 인증): 작업 목록/취소, 큐 일시정지
+	adminSrv, err := admin.NewFromEnv()
+	if err != nil {
+		logging.Fatal("failed to configure admin server", "error", err)
+	}
+	if adminSrv != nil {
+		pb.RegisterTranscoderAdminServiceServer(adminSrv, &adminServer{jobs: internalServer})
+		if err := adminSrv.Start(); err != nil {
+			logging.Fatal("failed to start admin server", "error", err)
+		}
+		defer adminSrv.Stop()
+	}
 
-	log.Printf("Internal server listening at %v", internalAddr)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	// 운영용 HTTP 서버 (readiness/liveness probe, prometheus metrics)
+	registerServerMetrics(internalServer, os.Getenv("TEMP_DIR"))
+	opsServer := ops.New(os.Getenv("OPS_ADDR"))
+	opsServer.Handle("/metrics", metrics.Handler())
+	opsServer.Start()
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+	checker.Start(ctx)
+
+	// checkpoint에서 재개한 작업은 gRPC 스트림이 없으므로 별도 context로 관리
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	go internalServer.resumeLoop(jobsCtx, time.Minute)
+	// RetryJob 재변환 실행, 보관 기간이 지난 원본 삭제
+	go internalServer.retainLoop(jobsCtx, 10*time.Minute)
+	// live 업로드 녹화 파일 변환
+	go internalServer.recordLoop(jobsCtx, 10*time.Second)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("internal server listening", "addr", internalAddr)
+		serveErr <- s.Serve(lis)
+	}()
+	opsServer.SetReady(true)
+
+	select {
+	case err := <-serveErr:
+		logging.Fatal("failed to serve", "error", err)
+	case <-ctx.Done():
+	}
+
+	// SIGTERM: not-ready 전환 → 진행 중인 변환 대기 → 시간 초과 시 checkpoint 후 강제 종료
+	slog.Info("shutdown signal received")
+	shutdownCfg := lifecycle.ConfigFromEnv()
+	started := time.Now()
+	forceStop := func() {
+		internalServer.checkpointAll()
+		cancelJobs()
+	}
+	if lifecycle.Drain(s, shutdownCfg, func() {
+		opsServer.SetReady(false)
+		checker.Shutdown()
+	}, forceStop) {
+		// gRPC 스트림은 모두 끝났지만 재개된 백그라운드 작업이 남아 있을 수 있음
+		remaining := shutdownCfg.DrainTimeout - time.Since(started) + shutdownCfg.ReadinessDelay
+		if !waitGroupTimeout(&internalServer.jobs, remaining) {
+			forceStop()
+		}
+	}
+	cancelJobs()
+	internalServer.jobs.Wait()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// 남은 이벤트를 전달하고, 시간 안에 못 보낸 이벤트는 dead-letter로 기록
+	notifier.Stop(shutdownCtx)
+	opsServer.Shutdown(shutdownCtx)
+	slog.Info("internal server stopped")
+}
+
+// checkWritable은 dir에 파일을 만들고 지울 수 있는지 확인하는 검사를 반환합니다.
+func checkWritable(dir string) healthcheck.Check {
+	if dir == "" {
+		dir = "."
+	}
+	return func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		_, err = f.Write([]byte("ok"))
+		f.Close()
+		os.Remove(name)
+		return err
+	}
+}
+
+// waitGroupTimeout은 wg가 끝나면 true, timeout이 먼저 지나면 false를 반환합니다.
+func waitGroupTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
 }
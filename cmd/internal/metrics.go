@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// transcoder 메트릭
+var (
+	activeSessionsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "transcoder_active_sessions",
+		Help: "Number of processing sessions (receiving or converting).",
+	})
+
+	bytesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transcoder_received_bytes_total",
+		Help: "Total bytes received from the gateway.",
+	})
+
+	chunksReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transcoder_received_chunks_total",
+		Help: "Total chunks received from the gateway.",
+	})
+
+	uploadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "transcoder_upload_duration_seconds",
+		Help:    "Time spent receiving the source video.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	})
+
+	encodeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "transcoder_encode_duration_seconds",
+		Help:    "Duration of a single rendition encode, by quality.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"quality"})
+
+	encodeProgressGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "transcoder_encode_progress_percent",
+		Help: "Progress of the rendition currently being encoded, by session and quality.",
+	}, []string{"session_id", "quality"})
+
+	encodeSpeed = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "transcoder_encode_speed_ratio",
+		Help:    "ffmpeg encode speed relative to realtime at the end of a rendition, by quality.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16, 32},
+	}, []string{"quality"})
+
+	encodeTimeoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_encode_timeouts_total",
+		Help: "Total rendition encodes killed after exceeding the duration-based timeout, by quality.",
+	}, []string{"quality"})
+
+	encodeRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_encode_retries_total",
+		Help: "Total automatic rendition encode retries, by quality.",
+	}, []string{"quality"})
+
+	retainedSources = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_retained_sources_total",
+		Help: "Sources kept after partial failures, by event (retained, retried, expired).",
+	}, []string{"event"})
+
+	encodeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_encode_failures_total",
+		Help: "Total failed rendition encodes, by quality.",
+	}, []string{"quality"})
+
+	ffmpegExits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_ffmpeg_exit_total",
+		Help: "ffmpeg process exits by exit code (-1: killed by signal).",
+	}, []string{"code"})
+
+	loudnormAnalyses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_loudnorm_analyses_total",
+		Help: "Loudness analysis passes per audio track, by result (measured, failed).",
+	}, []string{"result"})
+
+	liveStreamsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "transcoder_live_streams",
+		Help: "Number of live uploads being transcoded to HLS.",
+	})
+
+	liveSegmentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_live_segments_total",
+		Help: "Total HLS segments produced for live uploads, by quality.",
+	}, []string{"quality"})
+
+	liveRecordings = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_live_recordings_total",
+		Help: "Live upload recordings by event (queued, converted, failed).",
+	}, []string{"event"})
+
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "transcoder_queue_depth",
+		Help: "Number of sessions waiting for a worker slot.",
+	})
+
+	probeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_probe_failures_total",
+		Help: "Sources rejected by ffprobe, by reason (no_video, unreadable, timeout, ffprobe_missing).",
+	}, []string{"reason"})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transcoder_webhook_deliveries_total",
+		Help: "Webhook delivery attempts by event type and result (delivered, retry, dead_letter).",
+	}, []string{"event", "result"})
+)
+
+// registerServerMetrics는 작업 슬롯과 임시 디스크 사용량을 조회 시점에 계산하는 메트릭을 등록합니다.
+func registerServerMetrics(s *server, tempDir string) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "transcoder_busy_workers",
+		Help: "Number of worker slots currently running an encode.",
+	}, func() float64 {
+		if s.workers == nil {
+			return 0
+		}
+		return float64(len(s.workers))
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "transcoder_temp_disk_usage_bytes",
+		Help: "Bytes used by files in TEMP_DIR.",
+	}, func() float64 {
+		return float64(dirSize(tempDir))
+	})
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+func recordFFmpegExit(code int) {
+	ffmpegExits.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// clearSessionMetrics는 끝난 작업의 세션별 시계열을 지웁니다.
+func clearSessionMetrics(sessionID string) {
+	encodeProgressGauge.DeletePartialMatch(prometheus.Labels{"session_id": sessionID})
+}
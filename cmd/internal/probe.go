@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"github.com/ket0825/grpc-streaming/internal/probe"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// probeSource는 원본을 분석하여 메타데이터를 작업 정보에 기록하고 OUTPUT_DIR/metadata에 저장합니다.
+// 영상 스트림이 없거나 분석할 수 없는 파일이면 InvalidArgument 오류를 반환합니다.
+func (s *server) probeSource(ctx context.Context, logger *slog.Logger, info *ProcessingInfo) (err error) {
+	ctx, span := tracing.Start(ctx, "probe")
+	defer func() { tracing.End(span, err) }()
+	// PROBE_TIMEOUT: ffprobe 1회 실행 제한 시간
+	probeCtx, cancel := context.WithTimeout(ctx, env.Duration("PROBE_TIMEOUT", 30*time.Second))
+	defer cancel()
+
+	meta, err := s.transcoder.Probe(probeCtx, info.tempPath)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return cancelStatus(ctx)
+	case probeCtx.Err() != nil:
+		probeFailures.WithLabelValues("timeout").Inc()
+		return status.Errorf(codes.DeadlineExceeded, "source probe timed out")
+	case errors.Is(err, exec.ErrNotFound):
+		probeFailures.WithLabelValues("ffprobe_missing").Inc()
+		return status.Errorf(codes.Internal, "ffprobe not available: %v", err)
+	case errors.Is(err, probe.ErrNoVideo):
+		probeFailures.WithLabelValues("no_video").Inc()
+		return status.Errorf(codes.InvalidArgument, "%v (container %s, %d audio streams)", err, meta.Container, len(meta.Audio))
+	default:
+		probeFailures.WithLabelValues("unreadable").Inc()
+		return status.Errorf(codes.InvalidArgument, "source cannot be decoded: %v", err)
+	}
+
+	video := meta.PrimaryVideo()
+	span.SetAttributes(
+		attribute.String("container", meta.Container),
+		attribute.Float64("duration", meta.Duration),
+		attribute.String("video.codec", video.Codec),
+		attribute.Int("video.width", video.Width),
+		attribute.Int("video.height", video.Height),
+	)
+	logger.Info("source probed", "container", meta.Container, "duration", meta.Duration,
+		"video_codec", video.Codec, "width", video.Width, "height", video.Height, "fps", video.FrameRate,
+		"rotation", video.Rotation, "hdr", video.HDR, "audio_streams", len(meta.Audio))
+
+	path, err := writeMetadata(info, meta)
+	if err != nil {
+		// 저장 실패는 변환에 영향이 없으므로 기록만 함
+		logger.Warn("failed to persist source metadata", "error", err)
+		err = nil
+	}
+
+	s.mu.Lock()
+	info.metadata = meta
+	info.metadataPath = path
+	s.mu.Unlock()
+	return nil
+}
+
+// writeMetadata는 메타데이터를 렌디션과 같은 OUTPUT_DIR 아래 metadata/<파일 이름>.json으로 저장합니다.
+func writeMetadata(info *ProcessingInfo, meta *probe.Metadata) (string, error) {
+	dir := filepath.Join(os.Getenv("OUTPUT_DIR"), "metadata")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	raw, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(info.filename, filepath.Ext(info.filename)) + ".json"
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to save metadata: %v", err)
+	}
+	return path, nil
+}
+
+// sourceProto는 메타데이터를 응답 메시지로 변환합니다.
+func sourceProto(meta *probe.Metadata, path string) *pb.SourceMetadata {
+	if meta == nil {
+		return nil
+	}
+	src := &pb.SourceMetadata{
+		Container:       meta.Container,
+		DurationSeconds: meta.Duration,
+		SizeBytes:       meta.Size,
+		Bitrate:         meta.Bitrate,
+		MetadataPath:    path,
+	}
+	if meta.CreationTime != nil {
+		src.CreationTime = meta.CreationTime.Format(time.RFC3339)
+	}
+	for _, v := range meta.Video {
+		src.Video = append(src.Video, &pb.VideoStreamInfo{
+			Index:          int32(v.Index),
+			Codec:          v.Codec,
+			Profile:        v.Profile,
+			Width:          int32(v.Width),
+			Height:         int32(v.Height),
+			FrameRate:      v.FrameRate,
+			Rotation:       int32(v.Rotation),
+			PixelFormat:    v.PixelFormat,
+			Bitrate:        v.Bitrate,
+			ColorSpace:     v.ColorSpace,
+			ColorTransfer:  v.Transfer,
+			ColorPrimaries: v.Primaries,
+			Hdr:            v.HDR,
+		})
+	}
+	for _, a := range meta.Audio {
+		src.Audio = append(src.Audio, &pb.AudioStreamInfo{
+			Index:         int32(a.Index),
+			Codec:         a.Codec,
+			Profile:       a.Profile,
+			Channels:      int32(a.Channels),
+			ChannelLayout: a.ChannelLayout,
+			SampleRate:    int32(a.SampleRate),
+			Bitrate:       a.Bitrate,
+			Language:      a.Language,
+			Title:         a.Title,
+			Default:       a.Default,
+		})
+	}
+	return src
+}
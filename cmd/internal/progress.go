@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encodeProgress는 ffmpeg -progress 출력 한 블록의 내용입니다.
+type encodeProgress struct {
+	Frame   int64
+	FPS     float64
+	OutTime time.Duration // 지금까지 인코딩한 출력 길이
+	Speed   float64       // 실시간 대비 속도 (2.0 = 2배속)
+	Percent float64       // 원본 길이 기준 진행률 (길이를 모르면 0)
+	Done    bool          // progress=end
+}
+
+// progressParser는 ffmpeg -progress pipe:1 출력(key=value 줄)을 읽어
+// progress=continue|end 줄마다 onProgress를 호출하는 io.Writer입니다.
+type progressParser struct {
+	duration   float64 // 원본 길이(초), 0이면 진행률을 계산하지 않음
+	onProgress func(encodeProgress)
+
+	mu      sync.Mutex
+	buf     []byte
+	current encodeProgress
+}
+
+func newProgressParser(duration float64, onProgress func(encodeProgress)) *progressParser {
+	return &progressParser{duration: duration, onProgress: onProgress}
+}
+
+func (p *progressParser) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.line(strings.TrimSpace(string(p.buf[:i])))
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+func (p *progressParser) line(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch key {
+	case "frame":
+		p.current.Frame, _ = strconv.ParseInt(value, 10, 64)
+	case "fps":
+		if fps, err := strconv.ParseFloat(value, 64); err == nil {
+			p.current.FPS = fps
+		}
+	case "out_time_us", "out_time_ms":
+		// out_time_ms도 이름과 달리 마이크로초 단위, 시작 직후에는 N/A
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			p.current.OutTime = time.Duration(us) * time.Microsecond
+		}
+	case "speed":
+		// 마지막 블록은 N/A일 수 있으므로 직전 값 유지
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+			p.current.Speed = speed
+		}
+	case "progress":
+		p.current.Done = value == "end"
+		p.current.Percent = 0
+		if p.duration > 0 {
+			p.current.Percent = min(p.current.OutTime.Seconds()/p.duration*100, 100)
+		}
+		if p.current.Done && p.duration > 0 {
+			p.current.Percent = 100
+		}
+		if p.onProgress != nil {
+			p.onProgress(p.current)
+		}
+	}
+}
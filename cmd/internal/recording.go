@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+)
+
+// live 업로드는 LIVE_RECORD_INTERVAL(0이면 녹화하지 않음)마다 원본을 잘라 녹화 파일로 남기고,
+// 녹화 파일마다 일반 업로드와 같은 변환 작업(화질별 변환, 메타데이터, 썸네일, webhook)을 실행합니다.
+//
+// transcoder는 LIVE_DIR/<live session>/recordings/에 녹화 파일과 segment list를 쓰고,
+// 다 쓴 파일은 작업 큐 <LIVE_DIR>/recordings/<작업 ID>/{원본 파일, recording.json}으로 옮겨집니다.
+// recordLoop는 checkpoint와 같이 recording.json을 recording.json.claimed로 rename하여 작업을 선점하고,
+// 같은 lease(claimLease)로 죽은 인스턴스가 남긴 선점을 풀어 다시 가져갑니다.
+const (
+	recordingManifestName = "recording.json"
+	liveRecordList        = "recordings.csv" // transcoder가 다 쓴 녹화 파일 목록
+)
+
+type recordingManifest struct {
+	checkpointManifest
+	LiveSessionID   string    `json:"live_session_id"`
+	LiveJobID       string    `json:"live_job_id,omitempty"` // live 업로드의 gateway job ID
+	Index           int       `json:"index"`                 // live 업로드의 몇 번째 녹화인지 (0부터)
+	StartedAt       time.Time `json:"started_at"`            // 녹화 구간 시작 시각 (추정)
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// recordingData는 녹화 작업의 job.accepted 이벤트에 포함되는 live 업로드 정보입니다.
+type recordingData struct {
+	LiveSessionID   string    `json:"live_session_id"`
+	LiveJobID       string    `json:"live_job_id,omitempty"`
+	Index           int       `json:"index"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// liveRecordFormat은 live 입력 형식에 맞는 녹화 파일 muxer와 확장자를 반환합니다.
+// MPEG-TS에 넣을 수 없는 코덱(VP9 등)이 올 수 있는 Matroska 입력은 Matroska로 녹화합니다.
+func liveRecordFormat(format string) (string, string) {
+	switch format {
+	case "ts", "flv":
+		return "mpegts", ".ts"
+	default:
+		return "matroska", ".mkv"
+	}
+}
+
+// recordingQueueDir은 녹화 작업 큐 디렉토리입니다.
+func (s *server) recordingQueueDir() string {
+	return filepath.Join(s.live.Dir, "recordings")
+}
+
+// liveRecorder는 transcoder가 다 쓴 녹화 파일을 녹화 작업 큐로 옮깁니다.
+type liveRecorder struct {
+	s         *server
+	dir       string // transcoder 녹화 출력 디렉토리
+	sessionID string // live 업로드 세션
+	info      *ProcessingInfo
+	queued    int // 큐로 옮긴 녹화 수 (segment list에서 처리한 줄 수)
+}
+
+// run은 ctx가 끝날 때까지 interval마다 새 녹화 파일을 큐로 옮깁니다.
+func (r *liveRecorder) run(ctx context.Context, logger *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.sync(logger); err != nil {
+				logger.Warn("failed to queue live recordings", "error", err)
+			}
+		}
+	}
+}
+
+// sync는 segment list에 새로 추가된 녹화 파일을 큐로 옮깁니다.
+func (r *liveRecorder) sync(logger *slog.Logger) error {
+	data, err := os.ReadFile(filepath.Join(r.dir, liveRecordList))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines[min(r.queued, len(lines)):] {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+		start, _ := strconv.ParseFloat(fields[1], 64)
+		end, _ := strconv.ParseFloat(fields[2], 64)
+		id, err := r.s.queueRecording(r.sessionID, r.info, r.queued, filepath.Join(r.dir, fields[0]), start, end)
+		if err != nil {
+			return fmt.Errorf("%s: %v", fields[0], err)
+		}
+		r.queued++
+		logger.Info("live recording queued", "recording_id", id, "duration", end-start)
+	}
+	return nil
+}
+
+// queueRecording은 녹화 파일을 작업 큐로 옮기고 manifest를 씁니다.
+// 작업은 live 업로드의 요청 ID, tenant, callback URL을 그대로 사용합니다.
+func (s *server) queueRecording(liveSessionID string, info *ProcessingInfo, index int, path string, start, end float64) (string, error) {
+	id := fmt.Sprintf("%s_rec%05d", liveSessionID, index)
+	dir := filepath.Join(s.recordingQueueDir(), id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return id, err
+	}
+	filename := "video_" + id + filepath.Ext(path)
+	if err := moveFile(path, filepath.Join(dir, filename)); err != nil {
+		os.RemoveAll(dir)
+		return id, fmt.Errorf("failed to move recording: %v", err)
+	}
+	manifest := recordingManifest{
+		checkpointManifest: checkpointManifest{
+			JobID:       id,
+			Filename:    filename,
+			RequestID:   info.requestID,
+			Tenant:      info.tenant,
+			CallbackURL: info.callbackURL,
+			CreatedAt:   time.Now(),
+		},
+		LiveSessionID:   liveSessionID,
+		LiveJobID:       info.jobID,
+		Index:           index,
+		StartedAt:       info.started.Add(time.Duration(start * float64(time.Second))),
+		DurationSeconds: end - start,
+	}
+	// manifest가 생기는 순간 recordLoop가 가져갈 수 있으므로 파일 이동 후 마지막에 기록
+	if err := writeManifest(dir, recordingManifestName, manifest); err != nil {
+		return id, err
+	}
+	liveRecordings.WithLabelValues("queued").Inc()
+	return id, nil
+}
+
+// recordLoop는 interval마다 녹화 작업 큐를 확인하여 선점한 녹화를 변환합니다.
+// 큐는 LIVE_DIR 아래에 있으므로 LIVE_DIR을 공유하는 다른 인스턴스도 가져갈 수 있습니다.
+func (s *server) recordLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.startRecordings(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *server) startRecordings(ctx context.Context) {
+	root := s.recordingQueueDir()
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("failed to read recording queue", "dir", root, "error", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || ctx.Err() != nil {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		s.sweepClaim(dir, recordingManifestName)
+		var manifest recordingManifest
+		if !claimManifest(dir, recordingManifestName, &manifest) {
+			continue
+		}
+		manifest.ClaimedBy = claimOwnerID
+		recordClaim(dir, recordingManifestName, manifest)
+
+		info := &ProcessingInfo{
+			filename:    manifest.Filename,
+			tempPath:    filepath.Join(dir, manifest.Filename),
+			requestID:   manifest.RequestID,
+			tenant:      manifest.Tenant,
+			callbackURL: manifest.CallbackURL,
+			format:      strings.TrimPrefix(filepath.Ext(manifest.Filename), "."),
+			started:     time.Now(),
+			converting:  true,
+			queued:      true,
+			converted:   make(map[string]bool),
+		}
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		info.cancel = func() { cancel(errCancelledByAdmin) }
+		s.mu.Lock()
+		s.activeProcessings[manifest.JobID] = info
+		s.claims[dir] = true
+		s.mu.Unlock()
+
+		s.jobs.Add(1)
+		go func() {
+			defer s.jobs.Done()
+			defer cancel(nil)
+			renewCtx, stopRenew := context.WithCancel(jobCtx)
+			go renewClaim(renewCtx, dir, recordingManifestName)
+			s.runRecording(jobCtx, dir, &manifest, info)
+			stopRenew()
+			s.mu.Lock()
+			delete(s.claims, dir)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// runRecording은 녹화 파일 하나를 일반 업로드와 같이 분석, 변환하고 결과를 알립니다.
+func (s *server) runRecording(ctx context.Context, dir string, manifest *recordingManifest, info *ProcessingInfo) {
+	sessionID := manifest.JobID
+	if info.requestID != "" {
+		ctx = logging.WithRequestID(ctx, info.requestID)
+	}
+	logger := logging.FromContext(ctx).With("session_id", sessionID, "live_session_id", manifest.LiveSessionID, "recording", manifest.Index)
+	logger.Info("converting live recording", "started_at", manifest.StartedAt, "duration", manifest.DurationSeconds)
+
+	// 녹화 파일도 일반 업로드처럼 영상 스트림이 없으면 변환하지 않음
+	if err := s.probeSource(ctx, logger, info); err != nil {
+		s.finishJob(sessionID, info)
+		if ctx.Err() != nil {
+			releaseClaim(dir, recordingManifestName)
+			return
+		}
+		logger.Warn("live recording rejected", "error", err)
+		liveRecordings.WithLabelValues("failed").Inc()
+		s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: err.Error()})
+		os.RemoveAll(dir)
+		return
+	}
+	var size int64
+	if st, err := os.Stat(info.tempPath); err == nil {
+		size = st.Size()
+	}
+	s.mu.Lock()
+	info.totalBytes = size
+	s.mu.Unlock()
+	s.notify(sessionID, info, webhook.JobAccepted, acceptedData{
+		Format: info.format, Bytes: size, Qualities: qualityNames(), Source: info.metadata,
+		Recording: &recordingData{
+			LiveSessionID:   manifest.LiveSessionID,
+			LiveJobID:       manifest.LiveJobID,
+			Index:           manifest.Index,
+			StartedAt:       manifest.StartedAt,
+			DurationSeconds: manifest.DurationSeconds,
+		},
+	})
+
+	successCount, conversionErrors, err := s.convertQueued(ctx, logger, sessionID, info, true)
+	s.finishJob(sessionID, info)
+	s.mu.Lock()
+	checkpointed := info.checkpointed
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("live recording interrupted", "error", err, "cause", context.Cause(ctx))
+		switch {
+		case checkpointed:
+			// 원본은 checkpoint 디렉토리로 옮겨져 다른 인스턴스가 이어서 변환
+			os.RemoveAll(dir)
+		case errors.Is(context.Cause(ctx), errCancelledByAdmin):
+			os.RemoveAll(dir)
+			liveRecordings.WithLabelValues("failed").Inc()
+			s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: errCancelledByAdmin.Error(), Errors: conversionErrors})
+		default:
+			// 선점을 풀어 재시작 후 다시 변환
+			releaseClaim(dir, recordingManifestName)
+		}
+		return
+	}
+
+	if len(conversionErrors) > 0 {
+		s.retainSource(logger, sessionID, info, conversionErrors)
+	}
+	os.RemoveAll(dir)
+	if successCount > 0 {
+		liveRecordings.WithLabelValues("converted").Inc()
+	} else {
+		liveRecordings.WithLabelValues("failed").Inc()
+	}
+	s.notifyResult(sessionID, info, successCount, conversionErrors)
+	logger.Info("live recording finished", "result", resultMessage(successCount, conversionErrors))
+}
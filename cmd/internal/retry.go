@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryPolicy는 실패한 화질을 같은 작업 안에서 다시 시도하는 규칙입니다.
+type retryPolicy struct {
+	MaxAttempts int           // 화질별 최대 시도 횟수 (1이면 재시도하지 않음)
+	Interval    time.Duration // 첫 재시도 전 대기 시간, 실패할 때마다 2배
+	MaxInterval time.Duration
+}
+
+// retryPolicyFromEnv는 RENDITION_MAX_ATTEMPTS, RENDITION_RETRY_INTERVAL, RENDITION_MAX_RETRY_INTERVAL을 읽습니다.
+func retryPolicyFromEnv() retryPolicy {
+	return retryPolicy{
+		MaxAttempts: env.Int("RENDITION_MAX_ATTEMPTS", 2),
+		Interval:    env.Duration("RENDITION_RETRY_INTERVAL", 10*time.Second),
+		MaxInterval: env.Duration("RENDITION_MAX_RETRY_INTERVAL", 2*time.Minute),
+	}
+}
+
+// backoff는 attempt번째 실패 후 기다릴 시간입니다.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.Interval
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxInterval > 0 && backoff > p.MaxInterval {
+			return p.MaxInterval
+		}
+	}
+	return backoff
+}
+
+// 일부 화질이 실패한 작업의 원본은 RETAIN_DIR에 RETAIN_DURATION 동안 보관하여
+// 다시 업로드하지 않고 실패한 화질만 재변환(관리 API RetryJob)할 수 있게 합니다.
+//
+// 디렉토리 구조: <RETAIN_DIR>/<job id>/{원본 파일, retained.json}
+// 재변환 중에는 checkpoint와 같이 retained.json을 retained.json.claimed로 rename하여 선점합니다.
+const retainedManifestName = "retained.json"
+
+type retention struct {
+	Dir string        // 비어 있으면 보관하지 않음
+	For time.Duration // 보관 기간
+}
+
+// retentionFromEnv는 RETAIN_DIR, RETAIN_DURATION을 읽습니다.
+func retentionFromEnv() retention {
+	return retention{
+		Dir: os.Getenv("RETAIN_DIR"),
+		For: env.Duration("RETAIN_DURATION", 24*time.Hour),
+	}
+}
+
+type retainedManifest struct {
+	checkpointManifest
+	Failed    []string  `json:"failed"`
+	Fallback  []string  `json:"fallback,omitempty"` // fallback 설정으로 변환된 화질
+	Errors    []string  `json:"errors,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// retryTask는 RetryJob으로 선점한 작업입니다. retainLoop가 실행합니다.
+type retryTask struct {
+	sessionID  string
+	info       *ProcessingInfo
+	thumbnails bool // 처음 변환에서 성공한 화질이 없어 이미지도 만들지 않은 경우
+}
+
+// rendition은 작업에서 실제로 사용할 화질 설정을 반환합니다.
+func (info *ProcessingInfo) rendition(q VideoQuality) VideoQuality {
+	if info.fallback[q.Name] {
+		return fallbackQuality(q)
+	}
+	return q
+}
+
+// retainSource는 실패한 화질이 있는 작업의 원본과 진행 상황을 보관합니다.
+// 재변환 중인 작업이면 기존 보관 디렉토리의 manifest만 갱신하고 만료 시각은 유지합니다.
+func (s *server) retainSource(logger *slog.Logger, sessionID string, info *ProcessingInfo, conversionErrors []string) {
+	if s.retention.Dir == "" {
+		return
+	}
+
+	s.mu.Lock()
+	manifest := retainedManifest{
+		checkpointManifest: checkpointManifest{
+			JobID:        sessionID,
+			Filename:     info.filename,
+			RequestID:    info.requestID,
+			GatewayJobID: info.jobID,
+			Tenant:       info.tenant,
+			CallbackURL:  info.callbackURL,
+			CreatedAt:    info.started,
+		},
+		Errors:    conversionErrors,
+		ExpiresAt: info.retainedUntil,
+	}
+	for _, quality := range qualities {
+		if !info.converted[quality.Name] {
+			manifest.Failed = append(manifest.Failed, quality.Name)
+			continue
+		}
+		manifest.Converted = append(manifest.Converted, quality.Name)
+		if info.fallback[quality.Name] {
+			manifest.Fallback = append(manifest.Fallback, quality.Name)
+		}
+	}
+	for _, r := range s.audio.Renditions {
+		for _, t := range info.audio {
+			key := audioKey(r, t)
+			if info.converted[key] {
+				manifest.Converted = append(manifest.Converted, key)
+			} else {
+				manifest.Failed = append(manifest.Failed, key)
+			}
+		}
+	}
+	dir := info.retainedFrom
+	retrying := dir != ""
+	s.mu.Unlock()
+	if manifest.ExpiresAt.IsZero() {
+		manifest.ExpiresAt = time.Now().Add(s.retention.For)
+	}
+
+	if !retrying {
+		dir = filepath.Join(s.retention.Dir, sessionID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logger.Error("failed to retain source", "error", err)
+			return
+		}
+		if err := moveFile(info.tempPath, filepath.Join(dir, info.filename)); err != nil {
+			os.RemoveAll(dir)
+			logger.Error("failed to retain source", "error", err)
+			return
+		}
+	}
+	// 재변환이면 writeManifest가 선점 중인 retained.json.claimed를 지움
+	if err := writeManifest(dir, retainedManifestName, manifest); err != nil {
+		if retrying {
+			// 선점한 이전 manifest를 되돌려 다시 재변환하거나 만료될 수 있게 함
+			releaseRetained(dir)
+		}
+		logger.Error("failed to retain source", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	info.retainedUntil = manifest.ExpiresAt
+	s.mu.Unlock()
+	retainedSources.WithLabelValues("retained").Inc()
+	logger.Info("source retained for retry", "failed", manifest.Failed, "until", manifest.ExpiresAt)
+}
+
+func readRetained(path string) (*retainedManifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest retainedManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("corrupt manifest %s: %v", path, err)
+	}
+	return &manifest, nil
+}
+
+// claimRetained는 보관된 작업을 선점하고 실패한 화질을 다시 변환할 작업 정보를 만듭니다.
+func (s *server) claimRetained(id string, fallback bool) (*retryTask, []string, error) {
+	if s.retention.Dir == "" {
+		return nil, nil, status.Error(codes.FailedPrecondition, "source retention is disabled (RETAIN_DIR)")
+	}
+	if id == "" || filepath.Base(id) != id || id == "." || id == ".." {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid job id %q", id)
+	}
+	if s.isActive(id) {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "job %s is still running", id)
+	}
+
+	dir := filepath.Join(s.retention.Dir, id)
+	manifestPath := filepath.Join(dir, retainedManifestName)
+	claimedPath := manifestPath + claimedSuffix
+	if err := os.Rename(manifestPath, claimedPath); err != nil {
+		if _, statErr := os.Stat(claimedPath); statErr == nil {
+			return nil, nil, status.Errorf(codes.FailedPrecondition, "job %s is already being retried", id)
+		}
+		return nil, nil, status.Errorf(codes.NotFound, "no retained source for job %s", id)
+	}
+	manifest, err := readRetained(claimedPath)
+	if err != nil {
+		os.Rename(claimedPath, manifestPath)
+		return nil, nil, status.Error(codes.Internal, err.Error())
+	}
+	if time.Now().After(manifest.ExpiresAt) {
+		os.RemoveAll(dir)
+		retainedSources.WithLabelValues("expired").Inc()
+		return nil, nil, status.Errorf(codes.NotFound, "retained source for job %s expired at %s", id, manifest.ExpiresAt.Format(time.RFC3339))
+	}
+
+	info := &ProcessingInfo{
+		filename:      manifest.Filename,
+		tempPath:      filepath.Join(dir, manifest.Filename),
+		requestID:     manifest.RequestID,
+		jobID:         manifest.GatewayJobID,
+		tenant:        manifest.Tenant,
+		callbackURL:   manifest.CallbackURL,
+		started:       time.Now(),
+		converting:    true,
+		queued:        true,
+		converted:     make(map[string]bool),
+		fallback:      make(map[string]bool),
+		retainedFrom:  dir,
+		retainedUntil: manifest.ExpiresAt,
+	}
+	convertedVideo := 0
+	for _, quality := range manifest.Converted {
+		info.converted[quality] = true
+		if !strings.Contains(quality, "/") {
+			convertedVideo++
+		}
+	}
+	for _, quality := range manifest.Fallback {
+		info.fallback[quality] = true
+	}
+	var pending []string
+	for _, quality := range qualities {
+		if info.converted[quality.Name] {
+			continue
+		}
+		pending = append(pending, quality.Name)
+		if fallback {
+			info.fallback[quality.Name] = true
+		}
+	}
+	// 음성 전용 화질은 원본 트랙을 다시 분석한 후 변환되지 않은 출력만 변환
+	for _, name := range manifest.Failed {
+		if strings.Contains(name, "/") {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) == 0 {
+		os.Rename(claimedPath, manifestPath)
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "job %s has no failed renditions in the current ladder", id)
+	}
+
+	s.mu.Lock()
+	s.activeProcessings[id] = info
+	s.mu.Unlock()
+	return &retryTask{sessionID: id, info: info, thumbnails: convertedVideo == 0}, pending, nil
+}
+
+func (s *server) isActive(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.activeProcessings[id]
+	return ok
+}
+
+// enqueueRetry는 선점한 작업을 retainLoop에 넘깁니다. 넘기지 못하면 선점을 풉니다.
+func (s *server) enqueueRetry(ctx context.Context, task *retryTask) error {
+	select {
+	case s.retryTasks <- task:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		delete(s.activeProcessings, task.sessionID)
+		s.mu.Unlock()
+		releaseRetained(task.info.retainedFrom)
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// releaseRetained는 선점을 풀어 다시 재변환할 수 있게 합니다.
+func releaseRetained(dir string) {
+	manifestPath := filepath.Join(dir, retainedManifestName)
+	os.Rename(manifestPath+claimedSuffix, manifestPath)
+}
+
+// retainLoop는 RetryJob으로 요청된 재변환을 실행하고, 주기적으로 만료된 원본을 삭제합니다.
+func (s *server) retainLoop(ctx context.Context, interval time.Duration) {
+	if s.retention.Dir == "" {
+		return
+	}
+	if err := os.MkdirAll(s.retention.Dir, 0755); err != nil {
+		slog.Error("failed to create retain directory", "dir", s.retention.Dir, "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	s.removeExpired()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.removeExpired()
+		case task := <-s.retryTasks:
+			jobCtx, cancel := context.WithCancelCause(ctx)
+			s.mu.Lock()
+			task.info.cancel = func() { cancel(errCancelledByAdmin) }
+			s.mu.Unlock()
+
+			s.jobs.Add(1)
+			go func() {
+				defer s.jobs.Done()
+				defer cancel(nil)
+				s.runRetry(jobCtx, task)
+			}()
+		}
+	}
+}
+
+// removeExpired는 보관 기간이 지난 원본을 삭제합니다. 재변환 중인 작업은 건너뜁니다.
+func (s *server) removeExpired() {
+	entries, err := os.ReadDir(s.retention.Dir)
+	if err != nil {
+		slog.Error("failed to read retain directory", "dir", s.retention.Dir, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.retention.Dir, entry.Name())
+		manifestPath := filepath.Join(dir, retainedManifestName)
+		manifest, err := readRetained(manifestPath)
+		if err != nil || time.Now().Before(manifest.ExpiresAt) {
+			continue
+		}
+		// 같은 시점의 RetryJob과 경합하지 않도록 선점 후 삭제
+		if err := os.Rename(manifestPath, manifestPath+claimedSuffix); err != nil {
+			continue
+		}
+		os.RemoveAll(dir)
+		retainedSources.WithLabelValues("expired").Inc()
+		slog.Info("retained source expired", "session_id", manifest.JobID, "failed", manifest.Failed)
+	}
+}
+
+// retainedJobs는 재변환을 기다리는 보관 작업을 관리 API 형식으로 반환합니다.
+func (s *server) retainedJobs() []*pb.JobStatus {
+	if s.retention.Dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(s.retention.Dir)
+	if err != nil {
+		return nil
+	}
+	var jobs []*pb.JobStatus
+	for _, entry := range entries {
+		dir := filepath.Join(s.retention.Dir, entry.Name())
+		manifest, err := readRetained(filepath.Join(dir, retainedManifestName))
+		if err != nil {
+			continue
+		}
+		job := &pb.JobStatus{
+			Id:            manifest.JobID,
+			RequestId:     manifest.RequestID,
+			State:         "retained",
+			Format:        strings.TrimPrefix(filepath.Ext(manifest.Filename), "."),
+			AgeSeconds:    time.Since(manifest.CreatedAt).Seconds(),
+			Converted:     manifest.Converted,
+			Failed:        manifest.Failed,
+			RetainedUntil: manifest.ExpiresAt.Format(time.RFC3339),
+		}
+		if st, err := os.Stat(filepath.Join(dir, manifest.Filename)); err == nil {
+			job.Bytes = st.Size()
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// runRetry는 보관된 원본으로 실패한 화질만 다시 변환합니다.
+func (s *server) runRetry(ctx context.Context, task *retryTask) {
+	sessionID, info := task.sessionID, task.info
+	if info.requestID != "" {
+		ctx = logging.WithRequestID(ctx, info.requestID)
+	}
+	logger := logging.FromContext(ctx).With("session_id", sessionID)
+	logger.Info("retrying failed renditions", "fallback", len(info.fallback) > 0)
+	retainedSources.WithLabelValues("retried").Inc()
+
+	successCount, conversionErrors, err := s.convertQueued(ctx, logger, sessionID, info, task.thumbnails)
+	s.finishJob(sessionID, info)
+	s.mu.Lock()
+	checkpointed := info.checkpointed
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Warn("retry interrupted", "error", err, "cause", context.Cause(ctx))
+		if checkpointed {
+			// 원본은 checkpoint 디렉토리로 옮겨졌으므로 보관 디렉토리는 정리
+			os.RemoveAll(info.retainedFrom)
+			return
+		}
+		// 원본은 그대로 두고 선점을 풀어 다시 재시도할 수 있게 함
+		releaseRetained(info.retainedFrom)
+		if errors.Is(context.Cause(ctx), errCancelledByAdmin) {
+			s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: errCancelledByAdmin.Error(), Errors: conversionErrors})
+		}
+		return
+	}
+
+	if len(conversionErrors) > 0 {
+		s.retainSource(logger, sessionID, info, conversionErrors)
+	} else {
+		os.RemoveAll(info.retainedFrom)
+	}
+	s.notifyResult(sessionID, info, successCount, conversionErrors)
+	logger.Info("retry finished", "result", resultMessage(successCount, conversionErrors))
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/probe"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverOptions는 internal 서버 설정입니다.
+type serverOptions struct {
+	checkpointDir string // 비어 있으면 종료 시 checkpoint를 만들지 않음
+	maxJobs       int    // 동시에 실행할 변환 작업 수 (0: 제한 없음)
+	policy        mediatype.Policy
+	notifier      *webhook.Notifier // nil이면 이벤트를 보내지 않음
+	thumbnails    thumbnailConfig
+	transcoder    Transcoder     // nil이면 ffmpegTranscoder
+	timeouts      encodeTimeouts // 화질별 변환 제한 시간 (0이면 제한 없음)
+	retries       retryPolicy    // 화질별 자동 재시도
+	retention     retention      // 부분 실패 시 원본 보관 (RetryJob)
+	live          liveConfig     // live 업로드 HLS 출력
+	audio         audioConfig    // 음성 트랙, 음량 정규화, 음성 전용 화질
+}
+
+type server struct {
+	pb.UnimplementedVideoStreamingServiceServer
+	mu                sync.Mutex
+	activeProcessings map[string]*ProcessingInfo
+	checkpointDir     string
+	workers           chan struct{}  // 변환 작업 슬롯 (nil이면 제한 없음)
+	liveSlots         chan struct{}  // live 업로드 슬롯 (nil이면 제한 없음, workers와 별개)
+	jobs              sync.WaitGroup // checkpoint에서 재개된 백그라운드 작업
+	policy            mediatype.Policy
+	notifier          *webhook.Notifier
+	thumbnails        thumbnailConfig
+	transcoder        Transcoder
+	timeouts          encodeTimeouts
+	retries           retryPolicy
+	retention         retention
+	live              liveConfig
+	audio             audioConfig
+	retryTasks        chan *retryTask // RetryJob → retainLoop
+	claims            map[string]bool // 이 프로세스가 선점하여 변환 중인 checkpoint, 녹화 디렉토리
+
+	paused   bool          // 관리 API로 작업 큐를 멈춘 상태
+	resumeCh chan struct{} // 큐 재개 시 close
+	watchers jobWatchers   // WatchJobs 구독자
+}
+
+type ProcessingInfo struct {
+	file        *os.File
+	totalBytes  int64
+	filename    string
+	tempPath    string
+	requestID   string // checkpoint에서 재개할 때 같은 요청 ID로 로그를 남기기 위해 보관
+	jobID       string // gateway job ID (webhook 이벤트에 포함)
+	tenant      string
+	callbackURL string // 비어 있으면 전역 WEBHOOK_URL
+
+	format       string
+	started      time.Time
+	cancel       func()          // 관리 API에서 작업 취소
+	converting   bool            // 업로드가 끝나고 변환 중인지 여부 (작업 슬롯 대기 포함)
+	queued       bool            // 작업 슬롯 대기 중
+	current      string          // 변환 중인 화질
+	converted    map[string]bool // 완료된 화질
+	checkpointed bool            // 종료 시 checkpoint로 넘겨졌는지 여부
+	resumedFrom  string          // checkpoint에서 재개된 경우 해당 디렉토리
+	thumbnails   *pb.Thumbnails  // 생성된 대표 이미지, 썸네일, sprite sheet
+	metadata     *probe.Metadata // ffprobe로 분석한 원본 정보
+	metadataPath string
+	progress     encodeProgress // 변환 중인 화질의 ffmpeg 진행 상황
+
+	fallback      map[string]bool // RetryJob에서 fallback 설정으로 변환할(변환된) 화질
+	retainedFrom  string          // 보관된 원본으로 재변환하는 경우 해당 디렉토리
+	retainedUntil time.Time       // 원본 보관 만료 시각 (보관되지 않았으면 zero)
+
+	live bool // live 업로드 (임시 파일 없이 HLS로 바로 변환, checkpoint 대상 아님)
+
+	audio      []audioTrack // 출력에 포함할 음성 트랙과 loudnorm 분석 결과 (prepareAudio)
+	audioReady bool
+}
+
+func NewInternalServer(opts serverOptions) *server {
+	s := &server{
+		activeProcessings: make(map[string]*ProcessingInfo),
+		checkpointDir:     opts.checkpointDir,
+		policy:            opts.policy,
+		notifier:          opts.notifier,
+		thumbnails:        opts.thumbnails,
+		transcoder:        opts.transcoder,
+		timeouts:          opts.timeouts,
+		retries:           opts.retries,
+		retention:         opts.retention,
+		live:              opts.live,
+		audio:             opts.audio,
+		retryTasks:        make(chan *retryTask),
+		claims:            make(map[string]bool),
+	}
+	if s.transcoder == nil {
+		s.transcoder = ffmpegTranscoder{}
+	}
+	if opts.maxJobs > 0 {
+		s.workers = make(chan struct{}, opts.maxJobs)
+	}
+	if opts.live.MaxSessions > 0 {
+		s.liveSlots = make(chan struct{}, opts.live.MaxSessions)
+	}
+	return s
+}
+
+// workerSlot은 작업 하나가 가진 변환 작업 슬롯입니다.
+// 재시도 대기 중에는 슬롯을 내려놓고 다시 얻으므로, 해제는 release로만 합니다.
+type workerSlot struct {
+	s    *server
+	held bool
+}
+
+// acquireWorker는 작업 큐가 멈춰 있으면 재개될 때까지, 그 다음 변환 작업 슬롯이 빌 때까지 기다립니다.
+func (s *server) acquireWorker(ctx context.Context) (*workerSlot, error) {
+	slot := &workerSlot{s: s}
+	if err := slot.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// acquire는 내려놓은 슬롯을 다시 얻습니다. 이미 가지고 있으면 바로 반환합니다.
+func (w *workerSlot) acquire(ctx context.Context) error {
+	if w.held {
+		return nil
+	}
+	if err := w.s.waitWorker(ctx); err != nil {
+		return err
+	}
+	w.held = true
+	return nil
+}
+
+// release는 슬롯을 가지고 있으면 내려놓습니다.
+func (w *workerSlot) release() {
+	if !w.held {
+		return
+	}
+	w.held = false
+	if w.s.workers != nil {
+		<-w.s.workers
+	}
+}
+
+func (s *server) waitWorker(ctx context.Context) error {
+	queueDepth.Inc()
+	defer queueDepth.Dec()
+	for {
+		s.mu.Lock()
+		paused, resumeCh := s.paused, s.resumeCh
+		s.mu.Unlock()
+		if !paused {
+			break
+		}
+		select {
+		case <-resumeCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if s.workers == nil {
+		return nil
+	}
+	select {
+	case s.workers <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquireLive는 live 업로드 슬롯을 얻습니다. 모두 사용 중이면 기다리지 않고 ok=false를 반환합니다.
+// live는 지연 없이 받아야 하고, 녹화 변환 작업이 VOD 작업 슬롯을 얻을 수 있도록 workers와 따로 셉니다.
+func (s *server) acquireLive() (release func(), ok bool) {
+	if s.liveSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case s.liveSlots <- struct{}{}:
+		return func() { <-s.liveSlots }, true
+	default:
+		return nil, false
+	}
+}
+
+// checkFormat은 업로드 앞부분으로 컨테이너 형식을 판별하고 허용 정책을 확인합니다.
+func (s *server) checkFormat(head []*pb.VideoChunk, headBytes []byte) (mediatype.Format, error) {
+	if len(headBytes) == 0 {
+		return mediatype.Format{}, errors.New("empty upload")
+	}
+	format, err := mediatype.Detect(headBytes)
+	if err != nil {
+		return format, err
+	}
+	return format, s.policy.Check(format, head[0].ContentType)
+}
+
+// checkQueue는 관리 API로 작업 큐가 멈춰 있지 않은지 확인합니다.
+// 작업 슬롯이 모두 사용 중이어도 스트림은 받아 대기시킬 수 있으므로 여유 슬롯은 확인하지 않습니다.
+func (s *server) checkQueue(ctx context.Context) error {
+	s.mu.Lock()
+	paused := s.paused
+	s.mu.Unlock()
+	if paused {
+		return errors.New("queue paused")
+	}
+	return nil
+}
+
+func (s *server) StreamVideo(stream pb.VideoStreamingService_StreamVideoServer) error {
+	// gateway가 취소하거나 deadline이 지나면 ctx가 종료되어 수신 및 변환이 중단됨
+	// 관리 API로 취소하면 errCancelledByAdmin이 cause로 설정됨
+	ctx, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+
+	sessionID := fmt.Sprintf("process_%d", time.Now().UnixNano())
+	logger := logging.FromContext(ctx).With("session_id", sessionID)
+	logger.Info("starting new processing session")
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("session.id", sessionID))
+	if deadline, ok := ctx.Deadline(); ok {
+		logger.Info("session deadline", "deadline", deadline.Format(time.RFC3339))
+	}
+
+	// 업로드별 callback URL (x-callback-url metadata)
+	callbackURL := incomingValue(ctx, webhook.CallbackURLHeader)
+	if callbackURL != "" {
+		if err := s.notifier.CheckURL(callbackURL); err != nil {
+			logger.Warn("upload rejected", "error", err)
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	// 임시 디렉토리 생성
+	tempDir := os.Getenv("TEMP_DIR")
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp directory: %v", err)
+	}
+
+	// 앞부분으로 컨테이너 형식을 판별하여 영상이 아니면 거절하고, 임시 파일 확장자를 실제 형식에 맞춤
+	head, headBytes, eof, err := mediatype.ReadHead(stream.Recv)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return fmt.Errorf("error receiving chunk: %v", err)
+	}
+	format, err := s.checkFormat(head, headBytes)
+	if err != nil {
+		logger.Warn("upload rejected", "error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	logger = logger.With("format", format.Name)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("container", format.Name))
+	recv := mediatype.Replay(head, eof, stream.Recv)
+
+	// live 업로드는 EOF를 기다리지 않고 받는 즉시 HLS로 변환
+	if incomingValue(ctx, mediatype.LiveHeader) == "true" {
+		return s.streamLive(ctx, cancel, stream, sessionID, logger.With("live", true), format, recv, callbackURL)
+	}
+
+	// 임시 파일 생성
+	fileName := fmt.Sprintf("video_%s%s", sessionID, format.Extension)
+	tempPath := filepath.Join(tempDir, fileName)
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+
+	// 처리 정보 저장
+	info := &ProcessingInfo{
+		file:        file,
+		filename:    fileName,
+		tempPath:    tempPath,
+		requestID:   logging.RequestID(ctx),
+		jobID:       incomingValue(ctx, webhook.JobIDHeader),
+		tenant:      incomingValue(ctx, tenantHeader),
+		callbackURL: callbackURL,
+		format:      format.Name,
+		started:     time.Now(),
+		cancel:      func() { cancel(errCancelledByAdmin) },
+		converted:   make(map[string]bool),
+	}
+	s.mu.Lock()
+	s.activeProcessings[sessionID] = info
+	s.mu.Unlock()
+	activeSessionsGauge.Inc()
+
+	defer func() {
+		file.Close()
+		s.finishJob(sessionID, info)
+		activeSessionsGauge.Dec()
+		// 임시 파일 삭제 (checkpoint된 경우 이미 이동되어 있음)
+		os.Remove(tempPath)
+	}()
+
+	// 청크 수신 및 파일 저장
+	uploadStarted := time.Now()
+	_, writeSpan := tracing.Start(ctx, "temp_write", trace.WithAttributes(attribute.String("temp.path", tempPath)))
+	totalBytes := int64(0)
+	chunks := 0
+	for {
+		chunk, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err == nil && ctx.Err() != nil {
+			// 관리 API로 취소된 경우 gateway 스트림은 계속 열려 있으므로 다음 청크에서 중단
+			err = ctx.Err()
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Warn("session cancelled, discarding upload", "bytes", totalBytes, "cause", context.Cause(ctx))
+				tracing.End(writeSpan, ctx.Err())
+				return cancelStatus(ctx)
+			}
+			tracing.End(writeSpan, err)
+			return fmt.Errorf("error receiving chunk: %v", err)
+		}
+
+		n, err := file.Write(chunk.Data)
+		if err != nil {
+			tracing.End(writeSpan, err)
+			return fmt.Errorf("failed to write chunk: %v", err)
+		}
+
+		totalBytes += int64(n)
+		chunks++
+		s.mu.Lock()
+		info.totalBytes = totalBytes
+		s.mu.Unlock()
+		if err := s.policy.CheckSize(format, totalBytes); err != nil {
+			tracing.End(writeSpan, err)
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		bytesReceived.Add(float64(n))
+		chunksReceived.Inc()
+
+		if chunks%1000 == 0 {
+			logger.Debug("session progress", "chunks", chunks, "bytes", totalBytes)
+		}
+	}
+
+	uploadDuration.Observe(time.Since(uploadStarted).Seconds())
+	writeSpan.SetAttributes(attribute.Int64("bytes", totalBytes), attribute.Int("chunks", chunks))
+	tracing.End(writeSpan, nil)
+	logger.Info("received complete video", "bytes", totalBytes, "chunks", chunks)
+
+	// 파일을 닫고 다시 열어서 변환 시작
+	file.Close()
+
+	// 원본을 분석하여 영상 스트림이 없으면 화질별 변환을 시도하지 않고 바로 실패
+	if err := s.probeSource(ctx, logger, info); err != nil {
+		logger.Warn("source rejected", "error", err)
+		return err
+	}
+
+	s.updateJob(sessionID, info, func() {
+		info.converting = true
+		info.queued = true
+	})
+	s.notify(sessionID, info, webhook.JobAccepted, acceptedData{Format: format.Name, Bytes: totalBytes, Qualities: qualityNames(), Source: info.metadata})
+
+	// 작업 슬롯을 얻은 후 화질별 변환 시작
+	slot, err := s.acquireWorker(ctx)
+	if err != nil {
+		logger.Warn("session cancelled while waiting for a worker", "cause", context.Cause(ctx))
+		s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: context.Cause(ctx).Error()})
+		return cancelStatus(ctx)
+	}
+	s.updateJob(sessionID, info, func() { info.queued = false })
+	successCount, conversionErrors, err := s.runConversions(ctx, sessionID, info, slot)
+	if err == nil && successCount > 0 {
+		s.runThumbnails(ctx, logger, info)
+	}
+	slot.release()
+	if err != nil {
+		s.mu.Lock()
+		checkpointed := info.checkpointed
+		s.mu.Unlock()
+		if checkpointed {
+			return status.Errorf(codes.Unavailable,
+				"internal server shutting down; job %s checkpointed and will be resumed", sessionID)
+		}
+		s.notify(sessionID, info, webhook.JobFailed, jobResultData{Message: context.Cause(ctx).Error(), Errors: conversionErrors})
+		return cancelStatus(ctx)
+	}
+	// 실패한 화질이 있으면 원본을 보관하여 RetryJob으로 다시 변환할 수 있게 함
+	if len(conversionErrors) > 0 {
+		s.retainSource(logger, sessionID, info, conversionErrors)
+	}
+	s.notifyResult(sessionID, info, successCount, conversionErrors)
+
+	resp := &pb.StreamResponse{
+		Success:    successCount > 0,
+		Message:    resultMessage(successCount, conversionErrors),
+		Thumbnails: info.thumbnails,
+		Source:     sourceProto(info.metadata, info.metadataPath),
+	}
+	if !info.retainedUntil.IsZero() {
+		resp.RetainedUntil = info.retainedUntil.Format(time.RFC3339)
+	}
+	return stream.SendAndClose(resp)
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/probe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testMP4는 mediatype.Detect가 mp4로 판별하는 최소한의 업로드 데이터입니다.
+var testMP4 = append([]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), make([]byte, 64*1024)...)
+
+type testEnv struct {
+	srv       *server
+	fake      *fakeTranscoder
+	client    pb.VideoStreamingServiceClient
+	admin     pb.TranscoderAdminServiceClient
+	outputDir string
+	retainDir string
+}
+
+// newTestEnv는 fakeTranscoder를 쓰는 internal 서버를 bufconn 위에서 실행합니다.
+// 화질은 360p, 720p 두 개이고, configure로 바꾸지 않으면 재시도는 하지 않습니다.
+func newTestEnv(t *testing.T, fake *fakeTranscoder, configure ...func(*serverOptions)) *testEnv {
+	t.Helper()
+	root := t.TempDir()
+	env := &testEnv{
+		fake:      fake,
+		outputDir: filepath.Join(root, "output"),
+		retainDir: filepath.Join(root, "retained"),
+	}
+	t.Setenv("TEMP_DIR", filepath.Join(root, "temp"))
+	t.Setenv("OUTPUT_DIR", env.outputDir)
+
+	encoders, _ := fake.Encoders(context.Background())
+	ladder, err := normalizeLadder([]VideoQuality{
+		{Name: "360p", Height: 360, Bitrate: "800k"},
+		{Name: "720p", Height: 720, Bitrate: "2500k"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := qualities
+	qualities, err = resolveLadder(ladder, encoders, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { qualities = saved })
+
+	opts := serverOptions{
+		maxJobs:    2,
+		transcoder: fake,
+		retries:    retryPolicy{MaxAttempts: 1},
+		retention:  retention{Dir: env.retainDir, For: time.Hour},
+		audio:      audioConfig{Tracks: "all"},
+	}
+	for _, fn := range configure {
+		fn(&opts)
+	}
+	env.srv = NewInternalServer(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go env.srv.retainLoop(ctx, time.Minute)
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	pb.RegisterVideoStreamingServiceServer(gs, env.srv)
+	pb.RegisterTranscoderAdminServiceServer(gs, &adminServer{jobs: env.srv})
+	go gs.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		gs.Stop()
+		cancel()
+		env.srv.jobs.Wait()
+	})
+	env.client = pb.NewVideoStreamingServiceClient(conn)
+	env.admin = pb.NewTranscoderAdminServiceClient(conn)
+	return env
+}
+
+// upload는 data를 청크로 나누어 보내고 응답을 기다립니다.
+func (e *testEnv) upload(ctx context.Context, data []byte) (*pb.StreamResponse, error) {
+	stream, err := e.client.StreamVideo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	const chunkSize = 16 * 1024
+	for i := 0; i < len(data); i += chunkSize {
+		chunk := &pb.VideoChunk{Data: data[i:min(i+chunkSize, len(data))], ContentType: "video/mp4", Sequence: int32(i / chunkSize)}
+		if err := stream.Send(chunk); err != nil {
+			break // 서버가 먼저 끝낸 경우 CloseAndRecv에서 상태 확인
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// outputs는 화질별 출력 파일 내용을 반환합니다.
+func (e *testEnv) outputs(t *testing.T) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	for _, q := range qualities {
+		entries, _ := os.ReadDir(filepath.Join(e.outputDir, q.Directory))
+		for _, entry := range entries {
+			raw, err := os.ReadFile(filepath.Join(e.outputDir, q.Directory, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out[q.Name] = string(raw)
+		}
+	}
+	return out
+}
+
+func (e *testEnv) waitIdle(t *testing.T) {
+	t.Helper()
+	waitFor(t, "no active jobs", func() bool {
+		e.srv.mu.Lock()
+		defer e.srv.mu.Unlock()
+		return len(e.srv.activeProcessings) == 0
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamVideoConvertsAllQualities(t *testing.T) {
+	e := newTestEnv(t, newFakeTranscoder())
+
+	resp, err := e.upload(context.Background(), testMP4)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if !resp.Success || resp.RetainedUntil != "" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.Source == nil || resp.Source.DurationSeconds != 10 {
+		t.Fatalf("source metadata missing from response: %+v", resp.Source)
+	}
+
+	out := e.outputs(t)
+	for _, q := range qualities {
+		if !strings.HasPrefix(out[q.Name], "fake "+q.Name+"\n") {
+			t.Errorf("%s output = %q", q.Name, out[q.Name])
+		}
+		if !strings.Contains(out[q.Name], "audio 1 ") {
+			t.Errorf("%s output has no audio track line: %q", q.Name, out[q.Name])
+		}
+	}
+	e.waitIdle(t)
+	if entries, _ := os.ReadDir(os.Getenv("TEMP_DIR")); len(entries) != 0 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestStreamVideoRetainsPartialFailureForRetryJob(t *testing.T) {
+	fake := newFakeTranscoder()
+	fake.Failures = map[string]error{"720p": errors.New("encoder crashed")}
+	e := newTestEnv(t, fake)
+
+	resp, err := e.upload(context.Background(), testMP4)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if !resp.Success || !strings.Contains(resp.Message, "720p") || resp.RetainedUntil == "" {
+		t.Fatalf("expected partial success with retained source, got %+v", resp)
+	}
+	if out := e.outputs(t); out["360p"] == "" || out["720p"] != "" {
+		t.Fatalf("unexpected outputs after partial failure: %v", out)
+	}
+	e.waitIdle(t)
+
+	entries, err := os.ReadDir(e.retainDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one retained job, got %v (%v)", entries, err)
+	}
+	id := entries[0].Name()
+	manifest, err := readRetained(filepath.Join(e.retainDir, id, retainedManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(manifest.Failed, ",") != "720p" || strings.Join(manifest.Converted, ",") != "360p" {
+		t.Fatalf("unexpected retained manifest: failed %v, converted %v", manifest.Failed, manifest.Converted)
+	}
+
+	// 재변환이 다시 실패하면 manifest를 갱신하고 선점(.claimed)을 남기지 않음
+	manifestPath := filepath.Join(e.retainDir, id, retainedManifestName)
+	if _, err := e.admin.RetryJob(context.Background(), &pb.RetryJobRequest{Id: id}); err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+	e.waitIdle(t)
+	waitFor(t, "retained manifest rewritten", func() bool {
+		_, err := os.Stat(manifestPath)
+		return err == nil
+	})
+	if _, err := os.Stat(manifestPath + claimedSuffix); !os.IsNotExist(err) {
+		t.Fatalf("claimed manifest left after failed retry: %v", err)
+	}
+
+	// 실패 원인이 없어지면 실패한 화질만 변환하고 보관 디렉토리를 정리
+	delete(fake.Failures, "720p")
+	retry, err := e.admin.RetryJob(context.Background(), &pb.RetryJobRequest{Id: id})
+	if err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+	if strings.Join(retry.Qualities, ",") != "720p" {
+		t.Fatalf("RetryJob qualities = %v, want [720p]", retry.Qualities)
+	}
+	waitFor(t, "retained source removed", func() bool {
+		_, err := os.Stat(filepath.Join(e.retainDir, id))
+		return os.IsNotExist(err)
+	})
+	e.waitIdle(t)
+	if out := e.outputs(t); !strings.HasPrefix(out["720p"], "fake 720p\n") {
+		t.Fatalf("720p not converted by RetryJob: %v", out)
+	}
+
+	if _, err := e.admin.RetryJob(context.Background(), &pb.RetryJobRequest{Id: id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("second RetryJob = %v, want NotFound", err)
+	}
+}
+
+func TestRetryBackoffReleasesWorker(t *testing.T) {
+	fake := newFakeTranscoder()
+	fake.Failures = map[string]error{"720p": errors.New("encoder crashed")}
+	e := newTestEnv(t, fake, func(opts *serverOptions) {
+		opts.maxJobs = 1
+		opts.retries = retryPolicy{MaxAttempts: 2, Interval: 500 * time.Millisecond}
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := e.upload(context.Background(), testMP4)
+		errc <- err
+	}()
+	// backoff 동안에는 작업 슬롯을 내려놓고 대기 상태로 표시
+	waitFor(t, "worker released during backoff", func() bool {
+		e.srv.mu.Lock()
+		defer e.srv.mu.Unlock()
+		for _, info := range e.srv.activeProcessings {
+			if info.queued && info.converted["360p"] && len(e.srv.workers) == 0 {
+				return true
+			}
+		}
+		return false
+	})
+	if err := <-errc; err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	e.waitIdle(t)
+	if n := len(e.srv.workers); n != 0 {
+		t.Fatalf("%d worker slots still held after the job finished", n)
+	}
+}
+
+func TestStreamVideoCancellation(t *testing.T) {
+	converting := func(e *testEnv) func() bool {
+		return func() bool {
+			e.srv.mu.Lock()
+			defer e.srv.mu.Unlock()
+			for _, info := range e.srv.activeProcessings {
+				if info.current != "" {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	t.Run("client", func(t *testing.T) {
+		fake := newFakeTranscoder()
+		fake.EncodeDelay = time.Minute
+		e := newTestEnv(t, fake)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			_, err := e.upload(ctx, testMP4)
+			errc <- err
+		}()
+		waitFor(t, "conversion to start", converting(e))
+		cancel()
+		if err := <-errc; status.Code(err) != codes.Canceled {
+			t.Fatalf("upload error = %v, want Canceled", err)
+		}
+		e.waitIdle(t)
+		if out := e.outputs(t); len(out) != 0 {
+			t.Fatalf("outputs left after cancellation: %v", out)
+		}
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		fake := newFakeTranscoder()
+		fake.EncodeDelay = time.Minute
+		e := newTestEnv(t, fake)
+
+		errc := make(chan error, 1)
+		go func() {
+			_, err := e.upload(context.Background(), testMP4)
+			errc <- err
+		}()
+		waitFor(t, "conversion to start", converting(e))
+		jobs, err := e.admin.ListJobs(context.Background(), &pb.ListJobsRequest{})
+		if err != nil || len(jobs.Jobs) != 1 {
+			t.Fatalf("ListJobs = %v, %v", jobs, err)
+		}
+		if _, err := e.admin.CancelJob(context.Background(), &pb.CancelRequest{Id: jobs.Jobs[0].Id, Reason: "test"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; status.Code(err) != codes.Aborted {
+			t.Fatalf("upload error = %v, want Aborted", err)
+		}
+		e.waitIdle(t)
+		if entries, _ := os.ReadDir(e.retainDir); len(entries) != 0 {
+			t.Fatalf("cancelled job must not be retained: %v", entries)
+		}
+	})
+}
+
+func TestStreamVideoRejectsSourceWithoutVideo(t *testing.T) {
+	fake := newFakeTranscoder()
+	fake.Metadata.Video = nil
+	e := newTestEnv(t, fake)
+
+	_, err := e.upload(context.Background(), testMP4)
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "1 audio streams") {
+		t.Fatalf("upload error = %v, want InvalidArgument for missing video", err)
+	}
+	e.waitIdle(t)
+	if out := e.outputs(t); len(out) != 0 {
+		t.Fatalf("no rendition should be attempted without video: %v", out)
+	}
+}
+
+func TestSweepClaim(t *testing.T) {
+	tests := []struct {
+		name        string
+		owner       string
+		age         time.Duration
+		running     bool
+		wantRelease bool
+	}{
+		{name: "fresh claim of another instance", owner: "other/1", age: time.Minute},
+		{name: "expired claim of another instance", owner: "other/1", age: claimLease + time.Minute, wantRelease: true},
+		{name: "claim left by this process before restart", owner: claimOwnerID, age: time.Minute, wantRelease: true},
+		{name: "claim being converted by this process", owner: claimOwnerID, age: claimLease + time.Minute, running: true},
+	}
+
+	// checkpoint 재개와 live 녹화는 같은 선점 방식을 사용
+	queues := []struct {
+		name     string
+		manifest func(owner string) any
+	}{
+		{name: manifestName, manifest: func(owner string) any {
+			return checkpointManifest{JobID: "process_1", ClaimedBy: owner}
+		}},
+		{name: recordingManifestName, manifest: func(owner string) any {
+			return recordingManifest{checkpointManifest: checkpointManifest{JobID: "live_rec00000", ClaimedBy: owner}}
+		}},
+	}
+
+	for _, q := range queues {
+		for _, tt := range tests {
+			t.Run(q.name+"/"+tt.name, func(t *testing.T) {
+				s := NewInternalServer(serverOptions{})
+				dir := t.TempDir()
+				if err := writeManifest(dir, q.name, q.manifest(tt.owner)); err != nil {
+					t.Fatal(err)
+				}
+				manifestPath := filepath.Join(dir, q.name)
+				if err := os.Rename(manifestPath, manifestPath+claimedSuffix); err != nil {
+					t.Fatal(err)
+				}
+				claimedAt := time.Now().Add(-tt.age)
+				if err := os.Chtimes(manifestPath+claimedSuffix, claimedAt, claimedAt); err != nil {
+					t.Fatal(err)
+				}
+				s.claims[dir] = tt.running
+
+				s.sweepClaim(dir, q.name)
+				_, err := os.Stat(manifestPath)
+				if released := err == nil; released != tt.wantRelease {
+					t.Fatalf("released = %t, want %t", released, tt.wantRelease)
+				}
+			})
+		}
+	}
+}
+
+func TestLiveMasterListsAudioTracks(t *testing.T) {
+	cfg := liveConfig{SegmentDuration: 4 * time.Second, Window: 30 * time.Second, AudioGroups: true}
+	renditions := liveLadder([]VideoQuality{{Name: "720p", Height: 720, Bitrate: "2500k", Directory: "720p", Codec: "h264", AudioCodec: "aac"}})
+	audio := []AudioRendition{{Name: "aac_128k", Codec: "aac", Bitrate: "128k", Directory: filepath.Join("audio", "aac_128k")}}
+	tracks := selectAudioTracks([]probe.AudioStream{
+		{Index: 1, Language: "kor", Default: true},
+		{Index: 2, Language: "eng", Title: "Commentary"},
+	}, "all")
+
+	dir := t.TempDir()
+	p, err := newLivePlaylists(cfg, dir, renditions, audio, tracks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(p.masterPath(liveMediaPlaylist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	master := string(raw)
+	for _, want := range []string{
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac_128k",NAME="kor",LANGUAGE="kor",DEFAULT=YES,AUTOSELECT=YES,URI="audio/aac_128k/kor/index.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac_128k",NAME="Commentary",LANGUAGE="eng",DEFAULT=NO,AUTOSELECT=YES,URI="audio/aac_128k/eng/index.m3u8"`,
+		`#EXT-X-STREAM-INF:BANDWIDTH=2890800,CODECS="avc1.64001f,mp4a.40.2",RESOLUTION=1280x720,AUDIO="aac_128k"`,
+	} {
+		if !strings.Contains(master, want) {
+			t.Errorf("master playlist missing %q:\n%s", want, master)
+		}
+	}
+	for _, v := range p.audio {
+		if _, err := os.Stat(v.dir); err != nil {
+			t.Errorf("audio track directory: %v", err)
+		}
+	}
+}
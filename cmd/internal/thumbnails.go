@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+)
+
+// thumbnailConfig는 대표 이미지, 썸네일, sprite sheet 생성 설정입니다.
+type thumbnailConfig struct {
+	Enabled         bool
+	PosterAt        time.Duration // 대표 이미지 위치 (영상보다 길면 중간 지점)
+	PosterScene     bool          // true면 장면 전환이 처음 감지된 프레임을 대표 이미지로 사용
+	SceneThreshold  float64
+	Width           int // 대표 이미지, 썸네일 너비
+	Count           int // 일정 간격 썸네일 수
+	SpriteInterval  time.Duration
+	SpriteWidth     int // sprite sheet 타일 너비
+	SpriteColumns   int
+	SpriteMaxFrames int // 긴 영상은 간격을 늘려 타일 수를 제한
+}
+
+// thumbnailConfigFromEnv는 환경변수에서 썸네일 설정을 읽습니다. 값이 범위를 벗어나면 오류를 반환합니다.
+//
+//	THUMBNAILS_ENABLED      false면 이미지를 만들지 않음 (기본 true)
+//	POSTER_TIMESTAMP        대표 이미지 위치 (기본 5s)
+//	POSTER_MODE             timestamp 또는 scene (장면 전환 감지, 없으면 POSTER_TIMESTAMP 사용)
+//	POSTER_SCENE_THRESHOLD  장면 전환 기준값 0~1 (기본 0.4)
+//	THUMBNAIL_WIDTH         썸네일 너비 (기본 320)
+//	THUMBNAIL_COUNT         일정 간격 썸네일 수 (기본 10, 0이면 만들지 않음)
+//	SPRITE_INTERVAL         sprite sheet 타일 간격 (기본 10s, 0이면 만들지 않음)
+//	SPRITE_WIDTH            sprite sheet 타일 너비 (기본 160)
+//	SPRITE_COLUMNS          sprite sheet 열 수 (기본 10)
+//	SPRITE_MAX_FRAMES       sprite sheet 최대 타일 수 (기본 100)
+func thumbnailConfigFromEnv() (thumbnailConfig, error) {
+	cfg := thumbnailConfig{
+		Enabled:         os.Getenv("THUMBNAILS_ENABLED") != "false",
+		PosterAt:        env.Duration("POSTER_TIMESTAMP", 5*time.Second),
+		PosterScene:     os.Getenv("POSTER_MODE") == "scene",
+		SceneThreshold:  env.Float("POSTER_SCENE_THRESHOLD", 0.4),
+		Width:           env.Int("THUMBNAIL_WIDTH", 320),
+		Count:           env.Int("THUMBNAIL_COUNT", 10),
+		SpriteInterval:  env.Duration("SPRITE_INTERVAL", 10*time.Second),
+		SpriteWidth:     env.Int("SPRITE_WIDTH", 160),
+		SpriteColumns:   env.Int("SPRITE_COLUMNS", 10),
+		SpriteMaxFrames: env.Int("SPRITE_MAX_FRAMES", 100),
+	}
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"THUMBNAIL_WIDTH", cfg.Width},
+		{"SPRITE_WIDTH", cfg.SpriteWidth},
+		{"SPRITE_COLUMNS", cfg.SpriteColumns},
+		{"SPRITE_MAX_FRAMES", cfg.SpriteMaxFrames},
+	} {
+		if v.value <= 0 {
+			return cfg, fmt.Errorf("%s must be positive, got %d", v.name, v.value)
+		}
+	}
+	if cfg.Count < 0 {
+		return cfg, fmt.Errorf("THUMBNAIL_COUNT must not be negative, got %d", cfg.Count)
+	}
+	if cfg.SpriteInterval < 0 {
+		return cfg, fmt.Errorf("SPRITE_INTERVAL must not be negative, got %v", cfg.SpriteInterval)
+	}
+	if cfg.SceneThreshold <= 0 || cfg.SceneThreshold > 1 {
+		return cfg, fmt.Errorf("POSTER_SCENE_THRESHOLD must be in (0, 1], got %g", cfg.SceneThreshold)
+	}
+	return cfg, nil
+}
+
+// videoSize는 썸네일 크기 계산에 필요한 원본 정보입니다 (회전 반영).
+type videoSize struct {
+	Duration      float64
+	Width, Height int
+}
+
+// scaledHeight는 width에 맞춘 높이를 짝수로 반환합니다 (ffmpeg scale=w:-2와 같은 계산).
+func (v videoSize) scaledHeight(width int) int {
+	h := int(math.Round(float64(width) * float64(v.Height) / float64(v.Width) / 2))
+	return max(h*2, 2)
+}
+
+// thumbnailDir은 작업의 이미지 출력 디렉토리입니다. 렌디션과 같은 OUTPUT_DIR 아래에 둡니다.
+func thumbnailDir(info *ProcessingInfo) string {
+	name := strings.TrimSuffix(info.filename, filepath.Ext(info.filename))
+	return filepath.Join(os.Getenv("OUTPUT_DIR"), "thumbnails", name)
+}
+
+// runThumbnails는 설정에 따라 이미지를 만들고 결과를 작업 정보에 기록합니다.
+func (s *server) runThumbnails(ctx context.Context, logger *slog.Logger, info *ProcessingInfo) {
+	if !s.thumbnails.Enabled {
+		return
+	}
+	// 이미지 생성 전체에도 화질 하나와 같은 제한 시간 적용
+	if timeout := s.timeouts.For(s.sourceDuration(info)); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	thumbnails, err := s.generateThumbnails(ctx, logger, info)
+	if err != nil {
+		logger.Warn("thumbnail generation failed", "error", err)
+	}
+	s.mu.Lock()
+	info.thumbnails = thumbnails
+	s.mu.Unlock()
+}
+
+// generateThumbnails는 대표 이미지, 일정 간격 썸네일, sprite sheet와 WebVTT를 만듭니다.
+// 이미지 생성 실패는 작업 실패로 처리하지 않으므로 만들어진 결과만 반환합니다.
+func (s *server) generateThumbnails(ctx context.Context, logger *slog.Logger, info *ProcessingInfo) (*pb.Thumbnails, error) {
+	cfg := s.thumbnails
+	ctx, span := tracing.Start(ctx, "thumbnails")
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	dir := thumbnailDir(info)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail directory: %v", err)
+	}
+	s.mu.Lock()
+	meta := info.metadata
+	s.mu.Unlock()
+	if meta == nil {
+		err = fmt.Errorf("source metadata not available")
+		return nil, err
+	}
+	width, height := meta.PrimaryVideo().DisplaySize()
+	size := videoSize{Duration: meta.Duration, Width: width, Height: height}
+	logger = logger.With("thumbnail_dir", dir)
+	result := &pb.Thumbnails{}
+
+	// 대표 이미지
+	poster := filepath.Join(dir, "poster.jpg")
+	if err = s.posterFrame(ctx, logger, cfg, size, info.tempPath, poster); err != nil {
+		return result, fmt.Errorf("poster: %v", err)
+	}
+	result.Poster = poster
+
+	// 일정 간격 썸네일: 각 구간의 가운데 프레임
+	for i := 0; i < cfg.Count && size.Duration > 0; i++ {
+		at := size.Duration * (float64(i) + 0.5) / float64(cfg.Count)
+		path := filepath.Join(dir, fmt.Sprintf("thumb_%03d.jpg", i+1))
+		if err = s.extractFrame(ctx, logger, info.tempPath, path, at, fmt.Sprintf("scale=%d:-2", cfg.Width)); err != nil {
+			return result, fmt.Errorf("thumbnail %d: %v", i+1, err)
+		}
+		result.Images = append(result.Images, path)
+	}
+
+	// sprite sheet + WebVTT
+	if cfg.SpriteInterval > 0 && size.Duration > 0 {
+		sprite, vtt := filepath.Join(dir, "sprite.jpg"), filepath.Join(dir, "sprite.vtt")
+		if err = s.spriteSheet(ctx, logger, cfg, size, info.tempPath, sprite, vtt); err != nil {
+			return result, fmt.Errorf("sprite: %v", err)
+		}
+		result.Sprite, result.SpriteVtt = sprite, vtt
+	}
+
+	logger.Info("thumbnails generated", "images", len(result.Images), "sprite", result.Sprite != "")
+	return result, nil
+}
+
+// posterFrame은 장면 전환 감지 또는 지정한 위치의 프레임을 대표 이미지로 저장합니다.
+func (s *server) posterFrame(ctx context.Context, logger *slog.Logger, cfg thumbnailConfig, size videoSize, input, output string) error {
+	scale := fmt.Sprintf("scale=%d:-2", cfg.Width)
+	if cfg.PosterScene {
+		os.Remove(output)
+		err := s.transcoder.Package(ctx, logger, PackageJob{
+			Input:        input,
+			Output:       output,
+			Filter:       fmt.Sprintf("select='gt(scene,%g)',%s", cfg.SceneThreshold, scale),
+			VariableRate: true,
+		})
+		if err == nil {
+			if st, statErr := os.Stat(output); statErr == nil && st.Size() > 0 {
+				return nil
+			}
+		}
+		// 장면 전환이 없으면 (정지 화면 등) 지정한 위치로 대체
+		logger.Info("no scene change detected, using poster timestamp", "error", err)
+	}
+
+	at := cfg.PosterAt.Seconds()
+	if size.Duration > 0 && at >= size.Duration {
+		at = size.Duration / 2
+	}
+	return s.extractFrame(ctx, logger, input, output, at, scale)
+}
+
+// extractFrame은 at 초 위치의 프레임 하나를 filter를 적용해 저장합니다.
+func (s *server) extractFrame(ctx context.Context, logger *slog.Logger, input, output string, at float64, filter string) error {
+	return s.transcoder.Package(ctx, logger, PackageJob{
+		Input:        input,
+		Output:       output,
+		Filter:       filter,
+		Seek:         at,
+		ImageQuality: 3,
+	})
+}
+
+// spriteSheet는 일정 간격 프레임을 타일로 이어 붙인 이미지와 각 구간의 좌표를 담은 WebVTT를 만듭니다.
+func (s *server) spriteSheet(ctx context.Context, logger *slog.Logger, cfg thumbnailConfig, size videoSize, input, output, vttPath string) error {
+	interval := cfg.SpriteInterval.Seconds()
+	if cfg.SpriteMaxFrames > 0 && size.Duration/interval > float64(cfg.SpriteMaxFrames) {
+		interval = size.Duration / float64(cfg.SpriteMaxFrames)
+	}
+	frames := int(math.Ceil(size.Duration / interval))
+	columns := min(cfg.SpriteColumns, frames)
+	rows := (frames + columns - 1) / columns
+	tileW, tileH := cfg.SpriteWidth, size.scaledHeight(cfg.SpriteWidth)
+
+	err := s.transcoder.Package(ctx, logger, PackageJob{
+		Input:        input,
+		Output:       output,
+		Filter:       fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d", interval, tileW, tileH, columns, rows),
+		ImageQuality: 4,
+	})
+	if err != nil {
+		return err
+	}
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	name := filepath.Base(output)
+	for i := 0; i < frames; i++ {
+		start := float64(i) * interval
+		end := math.Min(start+interval, size.Duration)
+		x, y := (i%columns)*tileW, (i/columns)*tileH
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", vttTime(start), vttTime(end), name, x, y, tileW, tileH)
+	}
+	return os.WriteFile(vttPath, []byte(vtt.String()), 0644)
+}
+
+// vttTime은 초를 WebVTT 시간 형식(HH:MM:SS.mmm)으로 변환합니다.
+func vttTime(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
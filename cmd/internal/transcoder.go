@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/probe"
+)
+
+// Transcoder는 원본 분석, 화질별 변환, 부가 산출물 생성을 실행하는 backend입니다.
+// 운영에서는 ffmpegTranscoder를, 테스트와 ffmpeg 없는 로컬 실행에서는 fakeTranscoder를 사용합니다.
+type Transcoder interface {
+	// Check는 backend를 사용할 수 있는지 확인합니다 (health check).
+	Check(ctx context.Context) error
+	// Encoders는 사용 가능한 encoder 이름을 반환합니다 (resolveLadder에 사용).
+	Encoders(ctx context.Context) (map[string]bool, error)
+	// Probe는 원본을 분석합니다. 영상 스트림이 없으면 probe.ErrNoVideo를 반환합니다.
+	Probe(ctx context.Context, path string) (*probe.Metadata, error)
+	// Encode는 한 화질을 변환합니다. ctx가 취소되면 중단하고 오류를 반환합니다.
+	Encode(ctx context.Context, logger *slog.Logger, job EncodeJob) error
+	// EncodeAudio는 음성 트랙 하나를 음성 전용 화질로 변환합니다.
+	EncodeAudio(ctx context.Context, logger *slog.Logger, job AudioJob) error
+	// MeasureLoudness는 음성 트랙 하나의 loudnorm 1차 분석을 실행합니다.
+	MeasureLoudness(ctx context.Context, logger *slog.Logger, job LoudnessJob) (*loudnessMeasurement, error)
+	// Package는 원본에서 이미지 한 장(대표 이미지, 썸네일, sprite sheet)을 만듭니다.
+	Package(ctx context.Context, logger *slog.Logger, job PackageJob) error
+	// Live는 입력이 끝날 때까지 읽으며 화질별 HLS segment와 source 재생목록(liveSourcePlaylist)을 씁니다.
+	// 입력이 EOF면 마지막 segment를 쓰고 nil을 반환합니다.
+	Live(ctx context.Context, logger *slog.Logger, job LiveJob) error
+}
+
+// EncodeJob은 화질 하나의 변환 요청입니다.
+type EncodeJob struct {
+	Input    string
+	Output   string
+	Quality  VideoQuality
+	Progress io.Writer // ffmpeg -progress 형식(key=value 줄)의 진행 상황, nil이면 보내지 않음
+
+	// Audio가 비어 있지 않으면 VideoStream과 Audio 트랙을 모두 출력에 포함합니다 (비어 있으면 ffmpeg 기본 선택).
+	VideoStream int
+	Audio       []audioTrack
+	Target      loudnessTarget // 트랙에 분석 결과가 있으면 정규화 목표
+}
+
+// AudioJob은 음성 트랙 하나를 음성 전용 화질로 변환하는 요청입니다.
+type AudioJob struct {
+	Input     string
+	Output    string
+	Rendition AudioRendition
+	Track     audioTrack
+	Target    loudnessTarget
+	Progress  io.Writer
+}
+
+// LoudnessJob은 음성 트랙 하나의 음량 분석 요청입니다.
+type LoudnessJob struct {
+	Input  string
+	Stream int // 원본 stream index
+	Target loudnessTarget
+}
+
+// PackageJob은 원본에서 이미지 한 장을 만드는 요청입니다.
+type PackageJob struct {
+	Input        string
+	Output       string
+	Filter       string  // 영상 filter (-vf)
+	Seek         float64 // 시작 위치(초), 0이면 처음부터
+	ImageQuality int     // JPEG 품질 (-q:v, 2~31, 0이면 기본값)
+	VariableRate bool    // select filter처럼 프레임을 건너뛰는 filter일 때 true
+}
+
+// LiveJob은 live 입력을 HLS로 변환하는 요청입니다.
+type LiveJob struct {
+	Input           io.Reader // 업로드 데이터 (업로드가 끝나면 EOF)
+	Format          string    // 입력 컨테이너 (mediatype.Format.Name)
+	Dir             string    // 화질별 하위 디렉토리(VideoQuality.Directory)에 출력
+	Renditions      []VideoQuality
+	Audio           []AudioRendition // 비어 있지 않으면 영상 화질은 음성 없이, AudioTracks는 화질마다 별도 HLS 출력으로 변환
+	AudioTracks     []audioTrack     // Audio 출력마다 변환할 원본 음성 트랙 (Index가 음수면 첫 번째 음성 트랙, liveAudioDir에 출력)
+	SegmentDuration time.Duration
+	ListSize        int       // source 재생목록에 유지할 segment 수
+	Progress        io.Writer // ffmpeg -progress 형식의 진행 상황, nil이면 보내지 않음
+
+	// RecordDir이 비어 있지 않으면 원본을 재인코딩 없이 RecordInterval마다 잘라 녹화 파일로 쓰고,
+	// 다 쓴 파일을 liveRecordList(CSV: 파일 이름, 시작 초, 끝 초)에 추가합니다.
+	RecordDir      string
+	RecordInterval time.Duration
+	RecordAligned  bool // true면 시계 기준(정각 등)으로 자름
+}
+
+// transcoderFromEnv는 TRANSCODER 환경 변수로 backend를 고릅니다 (ffmpeg 기본, fake).
+func transcoderFromEnv() (Transcoder, error) {
+	switch name := os.Getenv("TRANSCODER"); name {
+	case "", "ffmpeg":
+		return ffmpegTranscoder{limits: processLimitsFromEnv()}, nil
+	case "fake":
+		return newFakeTranscoder(), nil
+	default:
+		return nil, fmt.Errorf("unknown TRANSCODER %q (ffmpeg, fake)", name)
+	}
+}
+
+// ffmpegTranscoder는 ffmpeg와 ffprobe를 실행하는 Transcoder입니다.
+type ffmpegTranscoder struct {
+	limits processLimits
+}
+
+// Check는 ffmpeg와 원본 분석에 쓰는 ffprobe가 설치되어 있는지 확인합니다.
+func (ffmpegTranscoder) Check(ctx context.Context) error {
+	for _, name := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ffmpegTranscoder) Encoders(ctx context.Context) (map[string]bool, error) {
+	return detectEncoders(ctx)
+}
+
+func (ffmpegTranscoder) Probe(ctx context.Context, path string) (*probe.Metadata, error) {
+	return probe.Probe(ctx, path)
+}
+
+func (t ffmpegTranscoder) Encode(ctx context.Context, logger *slog.Logger, job EncodeJob) error {
+	q := job.Quality
+	logger = logger.With("quality", q.Name, "encoder", q.encoder)
+	logger.Info("converting", "output", job.Output)
+
+	args := []string{"-i", job.Input}
+	args = append(args, audioTrackArgs(job.VideoStream, job.Audio, job.Target)...)
+	args = append(args, "-vf", fmt.Sprintf("scale=-2:%d", q.Height))
+	args = append(args, encoderArgs(q)...)
+	args = append(args, t.threadArgs()...)
+	args = append(args, "-y", job.Output)
+	if err := t.runFFmpeg(ctx, logger, nil, job.Progress, args...); err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+	return nil
+}
+
+func (t ffmpegTranscoder) EncodeAudio(ctx context.Context, logger *slog.Logger, job AudioJob) error {
+	logger = logger.With("quality", job.Rendition.Name, "stream", job.Track.Index, "encoder", job.Rendition.encoder)
+	logger.Info("converting audio", "output", job.Output)
+
+	args := []string{"-i", job.Input, "-map", fmt.Sprintf("0:%d", job.Track.Index), "-vn"}
+	args = append(args, trackArgs("0", job.Track, job.Target)...)
+	args = append(args, audioEncoderArgs(job.Rendition)...)
+	args = append(args, t.threadArgs()...)
+	args = append(args, "-y", job.Output)
+	if err := t.runFFmpeg(ctx, logger, nil, job.Progress, args...); err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+	return nil
+}
+
+// MeasureLoudness는 트랙을 끝까지 decode하며 loudnorm 분석 결과(print_format=json)를 출력하게 하고 읽습니다.
+func (t ffmpegTranscoder) MeasureLoudness(ctx context.Context, logger *slog.Logger, job LoudnessJob) (*loudnessMeasurement, error) {
+	logger = logger.With("stream", job.Stream)
+	logger.Info("measuring loudness")
+
+	filter := fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g:print_format=json", job.Target.I, job.Target.TP, job.Target.LRA)
+	// JSON(12줄)이 출력의 마지막에 오므로 -nostats로 진행 상황 줄을 빼고 충분한 줄을 보관
+	output := newFFmpegLog(logger, 40)
+	err := t.run(ctx, logger, nil, nil, output, "-nostats", "-i", job.Input, "-map", fmt.Sprintf("0:%d", job.Stream),
+		"-vn", "-filter:a", filter, "-f", "null", "-")
+	if err != nil {
+		return nil, fmt.Errorf("loudness analysis failed: %v", err)
+	}
+	return parseLoudnorm(output.Tail())
+}
+
+func (t ffmpegTranscoder) Package(ctx context.Context, logger *slog.Logger, job PackageJob) error {
+	var args []string
+	if job.Seek > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(job.Seek, 'f', 3, 64))
+	}
+	args = append(args, "-i", job.Input, "-vf", job.Filter, "-frames:v", "1")
+	if job.VariableRate {
+		args = append(args, "-fps_mode", "vfr")
+	}
+	if job.ImageQuality > 0 {
+		args = append(args, "-q:v", strconv.Itoa(job.ImageQuality))
+	}
+	args = append(args, t.threadArgs()...)
+	args = append(args, "-y", job.Output)
+	return t.runFFmpeg(ctx, logger, nil, nil, args...)
+}
+
+// liveInputFormats는 live 입력 컨테이너별 ffmpeg demuxer 이름입니다 (pipe 입력은 형식을 추측하지 않도록 지정).
+var liveInputFormats = map[string]string{"ts": "mpegts", "flv": "flv", "mkv": "matroska", "webm": "matroska"}
+
+// Live는 입력을 한 번 decode하여 화질마다 별도의 HLS 출력으로 변환합니다.
+// 모든 화질에 같은 시점마다 keyframe을 넣어 segment 경계와 sequence 번호를 맞춥니다.
+func (t ffmpegTranscoder) Live(ctx context.Context, logger *slog.Logger, job LiveJob) error {
+	logger.Info("starting live encode", "renditions", len(job.Renditions), "output", job.Dir)
+	demuxer, ok := liveInputFormats[job.Format]
+	if !ok {
+		return fmt.Errorf("unsupported live input format %q", job.Format)
+	}
+	segment := strconv.FormatFloat(job.SegmentDuration.Seconds(), 'f', -1, 64)
+
+	args := []string{"-fflags", "+genpts", "-f", demuxer, "-i", "pipe:0"}
+	for _, q := range job.Renditions {
+		dir := filepath.Join(job.Dir, q.Directory)
+		args = append(args, "-map", "0:v:0", "-vf", fmt.Sprintf("scale=-2:%d", q.Height))
+		// 컨테이너 옵션(-f mp4 등) 대신 HLS muxer 사용
+		q.Container = ""
+		args = append(args, encoderArgs(q)...)
+		// master 재생목록의 CODECS(liveCodecs)와 맞도록 profile과 level 고정
+		level := liveH264Level(q.Height)
+		args = append(args, "-profile:v", "high", "-level:v", fmt.Sprintf("%d.%d", level/10, level%10))
+		if len(job.Audio) > 0 {
+			// 음성은 audio group 재생목록으로 따로 전송
+			args = append(args, "-an")
+		} else {
+			args = append(args, "-map", "0:a:0?")
+		}
+		args = append(args, "-force_key_frames", "expr:gte(t,n_forced*"+segment+")")
+		args = append(args, t.threadArgs()...)
+		args = append(args, liveHLSArgs(segment, job.ListSize, dir)...)
+	}
+	for _, r := range job.Audio {
+		for _, track := range job.AudioTracks {
+			stream := "0:a:0"
+			if track.Index >= 0 {
+				stream = fmt.Sprintf("0:%d", track.Index)
+			}
+			args = append(args, "-map", stream, "-vn", "-c:a", r.encoder, "-b:a", r.Bitrate)
+			if track.Language != "" {
+				args = append(args, "-metadata:s:a:0", "language="+track.Language)
+			}
+			args = append(args, liveHLSArgs(segment, job.ListSize, filepath.Join(job.Dir, liveAudioDir(r, track)))...)
+		}
+	}
+	if job.RecordDir != "" {
+		// 녹화는 모든 음성 트랙을 포함하여 stream copy, 다음 keyframe에서 잘림
+		muxer, ext := liveRecordFormat(job.Format)
+		args = append(args, "-map", "0:v:0", "-map", "0:a?", "-c", "copy", "-f", "segment",
+			"-segment_time", strconv.FormatFloat(job.RecordInterval.Seconds(), 'f', -1, 64),
+			"-segment_format", muxer, "-reset_timestamps", "1",
+			"-segment_list", filepath.Join(job.RecordDir, liveRecordList), "-segment_list_type", "csv")
+		if job.RecordAligned {
+			args = append(args, "-segment_atclocktime", "1")
+		}
+		args = append(args, filepath.Join(job.RecordDir, "rec_%05d"+ext))
+	}
+	if err := t.runFFmpeg(ctx, logger, job.Input, job.Progress, args...); err != nil {
+		return fmt.Errorf("live encode failed: %v", err)
+	}
+	return nil
+}
+
+// liveHLSArgs는 dir에 segment와 source 재생목록을 쓰는 HLS 출력 옵션입니다.
+func liveHLSArgs(segment string, listSize int, dir string) []string {
+	return []string{"-f", "hls", "-hls_time", segment, "-hls_list_size", strconv.Itoa(listSize),
+		"-hls_flags", "independent_segments+program_date_time+temp_file",
+		"-hls_segment_filename", filepath.Join(dir, liveSegmentPattern),
+		filepath.Join(dir, liveSourcePlaylist)}
+}
+
+// threadArgs는 FFMPEG_THREADS가 설정되어 있으면 encoder thread 수를 제한하는 출력 옵션을 반환합니다.
+func (t ffmpegTranscoder) threadArgs() []string {
+	if t.limits.Threads <= 0 {
+		return nil
+	}
+	return []string{"-threads", strconv.Itoa(t.limits.Threads)}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/probe"
+)
+
+// fakeTranscoder는 ffmpeg 없이 결정적인 출력을 만드는 Transcoder입니다.
+// 같은 입력이면 항상 같은 내용의 파일을 쓰므로 서버 단위 테스트와 ffmpeg 없는 로컬 실행에 사용합니다.
+// TRANSCODER=fake로 ffmpeg 없이 gateway, client와 함께 실행할 수 있어야 하므로 _test.go가 아닌 파일에 둡니다.
+type fakeTranscoder struct {
+	Metadata    probe.Metadata   // Probe 결과 (Size는 실제 파일 크기로 채움)
+	EncodeDelay time.Duration    // 화질 하나의 변환 시간 (취소 테스트용)
+	Failures    map[string]error // 화질 이름별로 Encode가 반환할 오류
+}
+
+func newFakeTranscoder() *fakeTranscoder {
+	return &fakeTranscoder{
+		Metadata: probe.Metadata{
+			Container: "mov,mp4,m4a,3gp,3g2,mj2",
+			Duration:  10,
+			Bitrate:   5_000_000,
+			Video: []probe.VideoStream{{
+				Index: 0, Codec: "h264", Profile: "High", Width: 1920, Height: 1080,
+				FrameRate: 30, PixelFormat: "yuv420p",
+			}},
+			Audio: []probe.AudioStream{{
+				Index: 1, Codec: "aac", Profile: "LC", Channels: 2, ChannelLayout: "stereo",
+				SampleRate: 48000, Default: true,
+			}},
+		},
+	}
+}
+
+func (f *fakeTranscoder) Check(ctx context.Context) error {
+	return nil
+}
+
+// Encoders는 코덱별 첫 번째 encoder 후보를 모두 지원하는 것으로 보고합니다.
+func (f *fakeTranscoder) Encoders(ctx context.Context) (map[string]bool, error) {
+	encoders := make(map[string]bool)
+	for _, table := range []map[string][]string{videoEncoders, audioEncoders} {
+		for _, candidates := range table {
+			encoders[candidates[0]] = true
+		}
+	}
+	return encoders, nil
+}
+
+// Probe는 설정된 메타데이터를 반환합니다. 빈 파일이거나 Metadata에 영상 스트림이 없으면 probe.ErrNoVideo를 반환합니다.
+func (f *fakeTranscoder) Probe(ctx context.Context, path string) (*probe.Metadata, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	meta := f.Metadata
+	meta.Size = st.Size()
+	if st.Size() == 0 {
+		meta.Video = nil
+	}
+	if len(meta.Video) == 0 {
+		return &meta, probe.ErrNoVideo
+	}
+	return &meta, nil
+}
+
+// Encode는 EncodeDelay 동안 진행 상황을 네 번 보고한 후 화질 정보를 담은 파일을 씁니다.
+func (f *fakeTranscoder) Encode(ctx context.Context, logger *slog.Logger, job EncodeJob) error {
+	q := job.Quality
+	logger.Info("converting (fake)", "quality", q.Name, "output", job.Output)
+	st, err := os.Stat(job.Input)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+
+	const steps = 4
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("conversion failed: %v", ctx.Err())
+		case <-time.After(f.EncodeDelay / steps):
+		}
+		if job.Progress != nil {
+			state := "continue"
+			if i == steps {
+				state = "end"
+			}
+			outTime := time.Duration(f.Metadata.Duration * float64(time.Second) * float64(i) / steps)
+			fmt.Fprintf(job.Progress, "frame=%d\nfps=%g\nout_time_us=%d\nspeed=1.0x\nprogress=%s\n",
+				int(outTime.Seconds()*f.Metadata.PrimaryVideo().FrameRate), f.Metadata.PrimaryVideo().FrameRate,
+				outTime.Microseconds(), state)
+		}
+	}
+	if err := f.Failures[q.Name]; err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+
+	content := fmt.Sprintf("fake %s\nheight %d\nbitrate %s\ncodec %s/%s\ncontainer %s\nsource %s (%d bytes)\n",
+		q.Name, q.Height, q.Bitrate, q.Codec, q.AudioCodec, q.Container, filepath.Base(job.Input), st.Size())
+	for _, t := range job.Audio {
+		content += fakeTrackLine(t, job.Target)
+	}
+	return os.WriteFile(job.Output, []byte(content), 0644)
+}
+
+// EncodeAudio는 음성 전용 화질 정보를 담은 파일을 씁니다.
+func (f *fakeTranscoder) EncodeAudio(ctx context.Context, logger *slog.Logger, job AudioJob) error {
+	r := job.Rendition
+	logger.Info("converting audio (fake)", "quality", r.Name, "stream", job.Track.Index, "output", job.Output)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+	if err := f.Failures[r.Name]; err != nil {
+		return fmt.Errorf("conversion failed: %v", err)
+	}
+	content := fmt.Sprintf("fake audio %s\ncodec %s\nbitrate %s\ncontainer %s\nsource %s\n",
+		r.Name, r.Codec, r.Bitrate, r.Container, filepath.Base(job.Input)) + fakeTrackLine(job.Track, job.Target)
+	return os.WriteFile(job.Output, []byte(content), 0644)
+}
+
+// MeasureLoudness는 stream index에 따라 정해지는 측정값을 반환합니다 (트랙마다 1dB씩 작음).
+func (f *fakeTranscoder) MeasureLoudness(ctx context.Context, logger *slog.Logger, job LoudnessJob) (*loudnessMeasurement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("loudness analysis failed: %v", err)
+	}
+	i := -18 - float64(job.Stream)
+	return &loudnessMeasurement{InputI: i, InputTP: i + 16, InputLRA: 6, InputThresh: i - 10, TargetOffset: 0.5}, nil
+}
+
+func fakeTrackLine(t audioTrack, target loudnessTarget) string {
+	filter := t.Loudness.filter(target)
+	if filter == "" {
+		filter = "none"
+	}
+	return fmt.Sprintf("audio %d %s language=%q default=%t filter=%s\n", t.Index, t.Label, t.Language, t.Default, filter)
+}
+
+// Package는 요청 내용을 담은 파일을 이미지 대신 씁니다.
+func (f *fakeTranscoder) Package(ctx context.Context, logger *slog.Logger, job PackageJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("fake image\nsource %s\nfilter %s\nseek %.3f\n", filepath.Base(job.Input), job.Filter, job.Seek)
+	return os.WriteFile(job.Output, []byte(content), 0644)
+}
+
+// fakeLiveSegmentBytes는 fakeTranscoder.Live가 segment 하나를 만드는 입력 크기입니다.
+const fakeLiveSegmentBytes = 256 * 1024
+
+// Live는 입력 fakeLiveSegmentBytes마다 화질별 segment를 하나씩 쓰고 ffmpeg와 같은 형식으로 source 재생목록을 갱신합니다.
+// 입력이 끝나면 남은 데이터로 마지막 segment를 쓰고 #EXT-X-ENDLIST를 붙입니다.
+func (f *fakeTranscoder) Live(ctx context.Context, logger *slog.Logger, job LiveJob) error {
+	logger.Info("starting live encode (fake)", "renditions", len(job.Renditions), "output", job.Dir)
+	var durations []float64
+	var outTime time.Duration
+	recorder := &fakeRecorder{job: job}
+	defer recorder.abort()
+	// 출력 디렉토리별 segment 내용 (audio group이 있으면 영상 화질에는 음성 없음)
+	outputs := make(map[string]string)
+	for _, q := range job.Renditions {
+		outputs[filepath.Join(job.Dir, q.Directory)] = fmt.Sprintf("quality %s\nheight %d\naudio %t\n", q.Name, q.Height, len(job.Audio) == 0)
+	}
+	for _, r := range job.Audio {
+		for _, t := range job.AudioTracks {
+			outputs[filepath.Join(job.Dir, liveAudioDir(r, t))] = fmt.Sprintf("audio %s\nstream %d\nlanguage %q\n", r.Name, t.Index, t.Language)
+		}
+	}
+	writeSegment := func(size int64, ended bool) error {
+		if size > 0 {
+			d := job.SegmentDuration.Seconds() * float64(size) / fakeLiveSegmentBytes
+			durations = append(durations, d)
+			outTime += time.Duration(d * float64(time.Second))
+		}
+		seq := len(durations) - 1
+		for dir, content := range outputs {
+			if size > 0 {
+				content = fmt.Sprintf("fake live segment %d\n%sbytes %d\n", seq, content, size)
+				if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf(liveSegmentPattern, seq)), []byte(content), 0644); err != nil {
+					return err
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, liveSourcePlaylist), []byte(fakeSourcePlaylist(job, durations, ended)), 0644); err != nil {
+				return err
+			}
+		}
+		if err := recorder.rotate(outTime); err != nil {
+			return err
+		}
+		if job.Progress != nil {
+			state := "continue"
+			if ended {
+				state = "end"
+			}
+			fmt.Fprintf(job.Progress, "frame=%d\nfps=30\nout_time_us=%d\nspeed=1.0x\nprogress=%s\n",
+				int(outTime.Seconds()*30), outTime.Microseconds(), state)
+		}
+		return nil
+	}
+
+	buf := make([]byte, 32*1024)
+	var pending int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("live encode failed: %v", err)
+		}
+		n, err := job.Input.Read(buf)
+		if werr := recorder.write(buf[:n]); werr != nil {
+			return fmt.Errorf("live encode failed: %v", werr)
+		}
+		pending += int64(n)
+		for pending >= fakeLiveSegmentBytes {
+			if err := writeSegment(fakeLiveSegmentBytes, false); err != nil {
+				return fmt.Errorf("live encode failed: %v", err)
+			}
+			pending -= fakeLiveSegmentBytes
+		}
+		if err == io.EOF {
+			if err := writeSegment(pending, true); err != nil {
+				return fmt.Errorf("live encode failed: %v", err)
+			}
+			return recorder.close(outTime)
+		}
+		if err != nil {
+			return fmt.Errorf("live encode failed: %v", err)
+		}
+	}
+}
+
+// fakeSourcePlaylist는 마지막 job.ListSize개 segment로 ffmpeg hls muxer와 같은 형식의 재생목록을 만듭니다.
+func fakeSourcePlaylist(job LiveJob, durations []float64, ended bool) string {
+	first := max(len(durations)-job.ListSize, 0)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:%d\n",
+		int(job.SegmentDuration.Seconds()+0.5), first)
+	for i := first; i < len(durations); i++ {
+		fmt.Fprintf(&b, "#EXTINF:%.6f,\n%s\n", durations[i], fmt.Sprintf(liveSegmentPattern, i))
+	}
+	if ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
+
+// fakeRecorder는 입력을 그대로 녹화 파일에 쓰고 RecordInterval마다 다음 파일로 넘어갑니다.
+type fakeRecorder struct {
+	job   LiveJob
+	file  *os.File
+	index int
+	start time.Duration // 현재 녹화 파일의 시작 시점
+}
+
+func (r *fakeRecorder) write(p []byte) error {
+	if r.job.RecordDir == "" || len(p) == 0 {
+		return nil
+	}
+	if r.file == nil {
+		_, ext := liveRecordFormat(r.job.Format)
+		f, err := os.Create(filepath.Join(r.job.RecordDir, fmt.Sprintf("rec_%05d%s", r.index, ext)))
+		if err != nil {
+			return err
+		}
+		r.file = f
+	}
+	_, err := r.file.Write(p)
+	return err
+}
+
+// rotate는 현재 녹화 파일이 RecordInterval을 넘었으면 닫습니다. 다음 입력은 새 파일에 씁니다.
+func (r *fakeRecorder) rotate(outTime time.Duration) error {
+	if r.file == nil || outTime-r.start < r.job.RecordInterval {
+		return nil
+	}
+	return r.close(outTime)
+}
+
+// abort는 중단된 경우 다 쓰지 못한 녹화 파일을 segment list에 추가하지 않고 닫습니다 (ffmpeg가 종료된 경우와 같음).
+func (r *fakeRecorder) abort() {
+	if r.file != nil {
+		r.file.Close()
+	}
+}
+
+// close는 현재 녹화 파일을 닫고 segment list에 추가합니다.
+func (r *fakeRecorder) close(outTime time.Duration) error {
+	if r.file == nil {
+		return nil
+	}
+	name := filepath.Base(r.file.Name())
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	list, err := os.OpenFile(filepath.Join(r.job.RecordDir, liveRecordList), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer list.Close()
+	if _, err := fmt.Fprintf(list, "%s,%.6f,%.6f\n", name, r.start.Seconds(), outTime.Seconds()); err != nil {
+		return err
+	}
+	r.index++
+	r.start = outTime
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+)
+
+// jobWatchers는 작업 상태 변경을 WatchJobs 스트림에 전달합니다.
+// 느린 구독자 때문에 변환이 멈추지 않도록 버퍼가 차면 업데이트를 버리지 않고 구독을 끊습니다.
+type jobWatchers struct {
+	mu   sync.Mutex
+	subs map[*jobWatcher]struct{}
+}
+
+// jobWatcher는 WatchJobs 스트림 하나의 구독입니다.
+// updates가 닫히면 구독이 끝난 것이고, 남은 업데이트를 읽은 뒤 final 또는 lagged로 이유를 확인합니다.
+type jobWatcher struct {
+	id      string // 비어 있으면 전체 작업
+	updates chan *pb.JobStatus
+	final   *pb.JobStatus // id를 지정한 구독에서 작업이 끝난 상태
+	lagged  bool          // 버퍼가 가득 차서 끊김
+}
+
+func (w *jobWatchers) subscribe(id string) *jobWatcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.subs == nil {
+		w.subs = make(map[*jobWatcher]struct{})
+	}
+	sub := &jobWatcher{id: id, updates: make(chan *pb.JobStatus, 64)}
+	w.subs[sub] = struct{}{}
+	return sub
+}
+
+func (w *jobWatchers) unsubscribe(sub *jobWatcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.subs, sub)
+}
+
+func (w *jobWatchers) publish(st *pb.JobStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for sub := range w.subs {
+		if sub.id != "" && sub.id != st.Id {
+			continue
+		}
+		// 한 작업만 보는 구독의 종료 상태는 버퍼와 관계없이 전달되도록 채널을 닫아 알림
+		if sub.id != "" && st.State == "finished" {
+			sub.final = st
+			delete(w.subs, sub)
+			close(sub.updates)
+			continue
+		}
+		select {
+		case sub.updates <- st:
+		default:
+			sub.lagged = true
+			delete(w.subs, sub)
+			close(sub.updates)
+		}
+	}
+}
+
+// jobStatus는 s.mu를 잡은 상태에서 호출됩니다.
+func jobStatus(id string, info *ProcessingInfo) *pb.JobStatus {
+	job := &pb.JobStatus{
+		Id:         id,
+		RequestId:  info.requestID,
+		State:      info.state(),
+		Format:     info.format,
+		Bytes:      info.totalBytes,
+		AgeSeconds: time.Since(info.started).Seconds(),
+		Current:    info.current,
+		Resumed:    info.resumedFrom != "",
+		Progress:   info.progress.Percent,
+		Fps:        info.progress.FPS,
+		Speed:      info.progress.Speed,
+	}
+	if !info.converting && job.AgeSeconds > 0 {
+		job.ThroughputBps = float64(info.totalBytes) / job.AgeSeconds
+	}
+	for _, quality := range qualities {
+		if info.converted[quality.Name] {
+			job.Converted = append(job.Converted, quality.Name)
+		}
+	}
+	if len(qualities) > 0 {
+		done := float64(len(job.Converted))
+		if info.current != "" {
+			done += info.progress.Percent / 100
+		}
+		job.OverallProgress = done / float64(len(qualities)) * 100
+	}
+	return job
+}
+
+// updateJob은 작업 정보를 바꾸고 구독자에게 새 상태를 전달합니다.
+func (s *server) updateJob(sessionID string, info *ProcessingInfo, update func()) {
+	s.mu.Lock()
+	update()
+	st := jobStatus(sessionID, info)
+	s.mu.Unlock()
+	s.watchers.publish(st)
+}
+
+// finishJob은 작업을 목록에서 제거하고 구독자에게 finished 상태를 전달합니다.
+func (s *server) finishJob(sessionID string, info *ProcessingInfo) {
+	s.mu.Lock()
+	st := jobStatus(sessionID, info)
+	st.State = "finished"
+	delete(s.activeProcessings, sessionID)
+	s.mu.Unlock()
+	clearSessionMetrics(sessionID)
+	s.watchers.publish(st)
+}
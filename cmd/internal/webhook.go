@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/probe"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"google.golang.org/grpc/metadata"
+)
+
+// tenantHeader는 gateway가 인증된 tenant를 전달하는 metadata 키입니다.
+const tenantHeader = "x-tenant-id"
+
+// webhook 이벤트 data
+type (
+	acceptedData struct {
+		Format    string          `json:"format"`
+		Bytes     int64           `json:"bytes"`
+		Qualities []string        `json:"qualities"`
+		Source    *probe.Metadata `json:"source,omitempty"`
+		Recording *recordingData  `json:"recording,omitempty"` // live 업로드 녹화 작업
+	}
+
+	renditionData struct {
+		Quality         string  `json:"quality"`
+		Codec           string  `json:"codec"`
+		Container       string  `json:"container"`
+		Path            string  `json:"path"`
+		Bytes           int64   `json:"bytes"`
+		DurationSeconds float64 `json:"duration_seconds"`
+		// 출력에 포함된 음성 트랙 (원본 순서, 언어와 loudnorm 분석 결과)
+		AudioTracks []audioTrack `json:"audio_tracks,omitempty"`
+	}
+
+	// audioRenditionData는 음성 전용 화질 출력 하나입니다. HLS packager는 GroupID로 audio group을 만들고
+	// Language, Name, Default로 #EXT-X-MEDIA 항목을 채웁니다.
+	audioRenditionData struct {
+		GroupID    string `json:"group_id"`
+		Codec      string `json:"codec"`
+		Bitrate    string `json:"bitrate"`
+		Container  string `json:"container"`
+		Language   string `json:"language,omitempty"`
+		Name       string `json:"name"`
+		Default    bool   `json:"default"`
+		Normalized bool   `json:"normalized"` // EBU R128 loudnorm 적용 여부
+		Path       string `json:"path"`
+		Bytes      int64  `json:"bytes"`
+	}
+
+	jobResultData struct {
+		Message    string               `json:"message"`
+		Renditions []renditionData      `json:"renditions,omitempty"`
+		Audio      []audioRenditionData `json:"audio,omitempty"`
+		Errors     []string             `json:"errors,omitempty"`
+		Thumbnails *pb.Thumbnails       `json:"thumbnails,omitempty"`
+		// 원본이 보관되어 실패한 화질을 재변환할 수 있는 기한 (RFC 3339)
+		RetainedUntil string `json:"retained_until,omitempty"`
+	}
+
+	liveStartedData struct {
+		Format      string   `json:"format"`
+		Qualities   []string `json:"qualities"`
+		Playlist    string   `json:"playlist"`
+		DVRPlaylist string   `json:"dvr_playlist,omitempty"`
+		AudioGroups []string `json:"audio_groups,omitempty"` // 음성을 분리한 경우 HLS audio group ID
+	}
+
+	liveEndedData struct {
+		Message         string  `json:"message"`
+		VODPlaylist     string  `json:"vod_playlist,omitempty"` // LIVE_KEEP_SEGMENTS일 때만
+		Segments        int64   `json:"segments"`
+		DurationSeconds float64 `json:"duration_seconds"`
+	}
+)
+
+// incomingValue는 들어온 gRPC metadata에서 key의 첫 값을 반환합니다.
+func incomingValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// notify는 작업의 callback URL(없으면 전역 URL)로 이벤트를 보냅니다.
+func (s *server) notify(sessionID string, info *ProcessingInfo, eventType string, data any) {
+	jobID := info.jobID
+	if jobID == "" {
+		jobID = sessionID
+	}
+	s.notifier.Notify(info.callbackURL, webhook.Event{
+		Type:      eventType,
+		JobID:     jobID,
+		SessionID: sessionID,
+		RequestID: info.requestID,
+		Tenant:    info.tenant,
+		Data:      data,
+	})
+}
+
+// notifyResult는 변환 결과에 따라 job.completed 또는 job.failed 이벤트를 보냅니다.
+func (s *server) notifyResult(sessionID string, info *ProcessingInfo, successCount int, conversionErrors []string) {
+	result := jobResultData{Message: resultMessage(successCount, conversionErrors), Errors: conversionErrors}
+	s.mu.Lock()
+	if !info.retainedUntil.IsZero() {
+		result.RetainedUntil = info.retainedUntil.Format(time.RFC3339)
+	}
+	s.mu.Unlock()
+	if successCount == 0 {
+		s.notify(sessionID, info, webhook.JobFailed, result)
+		return
+	}
+	s.mu.Lock()
+	for _, quality := range qualities {
+		if info.converted[quality.Name] {
+			result.Renditions = append(result.Renditions, renditionOutput(info, info.rendition(quality), 0))
+		}
+	}
+	for _, r := range s.audio.Renditions {
+		for _, t := range info.audio {
+			if info.converted[audioKey(r, t)] {
+				result.Audio = append(result.Audio, s.audioRenditionOutput(info, r, t))
+			}
+		}
+	}
+	result.Thumbnails = info.thumbnails
+	s.mu.Unlock()
+	s.notify(sessionID, info, webhook.JobCompleted, result)
+}
+
+// renditionOutput은 변환된 화질의 출력 파일 정보를 반환합니다.
+func renditionOutput(info *ProcessingInfo, quality VideoQuality, seconds float64) renditionData {
+	path := filepath.Join(os.Getenv("OUTPUT_DIR"), quality.Directory, outputName(info.filename, quality.Container))
+	r := renditionData{Quality: quality.Name, Codec: quality.Codec, Container: quality.Container, Path: path, DurationSeconds: seconds}
+	if st, err := os.Stat(path); err == nil {
+		r.Bytes = st.Size()
+	}
+	r.AudioTracks = info.audio
+	return r
+}
+
+// audioRenditionOutput은 변환된 음성 전용 화질의 출력 파일 정보를 반환합니다.
+func (s *server) audioRenditionOutput(info *ProcessingInfo, r AudioRendition, t audioTrack) audioRenditionData {
+	a := audioRenditionData{
+		GroupID:    r.Name,
+		Codec:      r.Codec,
+		Bitrate:    r.Bitrate,
+		Container:  r.Container,
+		Language:   t.Language,
+		Name:       audioTrackName(t),
+		Default:    t.Default,
+		Normalized: t.Loudness.filter(s.audio.Target) != "",
+		Path:       audioOutputPath(info.filename, r, t),
+	}
+	if st, err := os.Stat(a.Path); err == nil {
+		a.Bytes = st.Size()
+	}
+	return a
+}
+
+func qualityNames() []string {
+	names := make([]string, len(qualities))
+	for i, quality := range qualities {
+		names[i] = quality.Name
+	}
+	return names
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/server/quota"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adminServer는 gateway의 관리 API입니다.
+type adminServer struct {
+	pb.UnimplementedAdminServiceServer
+	streams *VideoStreamingServer
+}
+
+func (a *adminServer) GetTenantUsage(ctx context.Context, req *pb.TenantUsageRequest) (*pb.TenantUsageResponse, error) {
+	resp := &pb.TenantUsageResponse{}
+	for _, u := range a.streams.quotas.Usage(req.Tenant) {
+		resp.Tenants = append(resp.Tenants, tenantUsageProto(u))
+	}
+	return resp, nil
+}
+
+func tenantUsageProto(u quota.Usage) *pb.TenantUsage {
+	return &pb.TenantUsage{
+		Tenant:             u.Tenant,
+		ActiveStreams:      int32(u.ActiveStreams),
+		BytesToday:         u.BytesToday,
+		RequestsLastMinute: int32(u.RequestsLastMinute),
+		TotalBytes:         u.TotalBytes,
+		TotalUploads:       u.TotalUploads,
+		Rejected:           u.Rejected,
+		Limits: &pb.TenantLimits{
+			MaxConcurrentStreams: int32(u.Limits.MaxConcurrentStreams),
+			MaxBytesPerDay:       u.Limits.MaxBytesPerDay,
+			MaxUploadBytes:       u.Limits.MaxUploadBytes,
+			RequestsPerMinute:    int32(u.Limits.RequestsPerMinute),
+		},
+	}
+}
+
+// errCancelledByAdmin은 관리 API로 업로드를 취소할 때 context cause로 사용됩니다.
+var errCancelledByAdmin = errors.New("stream cancelled by operator")
+
+// adminCancelled는 업로드가 관리 API로 취소되었으면 Aborted 오류를 반환합니다.
+// 클라이언트 스트림은 gateway가 취소할 수 없으므로 다음 청크를 받을 때 확인합니다.
+func adminCancelled(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), errCancelledByAdmin) {
+		return status.Error(codes.Aborted, errCancelledByAdmin.Error())
+	}
+	return nil
+}
+
+func (a *adminServer) ListStreams(ctx context.Context, req *pb.ListStreamsRequest) (*pb.ListStreamsResponse, error) {
+	s := a.streams
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &pb.ListStreamsResponse{}
+	for id, info := range s.activeStreams {
+		age := time.Since(info.started).Seconds()
+		st := &pb.StreamStatus{
+			Id:         id,
+			Tenant:     info.tenant,
+			Backend:    info.backend,
+			Spooled:    info.spooled,
+			Format:     info.format,
+			RequestId:  info.requestID,
+			Chunks:     int64(info.chunks),
+			Bytes:      info.bytesCnt,
+			AgeSeconds: age,
+		}
+		if age > 0 {
+			st.ThroughputBps = float64(info.bytesCnt) / age
+		}
+		resp.Streams = append(resp.Streams, st)
+	}
+	sort.Slice(resp.Streams, func(i, j int) bool { return resp.Streams[i].Id < resp.Streams[j].Id })
+	return resp, nil
+}
+
+func (a *adminServer) CancelStream(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	s := a.streams
+	s.mu.Lock()
+	info := s.activeStreams[req.Id]
+	s.mu.Unlock()
+	if info == nil {
+		return &pb.CancelResponse{}, nil
+	}
+	logging.FromContext(ctx).Warn("cancelling stream", "job_id", req.Id, "tenant", info.tenant, "reason", req.Reason)
+	info.cancel()
+	return &pb.CancelResponse{Cancelled: true}, nil
+}
@@ -4,58 +4,202 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"sync"
 	"time"
 
 	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/admin"
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/healthcheck"
+	"github.com/ket0825/grpc-streaming/internal/lifecycle"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/metrics"
+	"github.com/ket0825/grpc-streaming/internal/ops"
+	"github.com/ket0825/grpc-streaming/internal/server/backend"
+	"github.com/ket0825/grpc-streaming/internal/server/quota"
+	"github.com/ket0825/grpc-streaming/internal/server/spool"
+	"github.com/ket0825/grpc-streaming/internal/tlsconfig"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type VideoStreamingServer struct {
 	pb.UnimplementedVideoStreamingServiceServer
-	mu             sync.Mutex
-	activeStreams  map[string]*StreamInfo
-	internalClient pb.VideoStreamingServiceClient
+	mu            sync.Mutex
+	activeStreams map[string]*StreamInfo
+	backends      *backend.Pool
+	spool         *spool.Spool // nil이면 internal 서버 장애 시 업로드를 거절
+	quotas        *quota.Manager
+	policy        mediatype.Policy // 허용 컨테이너, content_type, 최대 크기
 }
 
 type StreamInfo struct {
 	chunks   int
 	bytesCnt int64
+
+	tenant    string
+	backend   string
+	spooled   bool
+	format    string
+	requestID string
+	started   time.Time
+	cancel    func() // 관리 API에서 업로드 취소
 }
 
-func NewVideoStreamingServer(internalClient pb.VideoStreamingServiceClient) *VideoStreamingServer {
+func NewVideoStreamingServer(backends *backend.Pool, quotas *quota.Manager) *VideoStreamingServer {
 	return &VideoStreamingServer{
-		activeStreams:  make(map[string]*StreamInfo), // proto의 StreamVideo 참고. byte 형태로 들어온 stream을 VideoChunk로 변환.
-		internalClient: internalClient, // internal 서버와의 연결
+		activeStreams: make(map[string]*StreamInfo), // proto의 StreamVideo 참고. byte 형태로 들어온 stream을 VideoChunk로 변환.
+		backends:      backends,                     // internal 서버들과의 연결 풀
+		quotas:        quotas,                       // tenant별 사용량 및 제한
+		policy:        mediatype.PolicyFromEnv(),
+	}
+}
+
+// openInternalStream은 건강한 internal 백엔드를 골라 스트림을 엽니다.
+// 스트림 생성에 실패한 백엔드는 제외하고 다른 백엔드로 재시도합니다.
+func (s *VideoStreamingServer) openInternalStream(ctx context.Context) (pb.VideoStreamingService_StreamVideoClient, *backend.Backend, func(), error) {
+	tried := make(map[string]bool)
+	for {
+		b, release, err := s.backends.Pick(tried)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		internalStream, err := b.Client.StreamVideo(ctx)
+		if err == nil {
+			return internalStream, b, release, nil
+		}
+
+		release()
+		s.reportFailure(ctx, b, err)
+		tried[b.Addr] = true
+		logging.FromContext(ctx).Warn("internal backend stream failed", "backend", b.Addr, "error", err)
+	}
+}
+
+// reportFailure는 backend 연결 오류를 pool에 반영합니다.
+// 전송 계층 오류(Unavailable)만 반영하고, internal 서버가 업로드를 거절한 응답이나
+// 클라이언트 취소, deadline으로 끝난 경우는 backend 문제가 아니므로 반영하지 않습니다.
+func (s *VideoStreamingServer) reportFailure(ctx context.Context, b *backend.Backend, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	if status.Code(err) != codes.Unavailable {
+		return
+	}
+	s.backends.ReportFailure(b, err)
+}
+
+// sendError는 internal 스트림의 Send 오류를 실제 상태로 바꿉니다.
+// internal 서버가 스트림을 먼저 끝내면 Send는 io.EOF만 반환하므로 CloseAndRecv로 서버의 상태를 받아옵니다.
+func sendError(internalStream pb.VideoStreamingService_StreamVideoClient, err error) error {
+	if err != io.EOF {
+		return err
+	}
+	if _, err := internalStream.CloseAndRecv(); err != nil {
+		return err
 	}
+	return status.Error(codes.Internal, "internal server closed the stream before the upload finished")
 }
 
-func (s *VideoStreamingServer) StreamVideo(stream pb.VideoStreamingService_StreamVideoServer) error {
+func (s *VideoStreamingServer) StreamVideo(stream pb.VideoStreamingService_StreamVideoServer) (err error) {
+	started := time.Now()
+	outcome := "failed"
+	defer func() {
+		if err == nil && outcome == "failed" {
+			outcome = "success"
+		}
+		uploadDuration.WithLabelValues(outcome).Observe(time.Since(started).Seconds())
+	}()
+
 	// Internal 서버와의 스트리밍 시작
-	ctx := context.Background()
+	// 클라이언트가 취소하거나 연결이 끊기면 internal 스트림도 함께 취소됨
+	ctx, cancel := context.WithCancelCause(forwardContext(stream.Context()))
+	defer cancel(nil)
+	streamID := fmt.Sprintf("stream_%d", time.Now().UnixNano())
+	// internal 서버의 webhook 이벤트가 클라이언트에 응답한 job ID를 담도록 전달
+	ctx = metadata.AppendToOutgoingContext(ctx, webhook.JobIDHeader, streamID)
+
+	ctx, span := tracing.Start(ctx, "forward", trace.WithAttributes(attribute.String("job.id", streamID)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx).With("job_id", streamID)
+	if id := auth.FromContext(ctx); id != nil {
+		logger = logger.With("tenant", id.Tenant)
+		span.SetAttributes(attribute.String("tenant", id.Tenant))
+	}
+
+	// tenant별 동시 업로드 수, 분당 요청 수, 하루 사용량 확인
+	tenant := tenantOf(ctx)
+	lease, err := s.quotas.Acquire(tenant)
+	if err != nil {
+		outcome = "rejected"
+		logger.Warn("upload rejected by quota", "tenant", tenant, "error", err)
+		return quotaStatus(tenant, err)
+	}
+	defer lease.Release()
 
-	internalStream, err := s.internalClient.StreamVideo(ctx)
+	// 앞부분으로 컨테이너 형식을 판별하여 영상이 아닌 업로드는 internal 서버에 연결하기 전에 거절
+	stream, format, err := s.validateUpload(stream)
 	if err != nil {
-		log.Printf("Internal server connection failed: %v", err)
+		outcome = "rejected"
+		logger.Warn("upload rejected", "error", err)
 		return err
 	}
+	logger = logger.With("format", format.Name)
+	span.SetAttributes(attribute.String("container", format.Name))
+	// live stream은 internal 서버가 받는 즉시 HLS로 변환하므로 spool하지 않고, 받을 수 있는 형식만 허용
+	live := incomingValue(ctx, mediatype.LiveHeader) == "true"
+	if live && !format.Streamable() {
+		outcome = "rejected"
+		logger.Warn("live upload rejected", "format", format.Name)
+		return status.Errorf(codes.InvalidArgument, "%s cannot be ingested live (use ts, flv, mkv or webm)", format.Name)
+	}
+	guard := &uploadGuard{lease: lease, tenant: tenant, policy: s.policy, format: format, live: live}
 
-	streamID := fmt.Sprintf("stream_%d", time.Now().UnixNano())
-	s.mu.Lock()
-	s.activeStreams[streamID] = &StreamInfo{}
-	s.mu.Unlock()
+	s.trackStart(streamID, &StreamInfo{
+		tenant:    tenant,
+		format:    format.Name,
+		requestID: logging.RequestID(ctx),
+		started:   started,
+		cancel:    func() { cancel(errCancelledByAdmin) },
+	})
+	defer s.trackEnd(streamID)
 
-	defer func() {
-		s.mu.Lock()
-		delete(s.activeStreams, streamID)
-		s.mu.Unlock()
-	}()
+	internalStream, internalBackend, release, err := s.openInternalStream(ctx)
+	if err != nil {
+		if s.spool == nil {
+			logger.Error("internal server connection failed", "error", err)
+			return err
+		}
+		if live {
+			logger.Error("internal server unavailable, live stream cannot be spooled", "error", err)
+			return status.Errorf(codes.Unavailable, "internal server unavailable: %v", err)
+		}
+		// Internal 서버를 사용할 수 없으면 디스크에 저장 후 나중에 전달
+		logger.Warn("internal server unavailable, spooling stream", "error", err)
+		outcome = "spooled"
+		s.trackRoute(streamID, "", true)
+		return s.spoolStream(ctx, stream, streamID, guard)
+	}
+	defer release()
+	span.SetAttributes(attribute.String("backend", internalBackend.Addr))
+	s.trackRoute(streamID, internalBackend.Addr, false)
 
-	log.Printf("Started new stream: %s", streamID)
+	logger = logger.With("backend", internalBackend.Addr)
+	logger.Info("started new stream")
 
 	// 데이터 스트리밍
 	for {
@@ -64,39 +208,101 @@ func (s *VideoStreamingServer) StreamVideo(stream pb.VideoStreamingService_Strea
 			break
 		}
 		if err != nil {
+			// 업로드가 중단되면 internal 스트림을 취소하여 잘린 파일이 변환되지 않도록 함
+			cancel(nil)
+			outcome = "cancelled"
+			logger.Warn("stream aborted by client", "error", err)
 			return fmt.Errorf("error receiving chunk: %v", err)
 		}
 
-		// Internal 서버로 청크 전송
-		if err := internalStream.Send(chunk); err != nil {
-			return fmt.Errorf("failed to send chunk to internal: %v", err)
+		// 관리 API로 취소된 업로드 (internal 스트림은 이미 취소됨)
+		if err := adminCancelled(ctx); err != nil {
+			outcome = "cancelled"
+			logger.Warn("stream cancelled by operator")
+			return err
+		}
+
+		// 업로드 최대 크기나 하루 사용량을 넘으면 internal 스트림을 취소하고 거절
+		if err := guard.add(len(chunk.Data)); err != nil {
+			cancel(nil)
+			outcome = "rejected"
+			logger.Warn("upload aborted by limit", "error", err)
+			return err
 		}
 
-		s.mu.Lock()
-		if info := s.activeStreams[streamID]; info != nil {
-			info.chunks++
-			info.bytesCnt += int64(len(chunk.Data))
-			if info.chunks%1000 == 0 {
-				log.Printf("Stream %s: Received %d chunks, %d bytes",
-					streamID, info.chunks, info.bytesCnt)
+		// Internal 서버로 청크 전송
+		if err := internalStream.Send(chunk); err != nil {
+			if err := adminCancelled(ctx); err != nil {
+				outcome = "cancelled"
+				return err
 			}
+			err = sendError(internalStream, err)
+			s.reportFailure(ctx, internalBackend, err)
+			logger.Warn("failed to send chunk to internal", "error", err)
+			return err
 		}
-		s.mu.Unlock()
+		bytesForwarded.Add(float64(len(chunk.Data)))
+		chunksForwarded.Inc()
+
+		s.trackChunk(streamID, len(chunk.Data))
 	}
 
 	// Internal 서버로부터 응답 받기
 	response, err := internalStream.CloseAndRecv()
 	if err != nil {
-		return fmt.Errorf("failed to get internal response: %v", err)
+		s.reportFailure(ctx, internalBackend, err)
+		logger.Warn("failed to get internal response", "error", err)
+		return err
 	}
 
-	log.Printf("Stream %s completed: %s", streamID, response.Message)
+	logger.Info("stream completed", "success", response.Success, "message", response.Message)
 
-	// 클라이언트에 응답
-	return stream.SendAndClose(&pb.StreamResponse{
-		Success: response.Success,
-		Message: response.Message,
-	})
+	if !response.Success {
+		outcome = "transcode_failed"
+	}
+
+	// 클라이언트에 응답 (변환 결과는 그대로 전달하고 job ID만 gateway 기준으로 설정)
+	response.JobId = streamID
+	return stream.SendAndClose(response)
+}
+
+func (s *VideoStreamingServer) trackStart(streamID string, info *StreamInfo) {
+	s.mu.Lock()
+	s.activeStreams[streamID] = info
+	s.mu.Unlock()
+	activeStreamsGauge.Inc()
+}
+
+// trackRoute는 업로드가 전달되는 internal 백엔드 또는 spool 여부를 기록합니다.
+func (s *VideoStreamingServer) trackRoute(streamID, backend string, spooled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info := s.activeStreams[streamID]; info != nil {
+		info.backend = backend
+		info.spooled = spooled
+	}
+}
+
+func (s *VideoStreamingServer) trackEnd(streamID string) {
+	s.mu.Lock()
+	delete(s.activeStreams, streamID)
+	s.mu.Unlock()
+	activeStreamsGauge.Dec()
+}
+
+func (s *VideoStreamingServer) trackChunk(streamID string, n int) {
+	bytesReceived.Add(float64(n))
+	chunksReceived.Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info := s.activeStreams[streamID]; info != nil {
+		info.chunks++
+		info.bytesCnt += int64(n)
+		if info.chunks%1000 == 0 {
+			slog.Debug("stream progress", "job_id", streamID, "chunks", info.chunks, "bytes", info.bytesCnt)
+		}
+	}
 }
 
 func main() {
@@ -105,27 +311,47 @@ func main() {
 	// 	log.Fatal("Error loading .env file")
 	// }
 
-	// 환경변수에서 Internal 서버 주소 가져오기
-	internalHost := os.Getenv("INTERNAL_HOST")
-	internalPort := os.Getenv("INTERNAL_PORT")
-	internalAddr := fmt.Sprintf("%s:%s", internalHost, internalPort)
+	// LOG_LEVEL, LOG_FORMAT으로 로그 레벨과 형식 설정
+	logging.Setup("grpc-server")
 
-	// Internal 서버 연결
-	internalConn, err := grpc.Dial(
-		internalAddr,
-		grpc.WithInsecure(),
+	// OpenTelemetry tracing (OTEL_EXPORTER_OTLP_ENDPOINT가 있으면 span 전송)
+	shutdownTracing, err := tracing.Setup(context.Background(), "grpc-server")
+	if err != nil {
+		logging.Fatal("failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// 환경변수에서 Internal 서버 목록 및 분산 정책 가져오기
+	backendCfg := backend.ConfigFromEnv()
+
+	// INTERNAL_TLS_ENABLED 또는 INTERNAL_TLS_CA_FILE이 있으면 internal 서버와 TLS로 연결
+	internalCreds := insecure.NewCredentials()
+	internalTLS, err := tlsconfig.ClientFromEnv("INTERNAL_TLS_")
+	if err != nil {
+		logging.Fatal("failed to configure internal TLS", "error", err)
+	}
+	if internalTLS != nil {
+		internalCreds = credentials.NewTLS(internalTLS)
+		slog.Info("internal TLS enabled", "server_name", os.Getenv("INTERNAL_TLS_SERVER_NAME"))
+	}
+	backendCfg.DialOptions = []grpc.DialOption{
+		grpc.WithTransportCredentials(internalCreds),
+		tracing.DialOption(),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                10 * time.Second,
 			Timeout:             2 * time.Second,
 			PermitWithoutStream: true,
 		}),
-	)
-	if err != nil {
-		log.Fatalf("Failed to connect to internal server: %v", err)
 	}
-	defer internalConn.Close()
 
-	internalClient := pb.NewVideoStreamingServiceClient(internalConn)
+	// Internal 서버 연결
+	backends, err := backend.NewPool(backendCfg)
+	if err != nil {
+		logging.Fatal("failed to connect to internal server", "error", err)
+	}
+	backends.Start()
+	defer backends.Close()
+	slog.Info("internal backends", "discovery", backendCfg.Discovery, "policy", backendCfg.Policy, "backends", backends.Statuses())
 
 	// 서버 설정
 	SERVER_PORT := os.Getenv("SERVER_PORT")
@@ -133,7 +359,7 @@ func main() {
 	serverAddr := fmt.Sprintf("%s:%s", SERVER_HOST, SERVER_PORT)
 	lis, err := net.Listen("tcp", serverAddr)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logging.Fatal("failed to listen", "addr", serverAddr, "error", err)
 	}
 
 	opts := []grpc.ServerOption{
@@ -144,13 +370,127 @@ func main() {
 			Time:              5 * time.Second,
 			Timeout:           1 * time.Second,
 		}),
+		tracing.ServerOption(),
+	}
+	unary := []grpc.UnaryServerInterceptor{metrics.UnaryServerInterceptor(), logging.UnaryServerInterceptor()}
+	streaming := []grpc.StreamServerInterceptor{metrics.StreamServerInterceptor(), logging.StreamServerInterceptor()}
+
+	// TLS_CERT_FILE, TLS_KEY_FILE이 있으면 TLS로 수신 (TLS_CLIENT_CA_FILE: 클라이언트 인증서 검증)
+	serverTLS, err := tlsconfig.ServerFromEnv("TLS_")
+	if err != nil {
+		logging.Fatal("failed to configure TLS", "error", err)
+	}
+	if serverTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLS)))
+		slog.Info("TLS enabled", "client_ca", os.Getenv("TLS_CLIENT_CA_FILE"))
+	}
+
+	// 업로드 인증 (API 키, JWT, mTLS). 아무것도 설정하지 않으면 인증 없이 동작
+	authCfg, err := auth.ConfigFromEnv()
+	if err != nil {
+		logging.Fatal("invalid auth config", "error", err)
+	}
+	if authCfg.Enabled() {
+		if authCfg.MTLS && os.Getenv("TLS_CLIENT_CA_FILE") == "" {
+			logging.Fatal("AUTH_MTLS requires TLS_CERT_FILE, TLS_KEY_FILE and TLS_CLIENT_CA_FILE")
+		}
+		authenticator, err := auth.New(authCfg)
+		if err != nil {
+			logging.Fatal("failed to initialize auth", "error", err)
+		}
+		unary = append(unary, auth.UnaryServerInterceptor(authenticator))
+		streaming = append(streaming, auth.StreamServerInterceptor(authenticator))
+		slog.Info("authentication enabled", "api_keys", len(authCfg.APIKeys), "jwks", authCfg.JWKSFile, "mtls", authCfg.MTLS)
+	} else {
+		slog.Warn("authentication disabled, anyone can upload")
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(streaming...))
+
+	// tenant별 quota (QUOTA_* 환경변수, 설정이 없으면 사용량만 집계)
+	quotaCfg, err := quota.ConfigFromEnv()
+	if err != nil {
+		logging.Fatal("invalid quota config", "error", err)
+	}
+	quotas, err := quota.New(quotaCfg)
+	if err != nil {
+		logging.Fatal("failed to initialize quotas", "error", err)
+	}
+	quotas.Start()
+	defer quotas.Stop()
+
+	videoServer := NewVideoStreamingServer(backends, quotas)
+
+	// Internal 서버 장애 시 업로드를 디스크에 저장하는 spool 설정
+	if spoolCfg, ok := spool.ConfigFromEnv(); ok {
+		sp, err := spool.New(spoolCfg, videoServer.forwardSpooled)
+		if err != nil {
+			logging.Fatal("failed to initialize spool", "error", err)
+		}
+		videoServer.spool = sp
+		sp.Start()
+		defer sp.Stop()
+		slog.Info("spooling enabled", "dir", spoolCfg.Dir, "max_upload_bytes", spoolCfg.MaxUploadBytes,
+			"max_total_bytes", spoolCfg.MaxTotalBytes)
 	}
 
 	server := grpc.NewServer(opts...)
-	pb.RegisterVideoStreamingServiceServer(server, NewVideoStreamingServer(internalClient))
+	pb.RegisterVideoStreamingServiceServer(server, videoServer)
 
-	log.Printf("Server started on %s", serverAddr)
-	if err := server.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+	// grpc.health.v1: 건강한 internal 백엔드가 있어야(또는 spool이 켜져 있어야) SERVING
+	checker := healthcheck.New(5 * time.Second)
+	checker.Add(pb.VideoStreamingService_ServiceDesc.ServiceName, "internal backend", func(ctx context.Context) error {
+		if backends.HealthyCount() == 0 && videoServer.spool == nil {
+			return backend.ErrNoHealthyBackend
+		}
+		return nil
+	})
+	checker.Register(server)
+
+	// 관리 API (ADMIN_ADDR이 있으면 별도 포트, 운영자 키로 인증)
+	adminSrv, err := admin.NewFromEnv()
+	if err != nil {
+		logging.Fatal("failed to configure admin server", "error", err)
+	}
+	if adminSrv != nil {
+		pb.RegisterAdminServiceServer(adminSrv, &adminServer{streams: videoServer})
+		if err := adminSrv.Start(); err != nil {
+			logging.Fatal("failed to start admin server", "error", err)
+		}
+		defer adminSrv.Stop()
+	}
+
+	// 운영용 HTTP 서버 (readiness/liveness probe, prometheus metrics)
+	registerPoolMetrics(videoServer)
+	opsServer := ops.New(os.Getenv("OPS_ADDR"))
+	opsServer.Handle("/metrics", metrics.Handler())
+	opsServer.Start()
+
+	ctx, stop := lifecycle.SignalContext(context.Background())
+	defer stop()
+	checker.Start(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server started", "addr", serverAddr)
+		serveErr <- server.Serve(lis)
+	}()
+	opsServer.SetReady(true)
+
+	select {
+	case err := <-serveErr:
+		logging.Fatal("failed to serve", "error", err)
+	case <-ctx.Done():
 	}
+
+	// SIGTERM: not-ready 전환 → 진행 중인 업로드 drain → 강제 종료
+	slog.Info("shutdown signal received")
+	lifecycle.Drain(server, lifecycle.ConfigFromEnv(), func() {
+		opsServer.SetReady(false)
+		checker.Shutdown()
+	}, nil)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opsServer.Shutdown(shutdownCtx)
+	slog.Info("server stopped")
 }
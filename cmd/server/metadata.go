@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"google.golang.org/grpc/metadata"
+)
+
+// tenantHeader는 인증된 호출자의 tenant를 internal 서버로 전달하는 metadata 키입니다.
+const tenantHeader = "x-tenant-id"
+
+// hopHeaders는 gRPC/HTTP2 전송 계층에서 관리하므로 internal 서버로 복사하지 않는 헤더입니다.
+var hopHeaders = map[string]bool{
+	"content-type":  true,
+	"user-agent":    true,
+	"te":            true,
+	"authorization": true,
+	// 자격 증명은 gateway에서 검증하고 internal 서버로는 tenant만 전달
+	auth.APIKeyHeader: true,
+	tenantHeader:      true,
+	// job ID는 gateway가 부여
+	webhook.JobIDHeader: true,
+	// trace context는 otelgrpc client handler가 현재 span 기준으로 다시 주입
+	"traceparent": true,
+	"tracestate":  true,
+	"baggage":     true,
+}
+
+// forwardContext는 클라이언트 스트림의 context에서 internal 서버용 context를 만듭니다.
+// 취소와 deadline은 부모 context를 통해 그대로 전파되고, 요청 metadata는 outgoing metadata로 복사됩니다.
+func forwardContext(ctx context.Context) context.Context {
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	out := metadata.MD{}
+	for k, v := range in {
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || hopHeaders[k] {
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+	if id := auth.FromContext(ctx); id != nil {
+		out.Set(tenantHeader, id.Tenant)
+	}
+	return metadata.NewOutgoingContext(ctx, out)
+}
+
+// incomingValue는 들어온 metadata에서 key의 첫 값을 반환합니다.
+func incomingValue(ctx context.Context, key string) string {
+	if v := metadata.ValueFromIncomingContext(ctx, key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// gateway 메트릭
+var (
+	activeStreamsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gateway_active_streams",
+		Help: "Number of upload streams currently being received.",
+	})
+
+	bytesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_received_bytes_total",
+		Help: "Total bytes received from clients.",
+	})
+
+	chunksReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_received_chunks_total",
+		Help: "Total chunks received from clients.",
+	})
+
+	bytesForwarded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_forwarded_bytes_total",
+		Help: "Total bytes forwarded to internal backends.",
+	})
+
+	chunksForwarded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_forwarded_chunks_total",
+		Help: "Total chunks forwarded to internal backends.",
+	})
+
+	uploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_upload_duration_seconds",
+		Help:    "Duration of upload streams from first byte to response, by outcome.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 14), // 1s ~ 2.3h
+	}, []string{"outcome"})
+
+	spooledUploads = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_spooled_uploads_total",
+		Help: "Total uploads spooled to disk because no internal backend was available.",
+	})
+
+	quotaRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_quota_rejections_total",
+		Help: "Total uploads rejected or aborted by tenant quotas, by exceeded limit.",
+	}, []string{"reason"})
+
+	uploadRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_upload_rejections_total",
+		Help: "Total uploads rejected by content validation, by reason.",
+	}, []string{"reason"})
+)
+
+// registerPoolMetrics는 백엔드 풀과 spool 상태를 조회 시점에 계산하는 메트릭을 등록합니다.
+func registerPoolMetrics(s *VideoStreamingServer) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "gateway_healthy_backends",
+		Help: "Number of internal backends currently eligible for new streams.",
+	}, func() float64 {
+		return float64(s.backends.HealthyCount())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "gateway_spool_queue_depth",
+		Help: "Number of spooled uploads waiting to be forwarded.",
+	}, func() float64 {
+		if s.spool == nil {
+			return 0
+		}
+		return float64(len(s.spool.Pending()))
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/server/quota"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// anonymousTenant는 인증을 사용하지 않을 때 모든 업로드가 속하는 tenant입니다.
+const anonymousTenant = "anonymous"
+
+func tenantOf(ctx context.Context) string {
+	if id := auth.FromContext(ctx); id != nil {
+		return id.Tenant
+	}
+	return anonymousTenant
+}
+
+// quotaStatus는 quota 오류를 ResourceExhausted로 변환하고 거절 메트릭을 기록합니다.
+func quotaStatus(tenant string, err error) error {
+	reason := "unknown"
+	switch {
+	case errors.Is(err, quota.ErrConcurrentStreams):
+		reason = "concurrent_streams"
+	case errors.Is(err, quota.ErrRateLimited):
+		reason = "requests_per_minute"
+	case errors.Is(err, quota.ErrDailyBytes):
+		reason = "bytes_per_day"
+	case errors.Is(err, quota.ErrUploadTooLarge):
+		reason = "upload_size"
+	}
+	quotaRejections.WithLabelValues(reason).Inc()
+	return status.Errorf(codes.ResourceExhausted, "tenant %s: %v", tenant, err)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/server/spool"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// spoolStream은 internal 서버를 사용할 수 없을 때 업로드를 디스크에 저장하고 job ID로 응답합니다.
+func (s *VideoStreamingServer) spoolStream(ctx context.Context, stream pb.VideoStreamingService_StreamVideoServer, jobID string, guard *uploadGuard) error {
+	w, err := s.spool.Create(jobID)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "internal server unavailable and spooling failed: %v", err)
+	}
+	defer w.Abort() // Commit 이후에는 아무 동작도 하지 않음
+	w.SetTraceContext(tracing.Inject(stream.Context()))
+	w.SetRequestID(logging.RequestID(stream.Context()))
+	tenant := ""
+	if id := auth.FromContext(ctx); id != nil {
+		tenant = id.Tenant
+	}
+	w.SetOwner(tenant, incomingValue(stream.Context(), webhook.CallbackURLHeader))
+
+	first := true
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error receiving chunk: %v", err)
+		}
+
+		if err := adminCancelled(ctx); err != nil {
+			return err
+		}
+		if err := guard.add(len(chunk.Data)); err != nil {
+			return err
+		}
+
+		if first {
+			w.SetMeta(chunk.ContentType, chunk.Headers)
+			first = false
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			if errors.Is(err, spool.ErrTooLarge) || errors.Is(err, spool.ErrSpoolFull) {
+				return status.Errorf(codes.ResourceExhausted, "failed to spool upload %s: %v", jobID, err)
+			}
+			return fmt.Errorf("failed to spool chunk: %v", err)
+		}
+
+		s.trackChunk(jobID, len(chunk.Data))
+	}
+
+	if err := w.Commit(); err != nil {
+		return status.Errorf(codes.Internal, "failed to spool upload %s: %v", jobID, err)
+	}
+
+	spooledUploads.Inc()
+	logging.FromContext(stream.Context()).Info("stream spooled, will be forwarded when an internal server is available", "job_id", jobID)
+
+	return stream.SendAndClose(&pb.StreamResponse{
+		Success: true,
+		Message: "Internal server unavailable; upload accepted and queued for processing",
+		JobId:   jobID,
+	})
+}
+
+// forwardSpooled는 spool된 업로드를 internal 서버로 다시 전송합니다.
+// internal 서버의 상태는 그대로 반환하여 spool이 재시도 여부를 판단하게 하고, 변환 실패 응답은 그대로 기록만 합니다.
+func (s *VideoStreamingServer) forwardSpooled(ctx context.Context, entry spool.Entry, data io.Reader) (err error) {
+	// 중간에 실패하면 스트림을 취소하여 internal 서버가 잘린 파일을 변환하지 않도록 함
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 원래 업로드와 같은 trace로 이어서 기록
+	ctx, span := tracing.Start(tracing.Extract(ctx, entry.TraceContext), "forward_spooled",
+		trace.WithAttributes(attribute.String("job.id", entry.JobID), attribute.Int("spool.attempt", entry.Attempts+1)))
+	defer func() { tracing.End(span, err) }()
+	if entry.RequestID != "" {
+		ctx = logging.OutgoingContext(ctx, entry.RequestID)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, webhook.JobIDHeader, entry.JobID)
+	if entry.Tenant != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, tenantHeader, entry.Tenant)
+	}
+	if entry.CallbackURL != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, webhook.CallbackURLHeader, entry.CallbackURL)
+	}
+
+	internalStream, internalBackend, release, err := s.openInternalStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	span.SetAttributes(attribute.String("backend", internalBackend.Addr))
+
+	buffer := make([]byte, 32*1024) // 32KB 버퍼
+	sequence := 0
+	for {
+		n, err := data.Read(buffer)
+		if n > 0 {
+			chunk := &pb.VideoChunk{
+				Data:        buffer[:n],
+				ContentType: entry.ContentType,
+				Headers:     entry.Headers,
+				Sequence:    int32(sequence),
+			}
+			if err := internalStream.Send(chunk); err != nil {
+				err = sendError(internalStream, err)
+				s.reportFailure(ctx, internalBackend, err)
+				return err
+			}
+			sequence++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spooled upload: %v", err)
+		}
+	}
+
+	response, err := internalStream.CloseAndRecv()
+	if err != nil {
+		s.reportFailure(ctx, internalBackend, err)
+		return err
+	}
+
+	logging.FromContext(ctx).Info("spooled stream completed", "job_id", entry.JobID, "backend", internalBackend.Addr,
+		"success", response.Success, "message", response.Message)
+	return nil
+}
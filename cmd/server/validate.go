@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/mediatype"
+	"github.com/ket0825/grpc-streaming/internal/server/quota"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// replayStream은 검사를 위해 먼저 받은 청크를 다시 돌려준 뒤 원래 스트림에서 이어서 받습니다.
+type replayStream struct {
+	pb.VideoStreamingService_StreamVideoServer
+	recv func() (*pb.VideoChunk, error)
+}
+
+func (r *replayStream) Recv() (*pb.VideoChunk, error) {
+	return r.recv()
+}
+
+// validateUpload는 업로드 앞부분으로 컨테이너 형식을 판별하고 허용 정책을 확인합니다.
+// internal 서버에 연결하기 전에 호출하여 영상이 아닌 업로드(HTML 오류 페이지, m3u8 등)를 바로 거절합니다.
+// 반환된 스트림은 검사에 사용한 청크부터 다시 읽습니다.
+func (s *VideoStreamingServer) validateUpload(stream pb.VideoStreamingService_StreamVideoServer) (pb.VideoStreamingService_StreamVideoServer, mediatype.Format, error) {
+	chunks, head, eof, err := mediatype.ReadHead(stream.Recv)
+	if err != nil {
+		return nil, mediatype.Format{}, fmt.Errorf("error receiving chunk: %v", err)
+	}
+	if len(head) == 0 {
+		uploadRejections.WithLabelValues("empty").Inc()
+		return nil, mediatype.Format{}, status.Error(codes.InvalidArgument, "empty upload")
+	}
+
+	format, err := mediatype.Detect(head)
+	if err != nil {
+		reason := "unknown_format"
+		if errors.Is(err, mediatype.ErrNotVideo) {
+			reason = "not_video"
+		}
+		uploadRejections.WithLabelValues(reason).Inc()
+		return nil, format, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.policy.Check(format, chunks[0].ContentType); err != nil {
+		reason := "format"
+		if errors.Is(err, mediatype.ErrContentTypeNotAllowed) {
+			reason = "content_type"
+		}
+		uploadRejections.WithLabelValues(reason).Inc()
+		return nil, format, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &replayStream{
+		VideoStreamingService_StreamVideoServer: stream,
+		recv:                                    mediatype.Replay(chunks, eof, stream.Recv),
+	}, format, nil
+}
+
+// uploadGuard는 업로드 1건의 크기 제한(업로드 정책)과 tenant 사용량(quota)을 함께 적용합니다.
+type uploadGuard struct {
+	lease  *quota.Lease
+	tenant string
+	policy mediatype.Policy
+	format mediatype.Format
+	live   bool // live stream은 끝이 없으므로 최대 크기 대신 tenant 사용량만 적용
+	bytes  int64
+}
+
+// add는 n 바이트를 기록하고 제한을 넘으면 ResourceExhausted 오류를 반환합니다.
+func (g *uploadGuard) add(n int) error {
+	g.bytes += int64(n)
+	if !g.live {
+		if err := g.policy.CheckSize(g.format, g.bytes); err != nil {
+			uploadRejections.WithLabelValues("size").Inc()
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+	}
+	if err := g.lease.Add(n); err != nil {
+		return quotaStatus(g.tenant, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// webhooksink는 작업 이벤트 webhook을 로컬에서 받아 보는 테스트용 HTTP 서버입니다.
+//
+//	WEBHOOK_SINK_ADDR        수신 주소 (기본 :8090)
+//	WEBHOOK_SECRET           설정하면 서명을 확인하고 맞지 않으면 401 응답
+//	WEBHOOK_SINK_FAIL_FIRST  이벤트마다 처음 N번은 503으로 응답하여 재시도 동작 확인
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
+)
+
+func main() {
+	logger := logging.Setup("webhook-sink")
+
+	addr := os.Getenv("WEBHOOK_SINK_ADDR")
+	if addr == "" {
+		addr = ":8090"
+	}
+	secret := []byte(os.Getenv("WEBHOOK_SECRET"))
+	failFirst, _ := strconv.Atoi(os.Getenv("WEBHOOK_SINK_FAIL_FIRST"))
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id := r.Header.Get(webhook.IDHeader)
+		if len(secret) > 0 {
+			err := webhook.Verify(secret, r.Header.Get(webhook.TimestampHeader), r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute)
+			if err != nil {
+				logger.Warn("rejected event", "event_id", id, "error", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		mu.Lock()
+		attempts[id]++
+		attempt := attempts[id]
+		mu.Unlock()
+		if attempt <= failFirst {
+			logger.Info("failing event on purpose", "event_id", id, "attempt", attempt)
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		var event webhook.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Info("received event", "path", r.URL.Path, "event_id", event.ID, "type", event.Type,
+			"job_id", event.JobID, "session_id", event.SessionID, "request_id", event.RequestID, "attempt", attempt)
+		os.Stdout.Write(append(body, '\n'))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	slog.Info("webhook sink listening", "addr", addr, "verify_signature", len(secret) > 0)
+	if err := http.ListenAndServe(addr, nil); err != nil {
+		logging.Fatal("webhook sink stopped", "error", err)
+	}
+}
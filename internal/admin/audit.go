@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"google.golang.org/grpc"
+)
+
+// auditInterceptor는 관리 API 호출을 운영자와 함께 기록합니다.
+func auditInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	audit(ctx, info.FullMethod, err)
+	return resp, err
+}
+
+// auditStreamInterceptor는 WatchJobs 같은 스트리밍 호출을 스트림이 끝날 때 기록합니다.
+func auditStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ss.Context()
+	logging.FromContext(ctx).Info("admin stream opened", "method", info.FullMethod, "operator", operator(ctx))
+	err := handler(srv, ss)
+	audit(ctx, info.FullMethod, err)
+	return err
+}
+
+func audit(ctx context.Context, method string, err error) {
+	logging.FromContext(ctx).Info("admin call", "method", method, "operator", operator(ctx), "error", err)
+}
+
+func operator(ctx context.Context) string {
+	if id := auth.FromContext(ctx); id != nil {
+		return id.Tenant
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/ket0825/grpc-streaming/internal/auth"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server는 업로드 포트와 분리된 관리용 gRPC 서버입니다.
+// 업로드용 인증(AUTH_*)과 별개로 ADMIN_API_KEYS의 운영자 키로만 접근할 수 있습니다.
+type Server struct {
+	*grpc.Server
+	addr string
+}
+
+// NewFromEnv는 환경변수로 관리 서버를 만듭니다. ADMIN_ADDR이 비어 있으면 nil을 반환합니다.
+// 운영자 키가 없으면 ADMIN_ALLOW_UNAUTHENTICATED=true일 때만 인증 없이 실행하고, 아니면 오류를 반환합니다.
+//
+//	ADMIN_ADDR           관리 API 주소 (예: ":5055")
+//	ADMIN_API_KEYS       "operator:key,operator:key" 형식의 운영자 키
+//	ADMIN_API_KEYS_FILE  한 줄에 하나씩 "operator:key" 형식의 운영자 키 파일
+//	ADMIN_ALLOW_UNAUTHENTICATED  "true"이면 운영자 키 없이 실행 (로컬 개발용)
+//	ADMIN_TLS_*          TLS 설정 (tlsconfig.ServerFromEnv 참고)
+func NewFromEnv() (*Server, error) {
+	addr := os.Getenv("ADMIN_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+
+	entries := strings.Split(os.Getenv("ADMIN_API_KEYS"), ",")
+	if path := os.Getenv("ADMIN_API_KEYS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ADMIN_API_KEYS_FILE: %v", err)
+		}
+		entries = append(entries, strings.Split(string(raw), "\n")...)
+	}
+	keys, err := auth.ParseAPIKeys(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	unary := []grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor()}
+	streaming := []grpc.StreamServerInterceptor{logging.StreamServerInterceptor()}
+	switch {
+	case len(keys) > 0:
+		authenticator := auth.NewAPIKeys(keys)
+		unary = append(unary, auth.UnaryServerInterceptor(authenticator))
+		streaming = append(streaming, auth.StreamServerInterceptor(authenticator))
+	case os.Getenv("ADMIN_ALLOW_UNAUTHENTICATED") == "true":
+		slog.Warn("admin API has no ADMIN_API_KEYS, anyone reaching it can use it", "addr", addr)
+	default:
+		return nil, fmt.Errorf("ADMIN_ADDR is set but no ADMIN_API_KEYS are configured (set ADMIN_ALLOW_UNAUTHENTICATED=true to run without authentication)")
+	}
+	unary = append(unary, auditInterceptor)
+	streaming = append(streaming, auditStreamInterceptor)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(streaming...),
+	}
+	tlsCfg, err := tlsconfig.ServerFromEnv("ADMIN_TLS_")
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	return &Server{Server: grpc.NewServer(opts...), addr: addr}, nil
+}
+
+// Start는 백그라운드에서 관리 서버를 실행합니다.
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address: %v", err)
+	}
+	go func() {
+		slog.Info("admin server listening", "addr", s.addr)
+		if err := s.Serve(lis); err != nil {
+			slog.Error("admin server failed", "error", err)
+		}
+	}()
+	return nil
+}
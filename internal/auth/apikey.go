@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// APIKeys는 x-api-key metadata의 정적 API 키로 인증합니다.
+type APIKeys struct {
+	tenants map[[sha256.Size]byte]string
+}
+
+// NewAPIKeys는 API 키 → tenant 맵으로 Authenticator를 만듭니다.
+// 키는 해시로만 보관하여 비교 시간이 키 내용에 따라 달라지지 않게 합니다.
+func NewAPIKeys(keys map[string]string) *APIKeys {
+	a := &APIKeys{tenants: make(map[[sha256.Size]byte]string, len(keys))}
+	for key, tenant := range keys {
+		a.tenants[sha256.Sum256([]byte(key))] = tenant
+	}
+	return a
+}
+
+func (a *APIKeys) Authenticate(ctx context.Context) (*Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(APIKeyHeader)
+	if len(keys) == 0 {
+		return nil, ErrNoCredentials
+	}
+	tenant, ok := a.tenants[sha256.Sum256([]byte(keys[0]))]
+	if !ok {
+		return nil, errors.New("unknown API key")
+	}
+	return &Identity{Tenant: tenant, Subject: tenant, Method: "api_key"}, nil
+}
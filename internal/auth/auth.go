@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoCredentials는 요청에 해당 인증 방식의 자격 증명이 없을 때 반환됩니다.
+// 다른 인증 방식을 이어서 시도합니다.
+var ErrNoCredentials = errors.New("no credentials")
+
+const (
+	// APIKeyHeader는 정적 API 키를 담는 metadata 키입니다.
+	APIKeyHeader = "x-api-key"
+	// AuthorizationHeader는 JWT bearer 토큰을 담는 metadata 키입니다.
+	AuthorizationHeader = "authorization"
+)
+
+// Identity는 인증된 호출자입니다. Tenant는 사용량 집계 등 호출자를 구분하는 단위입니다.
+type Identity struct {
+	Tenant  string
+	Subject string
+	Method  string // "api_key", "jwt", "mtls"
+}
+
+// Authenticator는 요청 context에서 호출자를 인증합니다.
+// 자격 증명이 없으면 ErrNoCredentials, 있지만 올바르지 않으면 다른 오류를 반환합니다.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (*Identity, error)
+}
+
+type identityKey struct{}
+
+// WithIdentity는 ctx에 인증된 호출자를 저장합니다.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext는 ctx에 저장된 호출자를 반환합니다. 인증을 사용하지 않으면 nil입니다.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Config는 서버 인증 설정입니다. 아무 방식도 설정하지 않으면 인증을 사용하지 않습니다.
+type Config struct {
+	APIKeys      map[string]string // API 키 → tenant
+	JWKSFile     string
+	JWTIssuer    string
+	JWTAudience  string
+	JWTTenantKey string // tenant를 담은 claim 이름, 없으면 sub 사용
+	MTLS         bool   // 검증된 클라이언트 인증서의 CN을 tenant로 사용
+}
+
+// ConfigFromEnv는 환경변수에서 인증 설정을 읽습니다.
+//
+//	AUTH_API_KEYS        "tenant:key,tenant:key" 형식의 API 키 목록
+//	AUTH_API_KEYS_FILE   한 줄에 하나씩 "tenant:key" 형식의 API 키 파일
+//	AUTH_JWKS_FILE       JWT 서명 검증용 JWKS 파일 (변경 시 자동으로 다시 읽음)
+//	AUTH_JWT_ISSUER      JWT iss 검증 값 (선택)
+//	AUTH_JWT_AUDIENCE    JWT aud 검증 값 (선택)
+//	AUTH_JWT_TENANT_CLAIM tenant claim 이름 (기본 "tenant")
+//	AUTH_MTLS            "true"이면 클라이언트 인증서로 인증 (TLS_CLIENT_CA_FILE 필요)
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		JWKSFile:     os.Getenv("AUTH_JWKS_FILE"),
+		JWTIssuer:    os.Getenv("AUTH_JWT_ISSUER"),
+		JWTAudience:  os.Getenv("AUTH_JWT_AUDIENCE"),
+		JWTTenantKey: os.Getenv("AUTH_JWT_TENANT_CLAIM"),
+		MTLS:         os.Getenv("AUTH_MTLS") == "true",
+	}
+	if cfg.JWTTenantKey == "" {
+		cfg.JWTTenantKey = "tenant"
+	}
+
+	entries := strings.Split(os.Getenv("AUTH_API_KEYS"), ",")
+	if path := os.Getenv("AUTH_API_KEYS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read AUTH_API_KEYS_FILE: %v", err)
+		}
+		entries = append(entries, strings.Split(string(raw), "\n")...)
+	}
+	keys, err := ParseAPIKeys(entries)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.APIKeys = keys
+	return cfg, nil
+}
+
+// ParseAPIKeys는 "tenant:key" 형식의 항목들을 API 키 → tenant 맵으로 변환합니다.
+// 빈 항목과 #으로 시작하는 항목은 무시합니다.
+func ParseAPIKeys(entries []string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		tenant, key, ok := strings.Cut(entry, ":")
+		if !ok || tenant == "" || key == "" {
+			return nil, fmt.Errorf("invalid API key entry %q, expected tenant:key", entry)
+		}
+		keys[key] = tenant
+	}
+	return keys, nil
+}
+
+// Enabled는 인증 방식이 하나라도 설정되어 있는지 반환합니다.
+func (c Config) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWKSFile != "" || c.MTLS
+}
+
+// New는 설정된 인증 방식들을 순서대로(mTLS, API 키, JWT) 시도하는 Authenticator를 만듭니다.
+func New(cfg Config) (Authenticator, error) {
+	var chain Chain
+	if cfg.MTLS {
+		chain = append(chain, MTLS{})
+	}
+	if len(cfg.APIKeys) > 0 {
+		chain = append(chain, NewAPIKeys(cfg.APIKeys))
+	}
+	if cfg.JWKSFile != "" {
+		jwt, err := NewJWT(cfg.JWKSFile, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTTenantKey)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, jwt)
+	}
+	return chain, nil
+}
+
+// Chain은 여러 Authenticator를 순서대로 시도합니다.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context) (*Identity, error) {
+	for _, a := range c {
+		id, err := a.Authenticate(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// publicMethod는 인증 없이 호출할 수 있는 메서드입니다. (health check, reflection)
+func publicMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.") ||
+		strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+func authenticate(ctx context.Context, a Authenticator, fullMethod string) (context.Context, error) {
+	if publicMethod(fullMethod) {
+		return ctx, nil
+	}
+	id, err := a.Authenticate(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		slog.Warn("authentication failed", "method", fullMethod, "error", err)
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	return WithIdentity(ctx, id), nil
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+// UnaryServerInterceptor는 a로 호출자를 인증하고 Identity를 handler context에 넣습니다.
+func UnaryServerInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, a, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor는 a로 호출자를 인증하고 Identity를 stream context에 넣습니다.
+func StreamServerInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), a, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testIssuer   = "https://issuer.example"
+	testAudience = "grpc-streaming"
+	testKid      = "key-1"
+)
+
+// newTestJWT는 ES256 키 하나를 담은 JWKS 파일로 JWT Authenticator를 만듭니다.
+func newTestJWT(t *testing.T) (*JWT, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks, err := json.Marshal(map[string]any{"keys": []jsonWebKey{{
+		Kty: "EC",
+		Kid: testKid,
+		Use: "sig",
+		Crv: "P-256",
+		X:   enc(key.X.FillBytes(make([]byte, 32))),
+		Y:   enc(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewJWT(path, testIssuer, testAudience, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, key
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":    "user-1",
+		"tenant": "acme",
+		"iss":    testIssuer,
+		"aud":    testAudience,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func withMetadata(key, value string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(key, value))
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	a, key := newTestJWT(t)
+
+	sign := func(t *testing.T, mutate func(jwt.MapClaims), kid string) string {
+		t.Helper()
+		claims := validClaims()
+		if mutate != nil {
+			mutate(claims)
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: func(t *testing.T) string { return sign(t, nil, testKid) },
+		},
+		{
+			name:  "no kid with single key",
+			token: func(t *testing.T) string { return sign(t, nil, "") },
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				return sign(t, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, testKid)
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing exp",
+			token: func(t *testing.T) string {
+				return sign(t, func(c jwt.MapClaims) { delete(c, "exp") }, testKid)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				return sign(t, func(c jwt.MapClaims) { c["aud"] = "other-service" }, testKid)
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				return sign(t, func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, testKid)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   func(t *testing.T) string { return sign(t, nil, "key-2") },
+			wantErr: true,
+		},
+		{
+			name: "HS256 instead of asymmetric alg",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+				token.Header["kid"] = testKid
+				signed, err := token.SignedString([]byte("shared-secret"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			wantErr: true,
+		},
+		{
+			name: "alg none",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+				token.Header["kid"] = testKid
+				signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			wantErr: true,
+		},
+		{
+			name: "no tenant or subject",
+			token: func(t *testing.T) string {
+				return sign(t, func(c jwt.MapClaims) {
+					delete(c, "sub")
+					delete(c, "tenant")
+				}, testKid)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withMetadata(AuthorizationHeader, "Bearer "+tt.token(t))
+			id, err := a.Authenticate(ctx)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got identity %+v", id)
+				}
+				if errors.Is(err, ErrNoCredentials) {
+					t.Fatalf("invalid token must not fall through to other authenticators: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id.Tenant != "acme" || id.Subject != "user-1" || id.Method != "jwt" {
+				t.Fatalf("unexpected identity %+v", id)
+			}
+		})
+	}
+}
+
+func TestJWTNoCredentials(t *testing.T) {
+	a, _ := newTestJWT(t)
+	for _, ctx := range []context.Context{
+		context.Background(),
+		withMetadata(AuthorizationHeader, "Basic dXNlcjpwYXNz"),
+	} {
+		if _, err := a.Authenticate(ctx); !errors.Is(err, ErrNoCredentials) {
+			t.Fatalf("expected ErrNoCredentials, got %v", err)
+		}
+	}
+}
+
+func TestAPIKeysAuthenticate(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"acme:active-key", "# globex:revoked-key", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAPIKeys(keys)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantTenant string
+		noCreds    bool // 자격 증명 없음 (다음 인증 방식으로 넘어감)
+		wantErr    bool // 자격 증명은 있지만 거부
+	}{
+		{name: "active key", ctx: withMetadata(APIKeyHeader, "active-key"), wantTenant: "acme"},
+		{name: "revoked key", ctx: withMetadata(APIKeyHeader, "revoked-key"), wantErr: true},
+		{name: "unknown key", ctx: withMetadata(APIKeyHeader, "guess"), wantErr: true},
+		{name: "tenant name as key", ctx: withMetadata(APIKeyHeader, "acme"), wantErr: true},
+		{name: "no key", ctx: context.Background(), noCreds: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.Authenticate(tt.ctx)
+			switch {
+			case tt.noCreds:
+				if !errors.Is(err, ErrNoCredentials) {
+					t.Fatalf("expected ErrNoCredentials, got %v", err)
+				}
+			case tt.wantErr:
+				if err == nil || errors.Is(err, ErrNoCredentials) {
+					t.Fatalf("expected rejection, got identity %+v, err %v", id, err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id.Tenant != tt.wantTenant || id.Method != "api_key" {
+					t.Fatalf("unexpected identity %+v", id)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAPIKeysInvalid(t *testing.T) {
+	for _, entry := range []string{"no-separator", ":key", "tenant:"} {
+		if _, err := ParseAPIKeys([]string{entry}); err == nil {
+			t.Errorf("ParseAPIKeys(%q): expected error", entry)
+		}
+	}
+}
+
+func TestChainStopsOnInvalidCredentials(t *testing.T) {
+	a, _ := newTestJWT(t)
+	chain := Chain{NewAPIKeys(map[string]string{"active-key": "acme"}), a}
+
+	// 잘못된 API 키는 JWT로 넘어가지 않고 거부되어야 함
+	if _, err := chain.Authenticate(withMetadata(APIKeyHeader, "revoked-key")); err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	if _, err := chain.Authenticate(context.Background()); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+}
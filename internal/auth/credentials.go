@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// perRPC는 클라이언트가 매 호출마다 보내는 인증 metadata입니다.
+type perRPC struct {
+	metadata func() (map[string]string, error)
+}
+
+func (c perRPC) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return c.metadata()
+}
+
+// TLS 없이도 사용할 수 있도록 허용. 신뢰할 수 없는 네트워크에서는 TLS와 함께 사용해야 함
+func (c perRPC) RequireTransportSecurity() bool {
+	return false
+}
+
+// APIKey는 x-api-key metadata로 API 키를 보내는 자격 증명을 반환합니다.
+func APIKey(key string) credentials.PerRPCCredentials {
+	return perRPC{metadata: func() (map[string]string, error) {
+		return map[string]string{APIKeyHeader: key}, nil
+	}}
+}
+
+// BearerToken은 authorization metadata로 JWT를 보내는 자격 증명을 반환합니다.
+func BearerToken(token string) credentials.PerRPCCredentials {
+	return perRPC{metadata: func() (map[string]string, error) {
+		return map[string]string{AuthorizationHeader: "Bearer " + token}, nil
+	}}
+}
+
+// BearerTokenFile은 호출마다 path에서 JWT를 읽어 보냅니다. 토큰이 주기적으로 갱신되는 경우 사용합니다.
+func BearerTokenFile(path string) credentials.PerRPCCredentials {
+	return perRPC{metadata: func() (map[string]string, error) {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file: %v", err)
+		}
+		return map[string]string{AuthorizationHeader: "Bearer " + strings.TrimSpace(string(raw))}, nil
+	}}
+}
+
+// CredentialsFromEnv는 클라이언트 환경변수에서 자격 증명을 만듭니다. 설정이 없으면 nil입니다.
+//
+//	API_KEY          정적 API 키
+//	AUTH_TOKEN       JWT bearer 토큰
+//	AUTH_TOKEN_FILE  JWT bearer 토큰 파일 (호출마다 다시 읽음)
+func CredentialsFromEnv() credentials.PerRPCCredentials {
+	if key := os.Getenv("API_KEY"); key != "" {
+		return APIKey(key)
+	}
+	if token := os.Getenv("AUTH_TOKEN"); token != "" {
+		return BearerToken(token)
+	}
+	if path := os.Getenv("AUTH_TOKEN_FILE"); path != "" {
+		return BearerTokenFile(path)
+	}
+	return nil
+}
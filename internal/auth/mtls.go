@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// MTLS는 TLS 핸드셰이크에서 검증된 클라이언트 인증서로 인증합니다.
+// 인증서 Subject의 CN을 tenant로 사용합니다.
+type MTLS struct{}
+
+func (MTLS) Authenticate(ctx context.Context) (*Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return nil, ErrNoCredentials
+	}
+	// 서버 tls.Config의 ClientCAs로 검증된 경우에만 VerifiedChains가 채워짐
+	if len(info.State.VerifiedChains) == 0 {
+		return nil, errors.New("client certificate not verified")
+	}
+	cert := info.State.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, errors.New("client certificate has no common name")
+	}
+	return &Identity{Tenant: cert.Subject.CommonName, Subject: cert.Subject.String(), Method: "mtls"}, nil
+}
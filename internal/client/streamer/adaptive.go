@@ -0,0 +1,81 @@
+package streamer
+
+import (
+	"sync"
+	"time"
+)
+
+// ChunkSizer는 전송 지연시간을 보고 청크 크기를 조정합니다.
+// Send가 빨리 끝나면(flow control 여유) 청크를 키우고, 지연이 목표를 넘으면 줄입니다.
+type ChunkSizer struct {
+	mu            sync.Mutex
+	size          int
+	min           int
+	max           int
+	targetLatency time.Duration
+	adaptive      bool
+}
+
+func NewChunkSizer(initial, min, max int, targetLatency time.Duration, adaptive bool) *ChunkSizer {
+	if min <= 0 {
+		min = initial
+	}
+	if max < min {
+		max = min
+	}
+	if initial < min {
+		initial = min
+	}
+	if initial > max {
+		initial = max
+	}
+	return &ChunkSizer{
+		size:          initial,
+		min:           min,
+		max:           max,
+		targetLatency: targetLatency,
+		adaptive:      adaptive,
+	}
+}
+
+// Size는 다음 청크에 사용할 크기를 반환합니다.
+func (c *ChunkSizer) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Max는 청크 크기의 상한을 반환합니다. 버퍼 할당에 사용됩니다.
+func (c *ChunkSizer) Max() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.adaptive {
+		return c.size
+	}
+	return c.max
+}
+
+// Observe는 n 바이트 청크 전송에 걸린 시간을 반영합니다.
+func (c *ChunkSizer) Observe(n int, elapsed time.Duration) {
+	if !c.adaptive || c.targetLatency <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case elapsed > c.targetLatency:
+		// 지연이 크면 절반으로 줄임
+		c.size /= 2
+		if c.size < c.min {
+			c.size = c.min
+		}
+	case elapsed < c.targetLatency/4 && n >= c.size:
+		// 청크가 꽉 찼고 여유가 있으면 두 배로 키움
+		c.size *= 2
+		if c.size > c.max {
+			c.size = c.max
+		}
+	}
+}
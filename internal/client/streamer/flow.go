@@ -0,0 +1,215 @@
+package streamer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+)
+
+const (
+	defaultChunkSize     = 32 * 1024 // 32KB
+	defaultMinChunkSize  = 16 * 1024
+	defaultTargetLatency = 200 * time.Millisecond
+	defaultStatsInterval = 10 * time.Second
+
+	// VideoChunk의 headers, content_type 등 메시지 오버헤드를 위한 여유분
+	chunkOverhead = 64 * 1024
+)
+
+// FlowConfig는 업로드 속도 제한과 청크 크기 조정 설정입니다.
+type FlowConfig struct {
+	RateLimit     int64 // bytes/sec, 0이면 제한 없음
+	Burst         int64 // 0이면 RateLimit과 동일
+	Adaptive      bool
+	InitialChunk  int
+	MinChunk      int
+	MaxChunk      int
+	TargetLatency time.Duration
+	StatsInterval time.Duration
+}
+
+// DefaultFlowConfig는 기존 동작(32KB 고정, 제한 없음)과 같은 설정을 반환합니다.
+// maxMsgSize는 MaxCallSendMsgSize 값으로, 청크 크기 상한 계산에 사용됩니다.
+func DefaultFlowConfig(maxMsgSize int) FlowConfig {
+	maxChunk := maxMsgSize - chunkOverhead
+	if maxChunk < defaultChunkSize {
+		maxChunk = defaultChunkSize
+	}
+	return FlowConfig{
+		InitialChunk:  defaultChunkSize,
+		MinChunk:      defaultMinChunkSize,
+		MaxChunk:      maxChunk,
+		TargetLatency: defaultTargetLatency,
+		StatsInterval: defaultStatsInterval,
+	}
+}
+
+// FlowConfigFromEnv는 환경변수로 기본 설정을 덮어씁니다.
+//
+//	UPLOAD_RATE_LIMIT    초당 최대 업로드 바이트 (0: 제한 없음)
+//	UPLOAD_BURST         토큰 버킷 burst 크기 (bytes)
+//	ADAPTIVE_CHUNK       "true"이면 청크 크기 자동 조정
+//	CHUNK_SIZE           초기 청크 크기 (bytes)
+//	CHUNK_SIZE_MIN       최소 청크 크기 (bytes)
+//	CHUNK_SIZE_MAX       최대 청크 크기 (bytes, MaxCallSendMsgSize 이하로 제한)
+//	CHUNK_TARGET_LATENCY 청크 전송 목표 지연시간 (예: 200ms)
+func FlowConfigFromEnv(maxMsgSize int) FlowConfig {
+	cfg := DefaultFlowConfig(maxMsgSize)
+	limit := cfg.MaxChunk
+
+	cfg.RateLimit = env.Int64("UPLOAD_RATE_LIMIT", cfg.RateLimit)
+	cfg.Burst = env.Int64("UPLOAD_BURST", cfg.Burst)
+	cfg.Adaptive = os.Getenv("ADAPTIVE_CHUNK") == "true"
+	cfg.InitialChunk = env.Int("CHUNK_SIZE", cfg.InitialChunk)
+	cfg.MinChunk = env.Int("CHUNK_SIZE_MIN", cfg.MinChunk)
+	cfg.MaxChunk = env.Int("CHUNK_SIZE_MAX", cfg.MaxChunk)
+	if cfg.MaxChunk > limit {
+		cfg.MaxChunk = limit
+	}
+	if v := os.Getenv("CHUNK_TARGET_LATENCY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.TargetLatency = d
+		} else {
+			slog.Warn("invalid env value", "key", "CHUNK_TARGET_LATENCY", "value", v, "error", err)
+		}
+	}
+	return cfg
+}
+
+// ThroughputStats는 업로드 처리량 통계입니다.
+type ThroughputStats struct {
+	Bytes       int64
+	Chunks      int64
+	Elapsed     time.Duration
+	AverageRate float64 // bytes/sec (전체 평균)
+	CurrentRate float64 // bytes/sec (최근 구간)
+	ChunkSize   int
+}
+
+// FlowController는 속도 제한, 청크 크기 조정, 처리량 측정을 묶어서 관리합니다.
+type FlowController struct {
+	bucket *TokenBucket
+	sizer  *ChunkSizer
+
+	mu            sync.Mutex
+	start         time.Time
+	bytes         int64
+	chunks        int64
+	windowStart   time.Time
+	windowBytes   int64
+	currentRate   float64
+	statsInterval time.Duration
+}
+
+func NewFlowController(cfg FlowConfig) *FlowController {
+	if cfg.InitialChunk <= 0 {
+		cfg.InitialChunk = defaultChunkSize
+	}
+	if cfg.RateLimit > 0 && cfg.Burst <= 0 {
+		cfg.Burst = cfg.RateLimit
+	}
+	// burst보다 큰 청크는 항상 대기를 유발하므로 상한을 맞춤
+	if cfg.Burst > 0 && int64(cfg.MaxChunk) > cfg.Burst {
+		cfg.MaxChunk = int(cfg.Burst)
+	}
+
+	now := time.Now()
+	return &FlowController{
+		bucket:        NewTokenBucket(cfg.RateLimit, cfg.Burst),
+		sizer:         NewChunkSizer(cfg.InitialChunk, cfg.MinChunk, cfg.MaxChunk, cfg.TargetLatency, cfg.Adaptive),
+		start:         now,
+		windowStart:   now,
+		statsInterval: cfg.StatsInterval,
+	}
+}
+
+// NewBuffer는 최대 청크 크기만큼의 읽기 버퍼를 할당합니다.
+func (f *FlowController) NewBuffer() []byte {
+	return make([]byte, f.sizer.Max())
+}
+
+// ReadChunk는 현재 청크 크기만큼 r에서 읽습니다.
+// 마지막 조각은 데이터와 함께 nil을 반환하고, 다음 호출에서 io.EOF를 반환합니다.
+func (f *FlowController) ReadChunk(r io.Reader, buffer []byte) (int, error) {
+	size := f.sizer.Size()
+	if size > len(buffer) {
+		size = len(buffer)
+	}
+	n, err := io.ReadFull(r, buffer[:size])
+	if err == io.ErrUnexpectedEOF || (err == io.EOF && n > 0) {
+		return n, nil
+	}
+	return n, err
+}
+
+// Send는 n 바이트에 대해 속도 제한을 적용한 후 send를 호출하고, 걸린 시간으로 청크 크기를 조정합니다.
+func (f *FlowController) Send(ctx context.Context, n int, send func() error) error {
+	waitStarted := time.Now()
+	if err := f.bucket.WaitN(ctx, n); err != nil {
+		return err
+	}
+	rateLimitWait.Add(time.Since(waitStarted).Seconds())
+
+	started := time.Now()
+	if err := send(); err != nil {
+		return err
+	}
+	elapsed := time.Since(started)
+
+	f.sizer.Observe(n, elapsed)
+	f.record(n)
+	return nil
+}
+
+func (f *FlowController) record(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bytes += int64(n)
+	f.chunks++
+	f.windowBytes += int64(n)
+	uploadedBytes.Add(float64(n))
+	uploadedChunks.Inc()
+	uploadChunkSize.Set(float64(f.sizer.Size()))
+
+	if window := time.Since(f.windowStart); window >= f.statsInterval && f.statsInterval > 0 {
+		f.currentRate = float64(f.windowBytes) / window.Seconds()
+		uploadThroughput.Set(f.currentRate)
+		f.windowBytes = 0
+		f.windowStart = time.Now()
+		slog.Info("upload throughput", "kbps", f.currentRate/1024, "avg_kbps", f.averageRateLocked()/1024,
+			"bytes", f.bytes, "chunks", f.chunks, "chunk_size", f.sizer.Size())
+	}
+}
+
+func (f *FlowController) averageRateLocked() float64 {
+	elapsed := time.Since(f.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(f.bytes) / elapsed
+}
+
+// Stats는 현재까지의 처리량 통계를 반환합니다.
+func (f *FlowController) Stats() ThroughputStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := f.currentRate
+	if current == 0 {
+		current = f.averageRateLocked()
+	}
+	return ThroughputStats{
+		Bytes:       f.bytes,
+		Chunks:      f.chunks,
+		Elapsed:     time.Since(f.start),
+		AverageRate: f.averageRateLocked(),
+		CurrentRate: current,
+		ChunkSize:   f.sizer.Size(),
+	}
+}
@@ -7,32 +7,79 @@ import (
 
 	pb "github.com/ket0825/grpc-streaming/api/proto"
 	"github.com/ket0825/grpc-streaming/internal/client/fetcher"
+	"github.com/ket0825/grpc-streaming/internal/logging"
+	"github.com/ket0825/grpc-streaming/internal/tracing"
+	"github.com/ket0825/grpc-streaming/internal/webhook"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 )
 
 type GRPCStreamer struct {
-	client     pb.VideoStreamingServiceClient
-	bufferSize int
+	client   pb.VideoStreamingServiceClient
+	flow     FlowConfig
+	callOpts []grpc.CallOption
+	callback string
 }
 
-func NewGRPCStreamer(conn *grpc.ClientConn) *GRPCStreamer {
-	return &GRPCStreamer{
-		client:     pb.NewVideoStreamingServiceClient(conn),
-		bufferSize: 32 * 1024, // 32KB buffer
+// Option은 GRPCStreamer 설정을 변경합니다.
+type Option func(*GRPCStreamer)
+
+// WithCredentials는 업로드 호출마다 보낼 자격 증명(API 키, JWT 등)을 지정합니다.
+// 연결 전체에 적용하려면 grpc.WithPerRPCCredentials를 사용합니다.
+func WithCredentials(creds credentials.PerRPCCredentials) Option {
+	return func(s *GRPCStreamer) {
+		s.callOpts = append(s.callOpts, grpc.PerRPCCredentials(creds))
 	}
 }
 
+// WithCallbackURL은 변환 완료 등 작업 이벤트를 받을 webhook URL을 지정합니다.
+func WithCallbackURL(url string) Option {
+	return func(s *GRPCStreamer) {
+		s.callback = url
+	}
+}
+
+// WithFlowConfig는 업로드 속도 제한과 청크 크기 조정 설정을 지정합니다.
+func WithFlowConfig(cfg FlowConfig) Option {
+	return func(s *GRPCStreamer) {
+		s.flow = cfg
+	}
+}
+
+func NewGRPCStreamer(conn *grpc.ClientConn, opts ...Option) *GRPCStreamer {
+	s := &GRPCStreamer{
+		client: pb.NewVideoStreamingServiceClient(conn),
+		flow:   DefaultFlowConfig(defaultChunkSize + chunkOverhead), // 32KB 고정 버퍼
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 // VideoResponse는 비디오 스트리밍 응답을 나타냅니다.
 
-func (s *GRPCStreamer) StreamToServer(ctx context.Context, videoResp *fetcher.VideoResponse) error {
-	stream, err := s.client.StreamVideo(ctx)
+func (s *GRPCStreamer) StreamToServer(ctx context.Context, videoResp *fetcher.VideoResponse) (err error) {
+	ctx, span := tracing.Start(ctx, "upload")
+	defer func() { tracing.End(span, err) }()
+
+	if logging.RequestID(ctx) == "" {
+		ctx = logging.OutgoingContext(ctx, logging.NewRequestID())
+	}
+	if s.callback != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, webhook.CallbackURLHeader, s.callback)
+	}
+
+	stream, err := s.client.StreamVideo(ctx, s.callOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
 	defer stream.CloseSend()
 
+	flow := NewFlowController(s.flow)
 	sequence := 0
-	buffer := make([]byte, s.bufferSize)
+	buffer := flow.NewBuffer()
 
 	headers := make(map[string]string)
 	for k, v := range videoResp.Headers {
@@ -40,9 +87,9 @@ func (s *GRPCStreamer) StreamToServer(ctx context.Context, videoResp *fetcher.Vi
 	}
 
 	// 비디오 스트리밍을 청크 단위로 전송
-	// flow control 로직 필요
+	// 속도 제한 및 청크 크기 조정은 FlowController가 담당
 	for {
-		n, err := videoResp.Body.Read(buffer)
+		n, err := flow.ReadChunk(videoResp.Body, buffer)
 		if err == io.EOF {
 			break
 		}
@@ -56,7 +103,10 @@ func (s *GRPCStreamer) StreamToServer(ctx context.Context, videoResp *fetcher.Vi
 			Headers:     headers,
 			Sequence:    int32(sequence),
 		}
-		if err := stream.Send(chunk); err != nil {
+		err = flow.Send(ctx, n, func() error {
+			return stream.Send(chunk)
+		})
+		if err != nil {
 			return fmt.Errorf("failed to send chunk: %w", err)
 		}
 
@@ -68,6 +118,10 @@ func (s *GRPCStreamer) StreamToServer(ctx context.Context, videoResp *fetcher.Vi
 		return fmt.Errorf("error receiving response: %w", err)
 	}
 
+	stats := flow.Stats()
+	logging.FromContext(ctx).Info("upload finished", "bytes", stats.Bytes, "chunks", stats.Chunks,
+		"avg_kbps", stats.AverageRate/1024, "job_id", response.JobId)
+
 	if !response.Success {
 		return fmt.Errorf("streaming failed: %s", response.Message)
 	}
@@ -0,0 +1,34 @@
+package streamer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 업로드 처리량 메트릭 (client OPS_ADDR의 /metrics)
+var (
+	uploadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "client_uploaded_bytes_total",
+		Help: "Total bytes sent to the gateway.",
+	})
+
+	uploadedChunks = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "client_uploaded_chunks_total",
+		Help: "Total chunks sent to the gateway.",
+	})
+
+	uploadThroughput = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "client_upload_throughput_bytes_per_second",
+		Help: "Achieved upload throughput over the last stats interval.",
+	})
+
+	uploadChunkSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "client_upload_chunk_size_bytes",
+		Help: "Current chunk size chosen by adaptive chunk sizing.",
+	})
+
+	rateLimitWait = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "client_upload_rate_limit_wait_seconds_total",
+		Help: "Total time spent waiting for the upload rate limit.",
+	})
+)
@@ -0,0 +1,76 @@
+package streamer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket은 초당 바이트 수 기준의 업로드 속도 제한기입니다.
+// burst보다 큰 요청도 허용하되, 부족한 토큰만큼 대기하여 평균 속도를 맞춥니다.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // bytes/sec
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket은 rate(bytes/sec)가 0 이하이면 nil(제한 없음)을 반환합니다.
+func NewTokenBucket(rate, burst int64) *TokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	return &TokenBucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// WaitN은 n 바이트를 보낼 수 있을 때까지 대기합니다.
+func (b *TokenBucket) WaitN(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Rate는 설정된 초당 바이트 수를 반환합니다. 제한이 없으면 0입니다.
+func (b *TokenBucket) Rate() int64 {
+	if b == nil {
+		return 0
+	}
+	return int64(b.rate)
+}
@@ -0,0 +1,43 @@
+// Package env는 환경 변수 설정 값을 읽는 helper입니다.
+// 값이 없으면 기본값을, 형식이 잘못되면 경고를 남기고 기본값을 반환합니다.
+package env
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Int는 key를 int로 읽습니다.
+func Int(key string, def int) int {
+	return parse(key, def, strconv.Atoi)
+}
+
+// Int64는 key를 int64로 읽습니다.
+func Int64(key string, def int64) int64 {
+	return parse(key, def, func(v string) (int64, error) { return strconv.ParseInt(v, 10, 64) })
+}
+
+// Float는 key를 float64로 읽습니다.
+func Float(key string, def float64) float64 {
+	return parse(key, def, func(v string) (float64, error) { return strconv.ParseFloat(v, 64) })
+}
+
+// Duration은 key를 time.ParseDuration 형식(예: 30s, 5m)으로 읽습니다.
+func Duration(key string, def time.Duration) time.Duration {
+	return parse(key, def, time.ParseDuration)
+}
+
+func parse[T any](key string, def T, parseFn func(string) (T, error)) T {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := parseFn(v)
+	if err != nil {
+		slog.Warn("invalid env value", "key", key, "value", v, "error", err)
+		return def
+	}
+	return n
+}
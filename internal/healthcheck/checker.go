@@ -0,0 +1,111 @@
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Check는 하나의 의존성을 확인합니다. nil이 아닌 오류를 반환하면 해당 서비스는 NOT_SERVING이 됩니다.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker는 서비스별 의존성 검사 결과를 grpc.health.v1 상태에 주기적으로 반영합니다.
+// 빈 서비스 이름("")은 서버 전체 상태를 나타냅니다.
+type Checker struct {
+	server   *health.Server
+	interval time.Duration
+
+	mu       sync.Mutex
+	services map[string][]namedCheck
+	lastErr  map[string]string
+}
+
+func New(interval time.Duration) *Checker {
+	return &Checker{
+		server:   health.NewServer(),
+		interval: interval,
+		services: make(map[string][]namedCheck),
+		lastErr:  make(map[string]string),
+	}
+}
+
+// Register는 grpc 서버에 health 서비스를 등록하고, ENABLE_REFLECTION=true이면 reflection도 등록합니다.
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.server)
+	if os.Getenv("ENABLE_REFLECTION") == "true" {
+		reflection.Register(s)
+		slog.Info("gRPC server reflection enabled")
+	}
+}
+
+// Add는 service에 검사 항목을 추가합니다. 검사 항목이 없는 서비스는 항상 SERVING입니다.
+func (c *Checker) Add(service, name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services[service] = append(c.services[service], namedCheck{name: name, check: check})
+}
+
+// Start는 즉시 한 번 검사한 뒤 ctx가 끝날 때까지 주기적으로 상태를 갱신합니다.
+func (c *Checker) Start(ctx context.Context) {
+	c.update(ctx)
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.update(ctx)
+			}
+		}
+	}()
+}
+
+// Shutdown은 모든 서비스를 NOT_SERVING으로 바꾸고 이후 갱신을 무시합니다. (drain 시작 시 호출)
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+func (c *Checker) update(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for service, checks := range c.services {
+		status := healthpb.HealthCheckResponse_SERVING
+		var failure string
+		for _, nc := range checks {
+			checkCtx, cancel := context.WithTimeout(ctx, c.interval)
+			err := nc.check(checkCtx)
+			cancel()
+			if err != nil {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+				failure = nc.name + ": " + err.Error()
+				break
+			}
+		}
+
+		// 상태가 바뀔 때만 로그
+		if failure != c.lastErr[service] {
+			if failure == "" {
+				slog.Info("health status changed", "service", service, "status", "SERVING")
+			} else {
+				slog.Warn("health status changed", "service", service, "status", "NOT_SERVING", "reason", failure)
+			}
+			c.lastErr[service] = failure
+		}
+		c.server.SetServingStatus(service, status)
+	}
+}
@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"google.golang.org/grpc"
+)
+
+// Config는 종료 절차 설정입니다.
+type Config struct {
+	// ReadinessDelay는 not-ready로 전환한 후 실제 drain을 시작하기까지 기다리는 시간입니다.
+	// k8s가 endpoint에서 pod를 제거할 시간을 줍니다.
+	ReadinessDelay time.Duration
+	// DrainTimeout은 진행 중인 스트림이 끝나기를 기다리는 최대 시간입니다.
+	DrainTimeout time.Duration
+}
+
+// ConfigFromEnv는 SHUTDOWN_READINESS_DELAY, SHUTDOWN_DRAIN_TIMEOUT 환경변수를 읽습니다.
+func ConfigFromEnv() Config {
+	return Config{
+		ReadinessDelay: env.Duration("SHUTDOWN_READINESS_DELAY", 5*time.Second),
+		DrainTimeout:   env.Duration("SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second),
+	}
+}
+
+// SignalContext는 SIGINT 또는 SIGTERM을 받거나 parent가 끝나면 취소되는 context를 반환합니다.
+func SignalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// Drain은 gRPC 서버를 graceful하게 종료합니다.
+// notReady를 먼저 호출하고 ReadinessDelay만큼 기다린 후 GracefulStop을 시작합니다.
+// DrainTimeout 안에 끝나지 않으면 onTimeout을 호출한 뒤 강제로 종료합니다.
+// 모든 스트림이 정상적으로 끝났으면 true를 반환합니다.
+func Drain(server *grpc.Server, cfg Config, notReady func(), onTimeout func()) bool {
+	if notReady != nil {
+		notReady()
+	}
+	if cfg.ReadinessDelay > 0 {
+		slog.Info("marked not ready, waiting before draining", "delay", cfg.ReadinessDelay)
+		time.Sleep(cfg.ReadinessDelay)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	slog.Info("draining in-flight streams", "timeout", cfg.DrainTimeout)
+	timer := time.NewTimer(cfg.DrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		slog.Info("all streams drained")
+		return true
+	case <-timer.C:
+		slog.Warn("drain timeout exceeded, forcing shutdown")
+		if onTimeout != nil {
+			onTimeout()
+		}
+		server.Stop()
+		<-done
+		return false
+	}
+}
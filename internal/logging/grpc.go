@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// OutgoingContext는 요청 ID를 ctx와 나가는 gRPC metadata에 설정합니다.
+func OutgoingContext(ctx context.Context, id string) context.Context {
+	ctx = WithRequestID(ctx, id)
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, id)
+}
+
+// incomingContext는 들어온 metadata의 요청 ID를 ctx에 저장합니다. 없으면 새로 생성합니다.
+func incomingContext(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 && ids[0] != "" {
+			return WithRequestID(ctx, ids[0])
+		}
+	}
+	id := NewRequestID()
+	// 요청 ID가 없던 요청도 다음 hop으로 같은 ID가 전달되도록 metadata에 추가
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Set(RequestIDKey, id)
+	return WithRequestID(metadata.NewIncomingContext(ctx, md), id)
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+// UnaryServerInterceptor는 요청 ID를 handler context에 넣습니다.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(incomingContext(ctx), req)
+	}
+}
+
+// StreamServerInterceptor는 요청 ID를 stream context에 넣습니다.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedStream{ServerStream: ss, ctx: incomingContext(ss.Context())})
+	}
+}
@@ -0,0 +1,165 @@
+package mediatype
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SniffSize는 컨테이너 판별에 필요한 최소 바이트 수입니다. (MPEG-TS는 sync byte 3개 확인)
+const SniffSize = 1024
+
+var (
+	ErrNotVideo      = errors.New("payload is not a video")
+	ErrUnknownFormat = errors.New("unrecognized container format")
+)
+
+// Format은 판별된 컨테이너 형식입니다.
+type Format struct {
+	Name      string // mp4, mov, mkv, webm, ts, avi, flv
+	Extension string // 임시 파일 확장자 (점 포함)
+	MIMEType  string
+}
+
+var (
+	MP4  = Format{Name: "mp4", Extension: ".mp4", MIMEType: "video/mp4"}
+	MOV  = Format{Name: "mov", Extension: ".mov", MIMEType: "video/quicktime"}
+	MKV  = Format{Name: "mkv", Extension: ".mkv", MIMEType: "video/x-matroska"}
+	WebM = Format{Name: "webm", Extension: ".webm", MIMEType: "video/webm"}
+	TS   = Format{Name: "ts", Extension: ".ts", MIMEType: "video/mp2t"}
+	AVI  = Format{Name: "avi", Extension: ".avi", MIMEType: "video/x-msvideo"}
+	FLV  = Format{Name: "flv", Extension: ".flv", MIMEType: "video/x-flv"}
+)
+
+// Formats는 지원하는 모든 컨테이너 형식입니다.
+var Formats = []Format{MP4, MOV, MKV, WebM, TS, AVI, FLV}
+
+// textSignatures는 영상 대신 잘못 전달되기 쉬운 텍스트 응답입니다. (HTTP 오류 페이지, HLS 재생목록 등)
+var textSignatures = []struct {
+	prefix string
+	what   string
+}{
+	{"#EXTM3U", "HLS playlist (m3u8)"},
+	{"<!DOCTYPE", "HTML document"},
+	{"<html", "HTML document"},
+	{"<HTML", "HTML document"},
+	{"<?xml", "XML document"},
+	{"<MPD", "DASH manifest"},
+	{"{", "JSON document"},
+}
+
+// Detect는 업로드 앞부분 head로 컨테이너 형식을 판별합니다.
+// 텍스트 응답 등 영상이 아닌 데이터는 ErrNotVideo, 알 수 없는 바이너리는 ErrUnknownFormat을 반환합니다.
+func Detect(head []byte) (Format, error) {
+	switch {
+	case len(head) >= 12 && isISOBMFF(head):
+		// QuickTime은 ftyp brand가 "qt  "이거나 ftyp 없이 moov/mdat 등으로 시작
+		if bytes.Equal(head[4:8], []byte("ftyp")) && !bytes.Equal(head[8:12], []byte("qt  ")) {
+			return MP4, nil
+		}
+		return MOV, nil
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		// EBML 헤더의 DocType으로 WebM과 Matroska 구분
+		if bytes.Contains(head[:min(len(head), 64)], []byte("webm")) {
+			return WebM, nil
+		}
+		return MKV, nil
+	case isMPEGTS(head):
+		return TS, nil
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("AVI ")):
+		return AVI, nil
+	case len(head) >= 4 && bytes.Equal(head[:3], []byte("FLV")) && head[3] == 0x01:
+		return FLV, nil
+	}
+
+	trimmed := bytes.TrimLeft(head, "\xef\xbb\xbf \t\r\n")
+	for _, sig := range textSignatures {
+		if bytes.HasPrefix(trimmed, []byte(sig.prefix)) {
+			return Format{}, fmt.Errorf("%w: looks like %s", ErrNotVideo, sig.what)
+		}
+	}
+	if isText(head) {
+		return Format{}, fmt.Errorf("%w: looks like text", ErrNotVideo)
+	}
+	return Format{}, ErrUnknownFormat
+}
+
+// isISOBMFF는 ISO base media(MP4/MOV)의 첫 box 타입을 확인합니다.
+func isISOBMFF(head []byte) bool {
+	switch string(head[4:8]) {
+	case "ftyp", "moov", "mdat", "free", "wide", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// isMPEGTS는 188바이트(TS) 또는 192바이트(M2TS) 간격의 sync byte(0x47)를 확인합니다.
+func isMPEGTS(head []byte) bool {
+	for _, layout := range []struct{ offset, size int }{{0, 188}, {4, 192}} {
+		if len(head) < layout.offset+2*layout.size+1 {
+			continue
+		}
+		ok := true
+		for i := 0; i < 3; i++ {
+			if head[layout.offset+i*layout.size] != 0x47 {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isText(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	for _, b := range head[:min(len(head), 512)] {
+		if b < 0x09 || (b > 0x0D && b < 0x20 && b != 0x1B) {
+			return false
+		}
+	}
+	return true
+}
+
+// Chunk는 데이터를 담은 업로드 청크입니다. (pb.VideoChunk)
+type Chunk interface {
+	GetData() []byte
+}
+
+// ReadHead는 recv로 청크를 받아 앞부분이 SniffSize 이상이 될 때까지 모읍니다.
+// 업로드가 그보다 짧으면 io.EOF까지 받은 청크를 반환하고 eof를 true로 설정합니다.
+func ReadHead[C Chunk](recv func() (C, error)) (chunks []C, head []byte, eof bool, err error) {
+	for len(head) < SniffSize {
+		chunk, err := recv()
+		if err == io.EOF {
+			return chunks, head, true, nil
+		}
+		if err != nil {
+			return chunks, head, false, err
+		}
+		chunks = append(chunks, chunk)
+		head = append(head, chunk.GetData()...)
+	}
+	return chunks, head, false, nil
+}
+
+// Replay는 ReadHead로 먼저 받은 청크를 다시 돌려준 뒤 recv로 이어서 받는 함수를 반환합니다.
+func Replay[C Chunk](pending []C, eof bool, recv func() (C, error)) func() (C, error) {
+	return func() (C, error) {
+		if len(pending) > 0 {
+			chunk := pending[0]
+			pending = pending[1:]
+			return chunk, nil
+		}
+		if eof {
+			var zero C
+			return zero, io.EOF
+		}
+		return recv()
+	}
+}
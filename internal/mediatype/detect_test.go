@@ -0,0 +1,67 @@
+package mediatype
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// mpegts는 packet 크기 size 간격으로 sync byte를 넣은 MPEG-TS 앞부분을 만듭니다.
+func mpegts(offset, size, packets int) []byte {
+	head := make([]byte, offset+size*packets)
+	for i := 0; i < packets; i++ {
+		head[offset+i*size] = 0x47
+	}
+	return head
+}
+
+func TestDetect(t *testing.T) {
+	pad := func(b []byte) []byte { return append(b, make([]byte, 64)...) }
+	tests := []struct {
+		name    string
+		head    []byte
+		want    Format
+		wantErr error
+	}{
+		{name: "mp4 ftyp", head: pad([]byte("\x00\x00\x00\x20ftypisom\x00\x00\x02\x00")), want: MP4},
+		{name: "quicktime ftyp", head: pad([]byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00")), want: MOV},
+		{name: "quicktime without ftyp", head: pad([]byte("\x00\x00\x00\x08wide\x00\x00\x00\x00mdat")), want: MOV},
+		{name: "matroska", head: pad([]byte("\x1a\x45\xdf\xa3\xa3\x42\x86\x81\x01\x42\x82\x88matroska")), want: MKV},
+		{name: "webm", head: pad([]byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm")), want: WebM},
+		{name: "mpeg-ts", head: mpegts(0, 188, 3), want: TS},
+		{name: "m2ts", head: mpegts(4, 192, 3), want: TS},
+		{name: "avi", head: pad([]byte("RIFF\x00\x00\x00\x00AVI LIST")), want: AVI},
+		{name: "flv", head: pad([]byte("FLV\x01\x05\x00\x00\x00\x09")), want: FLV},
+
+		{name: "too short for ISO box", head: []byte("\x00\x00\x00\x20ftyp"), wantErr: ErrUnknownFormat},
+		{name: "single ts sync byte", head: mpegts(0, 188, 1), wantErr: ErrUnknownFormat},
+		{name: "riff but not avi", head: pad([]byte("RIFF\x00\x00\x00\x00WAVEfmt ")), wantErr: ErrUnknownFormat},
+		{name: "flv with wrong version", head: pad([]byte("FLV\x02\x05")), wantErr: ErrUnknownFormat},
+		{name: "hls playlist", head: []byte("#EXTM3U\n#EXT-X-VERSION:3\n"), wantErr: ErrNotVideo},
+		{name: "html with bom", head: []byte("\xef\xbb\xbf\n<!DOCTYPE html><html>"), wantErr: ErrNotVideo},
+		{name: "json error", head: []byte(`{"error":"not found"}`), wantErr: ErrNotVideo},
+		{name: "plain text", head: []byte("404 page not found\n"), wantErr: ErrNotVideo},
+		{name: "random binary", head: pad([]byte{0x00, 0x01, 0x02, 0x03, 0xff, 0xfe}), wantErr: ErrUnknownFormat},
+		{name: "empty", head: nil, wantErr: ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.head)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Detect error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Detect = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectIgnoresWebMOutsideHeader(t *testing.T) {
+	// DocType은 EBML 헤더 앞부분에 있으므로 뒤쪽 데이터의 "webm"으로 판별하지 않음
+	head := append([]byte("\x1a\x45\xdf\xa3\xa3\x42\x86\x81\x01\x42\x82\x88matroska"), bytes.Repeat([]byte{0}, 64)...)
+	head = append(head, "webm"...)
+	if got, err := Detect(head); err != nil || got != MKV {
+		t.Fatalf("Detect = %+v, %v, want mkv", got, err)
+	}
+}
@@ -0,0 +1,15 @@
+package mediatype
+
+// LiveHeader는 업로드가 끝나지 않는 live stream임을 알리는 gRPC metadata 키입니다 (값 "true").
+// live 업로드는 최대 크기 제한을 받지 않고, internal 서버가 받는 즉시 HLS로 변환합니다.
+const LiveHeader = "x-live"
+
+// Streamable은 파일 끝까지 받지 않고 앞에서부터 읽으며 변환할 수 있는 형식인지 반환합니다.
+// MP4/MOV는 moov 위치에 따라, AVI는 index 때문에 live 입력으로 사용할 수 없습니다.
+func (f Format) Streamable() bool {
+	switch f.Name {
+	case TS.Name, FLV.Name, MKV.Name, WebM.Name:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package mediatype
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+)
+
+var (
+	ErrFormatNotAllowed      = errors.New("container format not allowed")
+	ErrContentTypeNotAllowed = errors.New("content type not allowed")
+	ErrTooLarge              = errors.New("upload exceeds maximum size")
+)
+
+// Policy는 업로드 허용 조건입니다.
+type Policy struct {
+	Formats      map[string]bool  // 허용 컨테이너, 비어 있으면 전체 허용
+	ContentTypes []string         // 허용 content_type prefix, 비어 있으면 전체 허용
+	MaxBytes     int64            // 0이면 제한 없음
+	FormatMax    map[string]int64 // 컨테이너별 최대 크기 (MaxBytes보다 우선)
+}
+
+// PolicyFromEnv는 환경변수에서 업로드 정책을 읽습니다.
+//
+//	UPLOAD_ALLOWED_FORMATS        허용 컨테이너 (예: "mp4,mov,webm", 기본 전체)
+//	UPLOAD_ALLOWED_CONTENT_TYPES  허용 content_type prefix (예: "video/,application/octet-stream")
+//	UPLOAD_MAX_BYTES              업로드 최대 크기
+//	UPLOAD_MAX_BYTES_<FORMAT>     컨테이너별 최대 크기 (예: UPLOAD_MAX_BYTES_TS)
+func PolicyFromEnv() Policy {
+	p := Policy{
+		Formats:   make(map[string]bool),
+		FormatMax: make(map[string]int64),
+		MaxBytes:  env.Int64("UPLOAD_MAX_BYTES", 0),
+	}
+	for _, name := range splitList(os.Getenv("UPLOAD_ALLOWED_FORMATS")) {
+		p.Formats[strings.ToLower(name)] = true
+	}
+	p.ContentTypes = splitList(strings.ToLower(os.Getenv("UPLOAD_ALLOWED_CONTENT_TYPES")))
+	for _, f := range Formats {
+		if n := env.Int64("UPLOAD_MAX_BYTES_"+strings.ToUpper(f.Name), 0); n > 0 {
+			p.FormatMax[f.Name] = n
+		}
+	}
+	return p
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Check는 판별된 형식과 클라이언트가 보낸 content_type이 허용되는지 확인합니다.
+func (p Policy) Check(format Format, contentType string) error {
+	if len(p.Formats) > 0 && !p.Formats[format.Name] {
+		return fmt.Errorf("%w: %s", ErrFormatNotAllowed, format.Name)
+	}
+	if len(p.ContentTypes) > 0 {
+		ct := strings.ToLower(contentType)
+		for _, prefix := range p.ContentTypes {
+			if strings.HasPrefix(ct, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %q", ErrContentTypeNotAllowed, contentType)
+	}
+	return nil
+}
+
+// CheckSize는 지금까지 받은 바이트가 format의 최대 크기를 넘는지 확인합니다.
+func (p Policy) CheckSize(format Format, size int64) error {
+	max := p.MaxBytes
+	if n, ok := p.FormatMax[format.Name]; ok {
+		max = n
+	}
+	if max > 0 && size > max {
+		return fmt.Errorf("%w (%s limit %d bytes)", ErrTooLarge, format.Name, max)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC 서버 RED(Rate, Errors, Duration) 메트릭
+var (
+	grpcStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_started_total",
+		Help: "Total number of RPCs started on the server.",
+	}, []string{"method"})
+
+	grpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of RPCs completed on the server, by status code.",
+	}, []string{"method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Duration of RPCs handled by the server.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600},
+	}, []string{"method"})
+
+	grpcInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpc_server_in_flight",
+		Help: "Number of RPCs currently being handled.",
+	}, []string{"method"})
+)
+
+// Handler는 /metrics 엔드포인트 핸들러를 반환합니다.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func observe(method string, started time.Time, err error) {
+	grpcHandled.WithLabelValues(method, status.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(method).Observe(time.Since(started).Seconds())
+	grpcInFlight.WithLabelValues(method).Dec()
+}
+
+// UnaryServerInterceptor는 unary RPC의 RED 메트릭을 기록합니다.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		started := time.Now()
+		grpcStarted.WithLabelValues(info.FullMethod).Inc()
+		grpcInFlight.WithLabelValues(info.FullMethod).Inc()
+
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, started, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor는 streaming RPC의 RED 메트릭을 기록합니다.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+		grpcStarted.WithLabelValues(info.FullMethod).Inc()
+		grpcInFlight.WithLabelValues(info.FullMethod).Inc()
+
+		err := handler(srv, ss)
+		observe(info.FullMethod, started, err)
+		return err
+	}
+}
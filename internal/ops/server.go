@@ -0,0 +1,75 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// Server는 k8s probe 등 운영용 HTTP 엔드포인트를 제공합니다.
+//
+//	/healthz  프로세스가 살아있으면 200 (liveness)
+//	/readyz   트래픽을 받을 준비가 되어 있으면 200, 아니면 503 (readiness)
+type Server struct {
+	mux   *http.ServeMux
+	srv   *http.Server
+	ready atomic.Bool
+}
+
+// New는 addr에서 동작할 운영 서버를 만듭니다. 처음에는 not-ready 상태입니다.
+func New(addr string) *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.srv = &http.Server{Addr: addr, Handler: s.mux}
+
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	s.mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	return s
+}
+
+// Handle은 추가 엔드포인트를 등록합니다.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetReady는 readiness 상태를 변경합니다.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Ready는 현재 readiness 상태를 반환합니다.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// Start는 백그라운드에서 HTTP 서버를 실행합니다. addr이 비어 있으면 아무것도 하지 않습니다.
+func (s *Server) Start() {
+	if s.srv.Addr == "" {
+		return
+	}
+	go func() {
+		slog.Info("ops server listening", "addr", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("ops server failed", "error", err)
+		}
+	}()
+}
+
+// Shutdown은 HTTP 서버를 종료합니다.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.srv.Addr == "" {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,248 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoVideo는 디코딩할 수 있는 영상 스트림이 없을 때 반환됩니다.
+var ErrNoVideo = errors.New("no decodable video stream")
+
+// Metadata는 원본 파일의 ffprobe 결과를 정리한 것입니다.
+type Metadata struct {
+	Container    string        `json:"container"` // ffprobe format_name (예: mov,mp4,m4a,3gp,3g2,mj2)
+	Duration     float64       `json:"duration_seconds"`
+	Size         int64         `json:"size_bytes"`
+	Bitrate      int64         `json:"bitrate"`
+	CreationTime *time.Time    `json:"creation_time,omitempty"`
+	Video        []VideoStream `json:"video"`
+	Audio        []AudioStream `json:"audio"`
+}
+
+// VideoStream은 영상 스트림 정보입니다.
+type VideoStream struct {
+	Index       int     `json:"index"`
+	Codec       string  `json:"codec"`
+	Profile     string  `json:"profile,omitempty"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	FrameRate   float64 `json:"frame_rate"`
+	Rotation    int     `json:"rotation"` // 시계 방향 각도 (0, 90, 180, 270)
+	PixelFormat string  `json:"pixel_format,omitempty"`
+	Bitrate     int64   `json:"bitrate,omitempty"`
+	ColorSpace  string  `json:"color_space,omitempty"`
+	Transfer    string  `json:"color_transfer,omitempty"`
+	Primaries   string  `json:"color_primaries,omitempty"`
+	HDR         string  `json:"hdr,omitempty"` // HDR10(PQ), HLG, 또는 비어 있으면 SDR
+}
+
+// AudioStream은 음성 스트림 정보입니다.
+type AudioStream struct {
+	Index         int    `json:"index"`
+	Codec         string `json:"codec"`
+	Profile       string `json:"profile,omitempty"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	SampleRate    int    `json:"sample_rate"`
+	Bitrate       int64  `json:"bitrate,omitempty"`
+	Language      string `json:"language,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Default       bool   `json:"default"`
+}
+
+// PrimaryVideo는 첫 번째 영상 스트림을 반환합니다. Probe가 성공했으면 항상 있습니다.
+func (m *Metadata) PrimaryVideo() VideoStream {
+	if len(m.Video) == 0 {
+		return VideoStream{}
+	}
+	return m.Video[0]
+}
+
+// DisplaySize는 회전을 반영한 화면 크기를 반환합니다.
+func (v VideoStream) DisplaySize() (width, height int) {
+	if v.Rotation == 90 || v.Rotation == 270 {
+		return v.Height, v.Width
+	}
+	return v.Width, v.Height
+}
+
+// ffprobe -show_format -show_streams -of json 출력 형식
+type ffprobeOutput struct {
+	Streams []struct {
+		Index          int               `json:"index"`
+		CodecType      string            `json:"codec_type"`
+		CodecName      string            `json:"codec_name"`
+		Profile        string            `json:"profile"`
+		Width          int               `json:"width"`
+		Height         int               `json:"height"`
+		PixFmt         string            `json:"pix_fmt"`
+		AvgFrameRate   string            `json:"avg_frame_rate"`
+		RFrameRate     string            `json:"r_frame_rate"`
+		BitRate        string            `json:"bit_rate"`
+		ColorSpace     string            `json:"color_space"`
+		ColorTransfer  string            `json:"color_transfer"`
+		ColorPrimaries string            `json:"color_primaries"`
+		Channels       int               `json:"channels"`
+		ChannelLayout  string            `json:"channel_layout"`
+		SampleRate     string            `json:"sample_rate"`
+		Tags           map[string]string `json:"tags"`
+		Disposition    map[string]int    `json:"disposition"`
+		SideDataList   []sideData        `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		Size       string            `json:"size"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+type sideData struct {
+	Rotation *float64 `json:"rotation"`
+}
+
+// Probe는 ffprobe로 path를 분석합니다. 영상 스트림이 없으면 ErrNoVideo를 반환합니다.
+func Probe(ctx context.Context, path string) (*Metadata, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error",
+		"-show_format", "-show_streams", "-of", "json", path)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("ffprobe failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Parse(out)
+}
+
+// Parse는 ffprobe JSON 출력을 Metadata로 변환합니다.
+func Parse(raw []byte) (*Metadata, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %v", err)
+	}
+
+	m := &Metadata{
+		Container: out.Format.FormatName,
+		Duration:  parseFloat(out.Format.Duration),
+		Size:      parseInt(out.Format.Size),
+		Bitrate:   parseInt(out.Format.BitRate),
+		Video:     []VideoStream{},
+		Audio:     []AudioStream{},
+	}
+	if t, err := time.Parse(time.RFC3339Nano, out.Format.Tags["creation_time"]); err == nil {
+		m.CreationTime = &t
+	}
+
+	for _, st := range out.Streams {
+		switch st.CodecType {
+		case "video":
+			// 앨범 아트 등 정지 이미지는 영상 스트림으로 보지 않음
+			if st.Disposition["attached_pic"] == 1 || st.CodecName == "" || st.Width == 0 || st.Height == 0 {
+				continue
+			}
+			v := VideoStream{
+				Index:       st.Index,
+				Codec:       st.CodecName,
+				Profile:     st.Profile,
+				Width:       st.Width,
+				Height:      st.Height,
+				FrameRate:   parseRate(st.AvgFrameRate),
+				PixelFormat: st.PixFmt,
+				Bitrate:     parseInt(st.BitRate),
+				ColorSpace:  st.ColorSpace,
+				Transfer:    st.ColorTransfer,
+				Primaries:   st.ColorPrimaries,
+			}
+			if v.FrameRate == 0 {
+				v.FrameRate = parseRate(st.RFrameRate)
+			}
+			v.Rotation = rotation(st.Tags["rotate"], st.SideDataList)
+			v.HDR = hdrFormat(st.ColorTransfer)
+			m.Video = append(m.Video, v)
+		case "audio":
+			if st.CodecName == "" {
+				continue
+			}
+			m.Audio = append(m.Audio, AudioStream{
+				Index:         st.Index,
+				Codec:         st.CodecName,
+				Profile:       st.Profile,
+				Channels:      st.Channels,
+				ChannelLayout: st.ChannelLayout,
+				SampleRate:    int(parseInt(st.SampleRate)),
+				Bitrate:       parseInt(st.BitRate),
+				Language:      st.Tags["language"],
+				Title:         st.Tags["title"],
+				Default:       st.Disposition["default"] == 1,
+			})
+		}
+	}
+
+	if len(m.Video) == 0 {
+		return m, ErrNoVideo
+	}
+	return m, nil
+}
+
+// rotation은 rotate 태그(구버전) 또는 display matrix side data에서 시계 방향 회전 각도를 구합니다.
+func rotation(tag string, sideData []sideData) int {
+	deg := 0.0
+	if tag != "" {
+		deg = parseFloat(tag)
+	}
+	for _, sd := range sideData {
+		if sd.Rotation != nil {
+			// display matrix의 rotation은 반시계 방향
+			deg = -*sd.Rotation
+		}
+	}
+	r := int(math.Round(deg)) % 360
+	if r < 0 {
+		r += 360
+	}
+	return r
+}
+
+func hdrFormat(transfer string) string {
+	switch transfer {
+	case "smpte2084":
+		return "HDR10"
+	case "arib-std-b67":
+		return "HLG"
+	}
+	return ""
+}
+
+// parseRate는 "30000/1001" 형식의 frame rate를 계산합니다.
+func parseRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return parseFloat(s)
+	}
+	d := parseFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return math.Round(parseFloat(num)/d*1000) / 1000
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,454 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Policy는 백엔드 선택 방식입니다.
+type Policy string
+
+const (
+	RoundRobin  Policy = "round_robin"
+	LeastActive Policy = "least_active"
+)
+
+// ErrNoHealthyBackend는 사용 가능한 internal 백엔드가 없을 때 반환됩니다.
+var ErrNoHealthyBackend = errors.New("no healthy internal backend available")
+
+// Config는 internal 백엔드 풀 설정입니다.
+type Config struct {
+	// Discovery가 "dns"이면 Host를 주기적으로 조회하여 모든 A 레코드를 백엔드로 사용합니다.
+	// (k8s headless service) 그 외에는 Targets 목록을 고정으로 사용합니다.
+	Discovery string
+	Host      string
+	Port      string
+	Targets   []string
+
+	Policy             Policy
+	ResolveInterval    time.Duration
+	HealthInterval     time.Duration
+	HealthTimeout      time.Duration
+	UnhealthyThreshold int
+
+	DialOptions []grpc.DialOption
+}
+
+// ConfigFromEnv는 환경변수에서 풀 설정을 읽습니다.
+//
+//	INTERNAL_DISCOVERY     "dns" 또는 "static" (기본값)
+//	INTERNAL_HOST/PORT     dns 모드의 조회 대상, static 모드의 기본 백엔드
+//	INTERNAL_BACKENDS      static 모드의 백엔드 목록 (host:port,host:port)
+//	LB_POLICY              "round_robin" (기본값) 또는 "least_active"
+//	HEALTH_CHECK_INTERVAL  헬스체크 주기 (기본 5s)
+//	DNS_RESOLVE_INTERVAL   DNS 재조회 주기 (기본 15s)
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Discovery:          os.Getenv("INTERNAL_DISCOVERY"),
+		Host:               os.Getenv("INTERNAL_HOST"),
+		Port:               os.Getenv("INTERNAL_PORT"),
+		Policy:             Policy(os.Getenv("LB_POLICY")),
+		ResolveInterval:    env.Duration("DNS_RESOLVE_INTERVAL", 15*time.Second),
+		HealthInterval:     env.Duration("HEALTH_CHECK_INTERVAL", 5*time.Second),
+		HealthTimeout:      env.Duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		UnhealthyThreshold: 2,
+	}
+	if cfg.Discovery == "" {
+		cfg.Discovery = "static"
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = RoundRobin
+	}
+	if v := os.Getenv("UNHEALTHY_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.UnhealthyThreshold = n
+		}
+	}
+	if v := os.Getenv("INTERNAL_BACKENDS"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				cfg.Targets = append(cfg.Targets, t)
+			}
+		}
+	}
+	if len(cfg.Targets) == 0 {
+		cfg.Targets = []string{net.JoinHostPort(cfg.Host, cfg.Port)}
+	}
+	return cfg
+}
+
+// Backend는 하나의 internal 서버 인스턴스와의 연결입니다.
+type Backend struct {
+	Addr   string
+	Client pb.VideoStreamingServiceClient
+
+	conn     *grpc.ClientConn
+	active   atomic.Int64
+	mu       sync.Mutex
+	healthy  bool
+	failures int
+	removed  bool
+}
+
+// Status는 백엔드 상태 스냅샷입니다.
+type Status struct {
+	Addr    string
+	Healthy bool
+	Active  int64
+}
+
+// Pool은 여러 internal 백엔드에 스트림을 분산합니다.
+type Pool struct {
+	cfg Config
+
+	mu       sync.Mutex
+	backends map[string]*Backend
+	order    []string // round robin 순서
+	next     int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPool(cfg Config) (*Pool, error) {
+	if cfg.Policy != RoundRobin && cfg.Policy != LeastActive {
+		return nil, fmt.Errorf("unknown load balancing policy: %q", cfg.Policy)
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = 1
+	}
+
+	p := &Pool{
+		cfg:      cfg,
+		backends: make(map[string]*Backend),
+	}
+
+	addrs, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.update(addrs); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Start는 DNS 재조회와 헬스체크 루프를 시작합니다.
+func (p *Pool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.checkAll(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.cfg.HealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkAll(ctx)
+			}
+		}
+	}()
+
+	if p.cfg.Discovery == "dns" {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ticker := time.NewTicker(p.cfg.ResolveInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					addrs, err := p.discover()
+					if err != nil {
+						slog.Warn("backend discovery failed", "error", err)
+						continue
+					}
+					if err := p.update(addrs); err != nil {
+						slog.Warn("backend update failed", "error", err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Close는 루프를 멈추고 모든 연결을 닫습니다.
+func (p *Pool) Close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, b := range p.backends {
+		b.conn.Close()
+		delete(p.backends, addr)
+	}
+	p.order = nil
+}
+
+func (p *Pool) discover() ([]string, error) {
+	if p.cfg.Discovery != "dns" {
+		return p.cfg.Targets, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupHost(ctx, p.cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", p.cfg.Host, err)
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, p.cfg.Port))
+	}
+	return addrs, nil
+}
+
+// update는 새 주소 목록을 반영합니다. 사라진 백엔드는 진행 중인 스트림이 끝난 후 닫힙니다.
+func (p *Pool) update(addrs []string) error {
+	sort.Strings(addrs)
+	wanted := make(map[string]bool, len(addrs))
+	for _, a := range addrs {
+		wanted[a] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, addr := range addrs {
+		if _, ok := p.backends[addr]; ok {
+			continue
+		}
+		conn, err := grpc.Dial(addr, p.cfg.DialOptions...)
+		if err != nil {
+			return fmt.Errorf("failed to dial backend %s: %w", addr, err)
+		}
+		p.backends[addr] = &Backend{
+			Addr:    addr,
+			Client:  pb.NewVideoStreamingServiceClient(conn),
+			conn:    conn,
+			healthy: true, // 첫 헬스체크 전까지는 사용 가능으로 간주
+		}
+		slog.Info("added internal backend", "backend", addr)
+	}
+
+	for addr, b := range p.backends {
+		if wanted[addr] {
+			continue
+		}
+		delete(p.backends, addr)
+		b.mu.Lock()
+		b.removed = true
+		b.mu.Unlock()
+		if b.active.Load() == 0 {
+			b.conn.Close()
+		}
+		slog.Info("removed internal backend", "backend", addr)
+	}
+
+	p.order = p.order[:0]
+	for addr := range p.backends {
+		p.order = append(p.order, addr)
+	}
+	sort.Strings(p.order)
+	return nil
+}
+
+// Pick은 정책에 따라 건강한 백엔드 하나를 고릅니다.
+// 반환된 release 함수는 스트림이 끝나면 반드시 호출해야 합니다.
+func (p *Pool) Pick(exclude map[string]bool) (*Backend, func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var picked *Backend
+	n := len(p.order)
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		b := p.backends[p.order[idx]]
+		if exclude[b.Addr] || !b.Healthy() {
+			continue
+		}
+		if p.cfg.Policy == RoundRobin {
+			picked = b
+			p.next = idx + 1
+			break
+		}
+		if picked == nil || b.active.Load() < picked.active.Load() {
+			picked = b
+		}
+	}
+	if p.cfg.Policy == LeastActive && n > 0 {
+		// 동률일 때 같은 백엔드에 몰리지 않도록 시작 위치를 회전
+		p.next = (p.next + 1) % n
+	}
+	if picked == nil {
+		return nil, nil, ErrNoHealthyBackend
+	}
+
+	picked.active.Add(1)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if picked.active.Add(-1) == 0 {
+				picked.mu.Lock()
+				removed := picked.removed
+				picked.mu.Unlock()
+				if removed {
+					picked.conn.Close()
+				}
+			}
+		})
+	}
+	return picked, release, nil
+}
+
+// Healthy는 백엔드가 현재 요청을 받을 수 있는지 반환합니다.
+func (b *Backend) Healthy() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.healthy && !b.removed
+}
+
+// ReportFailure는 스트림 도중 발생한 연결 오류를 반영합니다.
+// 임계치를 넘으면 다음 헬스체크 성공 전까지 선택 대상에서 제외됩니다.
+func (p *Pool) ReportFailure(b *Backend, err error) {
+	p.markResult(b, err)
+}
+
+func (p *Pool) markResult(b *Backend, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if !b.healthy {
+			slog.Info("internal backend is healthy again", "backend", b.Addr)
+		}
+		b.healthy = true
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.healthy && b.failures >= p.cfg.UnhealthyThreshold {
+		b.healthy = false
+		slog.Warn("evicting unhealthy internal backend", "backend", b.Addr, "error", err)
+	}
+}
+
+func (p *Pool) checkAll(ctx context.Context) {
+	p.mu.Lock()
+	backends := make([]*Backend, 0, len(p.backends))
+	for _, b := range p.backends {
+		backends = append(backends, b)
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, b := range backends {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			p.markResult(b, p.probe(ctx, b))
+		}(b)
+	}
+	wg.Wait()
+}
+
+// probe는 백엔드의 grpc.health.v1 상태를 확인합니다.
+// internal 서버는 ffmpeg이나 디스크를 사용할 수 없거나 작업 큐가 멈춰 있으면 NOT_SERVING을 반환합니다.
+// health 서비스가 없는 백엔드는 연결이 Ready 상태인지로 판단합니다.
+func (p *Pool) probe(ctx context.Context, b *Backend) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.HealthTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(b.conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: pb.VideoStreamingService_ServiceDesc.ServiceName,
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return p.probeConn(ctx, b)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health status %s", resp.Status)
+	}
+	return nil
+}
+
+// probeConn은 백엔드 연결이 Ready 상태가 되는지 확인합니다.
+func (p *Pool) probeConn(ctx context.Context, b *Backend) error {
+	b.conn.Connect()
+	for {
+		state := b.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return fmt.Errorf("connection is shut down")
+		}
+		if !b.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection not ready (state %s)", state)
+		}
+	}
+}
+
+// HealthyCount는 현재 선택 가능한 백엔드 수를 반환합니다.
+func (p *Pool) HealthyCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	count := 0
+	for _, b := range p.backends {
+		if b.Healthy() {
+			count++
+		}
+	}
+	return count
+}
+
+// Statuses는 모든 백엔드의 상태를 반환합니다.
+func (p *Pool) Statuses() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]Status, 0, len(p.order))
+	for _, addr := range p.order {
+		b := p.backends[addr]
+		statuses = append(statuses, Status{
+			Addr:    b.Addr,
+			Healthy: b.Healthy(),
+			Active:  b.active.Load(),
+		})
+	}
+	return statuses
+}
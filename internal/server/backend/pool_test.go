@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/ket0825/grpc-streaming/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var service = pb.VideoStreamingService_ServiceDesc.ServiceName
+
+func newTestPool(t *testing.T, threshold int, targets ...string) *Pool {
+	t.Helper()
+	p, err := NewPool(Config{
+		Discovery:          "static",
+		Targets:            targets,
+		Policy:             RoundRobin,
+		HealthTimeout:      time.Second,
+		UnhealthyThreshold: threshold,
+		DialOptions:        []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestUnhealthyThreshold(t *testing.T) {
+	errStream := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		threshold int
+		results   []error // 순서대로 반영할 결과 (nil: 성공)
+		want      []bool  // 각 결과를 반영한 후 Healthy
+	}{
+		{name: "evict on first failure", threshold: 1, results: []error{errStream}, want: []bool{false}},
+		{name: "evict after threshold", threshold: 3, results: []error{errStream, errStream, errStream}, want: []bool{true, true, false}},
+		{name: "success resets failures", threshold: 2, results: []error{errStream, nil, errStream, errStream}, want: []bool{true, true, true, false}},
+		{name: "recover after success", threshold: 2, results: []error{errStream, errStream, nil}, want: []bool{true, false, true}},
+		{name: "zero threshold means one", threshold: 0, results: []error{errStream}, want: []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPool(t, tt.threshold, "127.0.0.1:1")
+			b := p.backends["127.0.0.1:1"]
+			for i, err := range tt.results {
+				if err != nil {
+					p.ReportFailure(b, err)
+				} else {
+					p.markResult(b, nil)
+				}
+				if got := b.Healthy(); got != tt.want[i] {
+					t.Fatalf("after result %d (%v): Healthy = %t, want %t", i, err, got, tt.want[i])
+				}
+			}
+			if tt.want[len(tt.want)-1] {
+				return
+			}
+			if _, _, err := p.Pick(nil); !errors.Is(err, ErrNoHealthyBackend) {
+				t.Fatalf("Pick with evicted backend = %v, want ErrNoHealthyBackend", err)
+			}
+		})
+	}
+}
+
+// startBackend는 grpc.health.v1 서비스만 있는 백엔드를 띄웁니다. hs가 nil이면 health 서비스 없이 띄웁니다.
+func startBackend(t *testing.T, hs *health.Server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	if hs != nil {
+		healthpb.RegisterHealthServer(s, hs)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestProbeEviction(t *testing.T) {
+	serving, notServing := healthpb.HealthCheckResponse_SERVING, healthpb.HealthCheckResponse_NOT_SERVING
+	tests := []struct {
+		name     string
+		statuses []healthpb.HealthCheckResponse_ServingStatus // 헬스체크마다 백엔드가 보고할 상태
+		want     []bool
+	}{
+		{name: "serving", statuses: []healthpb.HealthCheckResponse_ServingStatus{serving, serving}, want: []bool{true, true}},
+		{name: "evicted after threshold", statuses: []healthpb.HealthCheckResponse_ServingStatus{notServing, notServing}, want: []bool{true, false}},
+		{name: "flapping stays healthy", statuses: []healthpb.HealthCheckResponse_ServingStatus{notServing, serving, notServing}, want: []bool{true, true, true}},
+		{name: "back after eviction", statuses: []healthpb.HealthCheckResponse_ServingStatus{notServing, notServing, serving}, want: []bool{true, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := health.NewServer()
+			p := newTestPool(t, 2, startBackend(t, hs))
+			for i, st := range tt.statuses {
+				hs.SetServingStatus(service, st)
+				p.checkAll(context.Background())
+				if got := p.HealthyCount() == 1; got != tt.want[i] {
+					t.Fatalf("after check %d (%s): healthy = %t, want %t", i, st, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProbeWithoutHealthService(t *testing.T) {
+	p := newTestPool(t, 1, startBackend(t, nil))
+	p.checkAll(context.Background())
+	if p.HealthyCount() != 1 {
+		t.Fatalf("backend without health service should be healthy once connected: %+v", p.Statuses())
+	}
+}
+
+func TestPickSkipsEvicted(t *testing.T) {
+	healthyHS, failingHS := health.NewServer(), health.NewServer()
+	healthyAddr := startBackend(t, healthyHS)
+	p := newTestPool(t, 1, healthyAddr, startBackend(t, failingHS))
+	failingHS.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthyHS.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	p.checkAll(context.Background())
+
+	for i := 0; i < 4; i++ {
+		b, release, err := p.Pick(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		if b.Addr != healthyAddr {
+			t.Fatalf("Pick returned evicted backend %s", b.Addr)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+)
+
+var (
+	ErrConcurrentStreams = errors.New("too many concurrent streams")
+	ErrRateLimited       = errors.New("too many requests per minute")
+	ErrDailyBytes        = errors.New("daily upload quota exceeded")
+	ErrUploadTooLarge    = errors.New("upload exceeds maximum size")
+)
+
+// Limits는 tenant별 제한입니다. 0이면 제한하지 않습니다.
+type Limits struct {
+	MaxConcurrentStreams int   `json:"max_concurrent_streams"`
+	MaxBytesPerDay       int64 `json:"max_bytes_per_day"`
+	MaxUploadBytes       int64 `json:"max_upload_bytes"`
+	RequestsPerMinute    int   `json:"requests_per_minute"`
+}
+
+// Config는 quota 설정입니다.
+type Config struct {
+	Default       Limits            // Tenants에 없는 tenant에 적용
+	Tenants       map[string]Limits // tenant별 제한
+	StateFile     string            // 사용량을 저장할 파일, 비어 있으면 메모리에만 유지
+	FlushInterval time.Duration
+}
+
+// ConfigFromEnv는 환경변수에서 quota 설정을 읽습니다.
+//
+//	QUOTA_MAX_CONCURRENT_STREAMS  tenant별 동시 업로드 수
+//	QUOTA_MAX_BYTES_PER_DAY       tenant별 하루(UTC) 업로드 바이트
+//	QUOTA_MAX_UPLOAD_BYTES        업로드 1건의 최대 바이트
+//	QUOTA_REQUESTS_PER_MINUTE     tenant별 분당 업로드 요청 수
+//	QUOTA_FILE                    tenant별 제한 JSON ({"tenant": {"max_concurrent_streams": 2, ...}})
+//	QUOTA_STATE_FILE              사용량 저장 파일 (재시작 후에도 하루 사용량 유지)
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Default: Limits{
+			MaxConcurrentStreams: env.Int("QUOTA_MAX_CONCURRENT_STREAMS", 0),
+			MaxBytesPerDay:       env.Int64("QUOTA_MAX_BYTES_PER_DAY", 0),
+			MaxUploadBytes:       env.Int64("QUOTA_MAX_UPLOAD_BYTES", 0),
+			RequestsPerMinute:    env.Int("QUOTA_REQUESTS_PER_MINUTE", 0),
+		},
+		Tenants:       make(map[string]Limits),
+		StateFile:     os.Getenv("QUOTA_STATE_FILE"),
+		FlushInterval: 10 * time.Second,
+	}
+	if path := os.Getenv("QUOTA_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read QUOTA_FILE: %v", err)
+		}
+		if err := json.Unmarshal(raw, &cfg.Tenants); err != nil {
+			return cfg, fmt.Errorf("invalid QUOTA_FILE: %v", err)
+		}
+	}
+	return cfg, nil
+}
+
+// Usage는 tenant의 사용량입니다.
+type Usage struct {
+	Tenant             string `json:"tenant"`
+	ActiveStreams      int    `json:"-"`
+	Day                string `json:"day"` // BytesToday의 기준 날짜 (UTC, 2006-01-02)
+	BytesToday         int64  `json:"bytes_today"`
+	RequestsLastMinute int    `json:"-"`
+	TotalBytes         int64  `json:"total_bytes"`
+	TotalUploads       int64  `json:"total_uploads"`
+	Rejected           int64  `json:"rejected"`
+	Limits             Limits `json:"-"`
+}
+
+type tenantState struct {
+	usage       Usage
+	minute      time.Time // 분당 요청 수 집계 구간 시작
+	minuteCount int
+}
+
+// Manager는 tenant별 사용량을 집계하고 제한을 적용합니다.
+type Manager struct {
+	cfg Config
+
+	mu      sync.Mutex
+	tenants map[string]*tenantState
+	dirty   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New는 Manager를 만들고 StateFile이 있으면 이전 사용량을 불러옵니다.
+func New(cfg Config) (*Manager, error) {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	m := &Manager{
+		cfg:     cfg,
+		tenants: make(map[string]*tenantState),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Limits는 tenant에 적용되는 제한을 반환합니다.
+func (m *Manager) Limits(tenant string) Limits {
+	if l, ok := m.cfg.Tenants[tenant]; ok {
+		return l
+	}
+	return m.cfg.Default
+}
+
+// state는 m.mu를 잡은 상태에서 호출됩니다. 날짜가 바뀌었으면 하루 사용량을 초기화합니다.
+func (m *Manager) state(tenant string, now time.Time) *tenantState {
+	st, ok := m.tenants[tenant]
+	if !ok {
+		st = &tenantState{usage: Usage{Tenant: tenant}}
+		m.tenants[tenant] = st
+	}
+	if day := now.UTC().Format("2006-01-02"); st.usage.Day != day {
+		st.usage.Day = day
+		st.usage.BytesToday = 0
+	}
+	if now.Sub(st.minute) >= time.Minute {
+		st.minute = now.Truncate(time.Minute)
+		st.minuteCount = 0
+	}
+	return st
+}
+
+// Acquire는 tenant의 새 업로드를 허용할지 확인하고, 허용되면 사용량을 기록할 Lease를 반환합니다.
+func (m *Manager) Acquire(tenant string) (*Lease, error) {
+	limits := m.Limits(tenant)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.state(tenant, now)
+
+	var err error
+	switch {
+	case limits.RequestsPerMinute > 0 && st.minuteCount >= limits.RequestsPerMinute:
+		err = fmt.Errorf("%w (limit %d)", ErrRateLimited, limits.RequestsPerMinute)
+	case limits.MaxConcurrentStreams > 0 && st.usage.ActiveStreams >= limits.MaxConcurrentStreams:
+		err = fmt.Errorf("%w (limit %d)", ErrConcurrentStreams, limits.MaxConcurrentStreams)
+	case limits.MaxBytesPerDay > 0 && st.usage.BytesToday >= limits.MaxBytesPerDay:
+		err = fmt.Errorf("%w (limit %d bytes)", ErrDailyBytes, limits.MaxBytesPerDay)
+	}
+	// 거절된 요청도 분당 요청 수에 포함하여 재시도 폭주를 막음
+	st.minuteCount++
+	if err != nil {
+		st.usage.Rejected++
+		m.dirty = true
+		return nil, err
+	}
+
+	st.usage.ActiveStreams++
+	st.usage.TotalUploads++
+	m.dirty = true
+	return &Lease{m: m, tenant: tenant, limits: limits}, nil
+}
+
+// Lease는 진행 중인 업로드 1건의 사용량을 기록합니다.
+type Lease struct {
+	m        *Manager
+	tenant   string
+	limits   Limits
+	bytes    int64
+	released bool
+}
+
+// Add는 n 바이트를 사용량에 더합니다. 업로드 최대 크기나 하루 사용량을 넘으면 오류를 반환합니다.
+func (l *Lease) Add(n int) error {
+	l.bytes += int64(n)
+	if l.limits.MaxUploadBytes > 0 && l.bytes > l.limits.MaxUploadBytes {
+		return fmt.Errorf("%w (limit %d bytes)", ErrUploadTooLarge, l.limits.MaxUploadBytes)
+	}
+
+	m := l.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.state(l.tenant, time.Now())
+	st.usage.BytesToday += int64(n)
+	st.usage.TotalBytes += int64(n)
+	m.dirty = true
+	if l.limits.MaxBytesPerDay > 0 && st.usage.BytesToday > l.limits.MaxBytesPerDay {
+		return fmt.Errorf("%w (limit %d bytes)", ErrDailyBytes, l.limits.MaxBytesPerDay)
+	}
+	return nil
+}
+
+// Release는 업로드 종료 시 호출합니다. 여러 번 호출해도 안전합니다.
+func (l *Lease) Release() {
+	if l.released {
+		return
+	}
+	l.released = true
+	m := l.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state(l.tenant, time.Now()).usage.ActiveStreams--
+	m.dirty = true
+}
+
+// Usage는 tenant의 사용량을 반환합니다. tenant가 비어 있으면 전체를 이름순으로 반환합니다.
+func (m *Manager) Usage(tenant string) []Usage {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	if tenant != "" {
+		names = []string{tenant}
+	} else {
+		for name := range m.tenants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	usages := make([]Usage, 0, len(names))
+	for _, name := range names {
+		st := m.state(name, now)
+		u := st.usage
+		u.RequestsLastMinute = st.minuteCount
+		u.Limits = m.Limits(name)
+		usages = append(usages, u)
+	}
+	return usages
+}
+
+// Start는 사용량을 주기적으로 StateFile에 저장합니다.
+func (m *Manager) Start() {
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				if err := m.flush(); err != nil {
+					slog.Warn("failed to persist quota usage", "path", m.cfg.StateFile, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop은 저장 루프를 멈추고 마지막 사용량을 저장합니다.
+func (m *Manager) Stop() {
+	close(m.stop)
+	<-m.done
+	if err := m.flush(); err != nil {
+		slog.Warn("failed to persist quota usage", "path", m.cfg.StateFile, "error", err)
+	}
+}
+
+func (m *Manager) load() error {
+	if m.cfg.StateFile == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(m.cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read quota state: %v", err)
+	}
+	var usages []Usage
+	if err := json.Unmarshal(raw, &usages); err != nil {
+		return fmt.Errorf("invalid quota state %s: %v", m.cfg.StateFile, err)
+	}
+	for _, u := range usages {
+		m.tenants[u.Tenant] = &tenantState{usage: u}
+	}
+	slog.Info("loaded quota usage", "path", m.cfg.StateFile, "tenants", len(usages))
+	return nil
+}
+
+// flush는 변경된 사용량을 임시 파일에 쓴 뒤 rename하여 원자적으로 저장합니다.
+func (m *Manager) flush() error {
+	if m.cfg.StateFile == "" {
+		return nil
+	}
+	m.mu.Lock()
+	if !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	usages := make([]Usage, 0, len(m.tenants))
+	for _, st := range m.tenants {
+		usages = append(usages, st.usage)
+	}
+	m.dirty = false
+	m.mu.Unlock()
+
+	if err := writeState(m.cfg.StateFile, usages); err != nil {
+		// 다음 주기에 다시 시도
+		m.mu.Lock()
+		m.dirty = true
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func writeState(path string, usages []Usage) error {
+	raw, err := json.MarshalIndent(usages, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Enabled는 제한이나 저장 설정이 하나라도 있는지 반환합니다.
+func (c Config) Enabled() bool {
+	return c.Default != (Limits{}) || len(c.Tenants) > 0 || c.StateFile != ""
+}
@@ -0,0 +1,153 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, limits Limits) *Manager {
+	t.Helper()
+	m, err := New(Config{Default: limits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDayRollover(t *testing.T) {
+	day := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		next      time.Time
+		wantBytes int64
+		wantDay   string
+	}{
+		{name: "same day", next: day.Add(30 * time.Second), wantBytes: 100, wantDay: "2026-03-01"},
+		{name: "next day (UTC)", next: day.Add(2 * time.Minute), wantBytes: 0, wantDay: "2026-03-02"},
+		// 하루는 UTC 기준이므로 다른 시간대의 날짜가 바뀌어도 유지
+		{name: "local midnight before UTC midnight", next: day.Add(30 * time.Second).In(time.FixedZone("KST", 9*60*60)), wantBytes: 100, wantDay: "2026-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, Limits{})
+			st := m.state("acme", day)
+			st.usage.BytesToday = 100
+			st.usage.TotalBytes = 100
+
+			st = m.state("acme", tt.next)
+			if st.usage.Day != tt.wantDay || st.usage.BytesToday != tt.wantBytes {
+				t.Fatalf("day %s, bytes today %d, want %s, %d", st.usage.Day, st.usage.BytesToday, tt.wantDay, tt.wantBytes)
+			}
+			if st.usage.TotalBytes != 100 {
+				t.Fatalf("total bytes = %d, should not reset on rollover", st.usage.TotalBytes)
+			}
+		})
+	}
+}
+
+func TestMinuteWindow(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 10, 0, time.UTC)
+	tests := []struct {
+		name      string
+		next      time.Time
+		wantCount int
+	}{
+		{name: "same minute", next: start.Add(40 * time.Second), wantCount: 3},
+		// 구간은 분 단위로 정렬되므로 12:00:10에 시작한 구간은 12:01:00에 끝남
+		{name: "next minute", next: start.Add(50 * time.Second), wantCount: 0},
+		{name: "long idle", next: start.Add(time.Hour), wantCount: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, Limits{})
+			st := m.state("acme", start)
+			if !st.minute.Equal(start.Truncate(time.Minute)) {
+				t.Fatalf("window starts at %v, want %v", st.minute, start.Truncate(time.Minute))
+			}
+			st.minuteCount = 3
+
+			if st = m.state("acme", tt.next); st.minuteCount != tt.wantCount {
+				t.Fatalf("requests in window = %d, want %d", st.minuteCount, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestAcquireLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		setup   func(st *tenantState)
+		wantErr error
+	}{
+		{name: "no limits", limits: Limits{}},
+		{name: "rate limited", limits: Limits{RequestsPerMinute: 2}, setup: func(st *tenantState) { st.minuteCount = 2 }, wantErr: ErrRateLimited},
+		{name: "under rate limit", limits: Limits{RequestsPerMinute: 2}, setup: func(st *tenantState) { st.minuteCount = 1 }},
+		{name: "concurrent streams", limits: Limits{MaxConcurrentStreams: 1}, setup: func(st *tenantState) { st.usage.ActiveStreams = 1 }, wantErr: ErrConcurrentStreams},
+		{name: "daily bytes used up", limits: Limits{MaxBytesPerDay: 100}, setup: func(st *tenantState) { st.usage.BytesToday = 100 }, wantErr: ErrDailyBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.limits)
+			st := m.state("acme", time.Now())
+			// 테스트 도중 분이 바뀌어도 같은 구간에 남도록 구간 시작을 현재 시각으로 둠
+			st.minute = time.Now()
+			if tt.setup != nil {
+				tt.setup(st)
+			}
+			before := st.minuteCount
+
+			lease, err := m.Acquire("acme")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Acquire = %v, want %v", err, tt.wantErr)
+			}
+			// 거절된 요청도 분당 요청 수에 포함
+			if st.minuteCount != before+1 {
+				t.Fatalf("requests in window = %d, want %d", st.minuteCount, before+1)
+			}
+			if err != nil {
+				if st.usage.Rejected != 1 {
+					t.Fatalf("rejected = %d, want 1", st.usage.Rejected)
+				}
+				return
+			}
+			lease.Release()
+			lease.Release()
+			if st.usage.ActiveStreams != 0 {
+				t.Fatalf("active streams after release = %d, want 0", st.usage.ActiveStreams)
+			}
+		})
+	}
+}
+
+func TestLeaseAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		chunks  []int
+		wantErr error
+	}{
+		{name: "within limits", limits: Limits{MaxUploadBytes: 100, MaxBytesPerDay: 100}, chunks: []int{50, 50}},
+		{name: "upload too large", limits: Limits{MaxUploadBytes: 100}, chunks: []int{60, 60}, wantErr: ErrUploadTooLarge},
+		{name: "daily quota exceeded mid-upload", limits: Limits{MaxBytesPerDay: 100}, chunks: []int{60, 60}, wantErr: ErrDailyBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.limits)
+			lease, err := m.Acquire("acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lease.Release()
+			for _, n := range tt.chunks {
+				if err = lease.Add(n); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
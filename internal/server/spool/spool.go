@@ -0,0 +1,457 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrTooLarge는 업로드가 spool 크기 제한을 넘을 때 반환됩니다.
+var ErrTooLarge = errors.New("upload exceeds spool size limit")
+
+// ErrSpoolFull은 spool 디렉토리 전체 용량이 부족할 때 반환됩니다.
+var ErrSpoolFull = errors.New("spool is full")
+
+const (
+	dataExt = ".data"
+	metaExt = ".json"
+	// deadDir는 internal 서버가 거절하여 다시 전달하지 않는 업로드를 옮겨 두는 하위 디렉토리입니다.
+	deadDir = "dead"
+)
+
+// Config는 spool 설정입니다.
+type Config struct {
+	Dir              string
+	MaxUploadBytes   int64 // 업로드 1건의 최대 크기 (0: 제한 없음)
+	MaxTotalBytes    int64 // spool 전체 최대 크기 (0: 제한 없음)
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+// ConfigFromEnv는 환경변수에서 spool 설정을 읽습니다. SPOOL_DIR이 비어 있으면 ok=false입니다.
+//
+//	SPOOL_DIR                 spool 파일 저장 위치
+//	SPOOL_MAX_UPLOAD_BYTES    업로드 1건의 최대 크기
+//	SPOOL_MAX_TOTAL_BYTES     spool 전체 최대 크기
+//	SPOOL_RETRY_INTERVAL      첫 재전송 대기 시간 (기본 5s, 실패할 때마다 2배)
+//	SPOOL_MAX_RETRY_INTERVAL  재전송 대기 시간 상한 (기본 5m)
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		Dir:              os.Getenv("SPOOL_DIR"),
+		MaxUploadBytes:   env.Int64("SPOOL_MAX_UPLOAD_BYTES", 2<<30),
+		MaxTotalBytes:    env.Int64("SPOOL_MAX_TOTAL_BYTES", 10<<30),
+		RetryInterval:    env.Duration("SPOOL_RETRY_INTERVAL", 5*time.Second),
+		MaxRetryInterval: env.Duration("SPOOL_MAX_RETRY_INTERVAL", 5*time.Minute),
+	}
+	return cfg, cfg.Dir != ""
+}
+
+// Entry는 spool된 업로드 하나의 메타데이터입니다. 파일로 저장되어 재시작 후에도 유지됩니다.
+type Entry struct {
+	JobID       string            `json:"job_id"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Size        int64             `json:"size"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"next_attempt"`
+	LastError   string            `json:"last_error,omitempty"`
+	// TraceContext는 원래 업로드의 trace context(W3C traceparent 등)로, 전달 시 같은 trace로 이어집니다.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	// RequestID는 원래 업로드의 요청 ID로, 전달 시 같은 ID로 로그를 남깁니다.
+	RequestID string `json:"request_id,omitempty"`
+	// Tenant, CallbackURL은 전달 시 internal 서버로 다시 넘겨 작업 이벤트에 사용됩니다.
+	Tenant      string `json:"tenant,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// ForwardFunc는 spool된 업로드를 internal 백엔드로 전달합니다.
+// 일시적인 오류(Retryable)를 반환하면 backoff 후 다시 시도하고, 그 외 오류는 dead 디렉토리로 옮깁니다.
+type ForwardFunc func(ctx context.Context, entry Entry, data io.Reader) error
+
+// Spool은 transcoder를 사용할 수 없을 때 업로드를 디스크에 저장했다가 나중에 전달합니다.
+type Spool struct {
+	cfg     Config
+	forward ForwardFunc
+
+	mu       sync.Mutex
+	entries  map[string]*Entry
+	reserved int64 // 저장 완료 + 저장 중인 바이트 수
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New는 spool 디렉토리를 준비하고, 이전 실행에서 남은 업로드를 다시 불러옵니다.
+func New(cfg Config, forward ForwardFunc) (*Spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %v", err)
+	}
+
+	s := &Spool{
+		cfg:     cfg,
+		forward: forward,
+		entries: make(map[string]*Entry),
+		wake:    make(chan struct{}, 1),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) load() error {
+	metas, err := filepath.Glob(filepath.Join(s.cfg.Dir, "*"+metaExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range metas {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read spool entry: %v", err)
+		}
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			slog.Warn("skipping corrupt spool entry", "path", path, "error", err)
+			continue
+		}
+		s.entries[e.JobID] = &e
+		s.reserved += e.Size
+	}
+
+	// 메타데이터 없이 남은 데이터 파일은 커밋 전에 중단된 업로드
+	datas, _ := filepath.Glob(filepath.Join(s.cfg.Dir, "*"+dataExt))
+	for _, path := range datas {
+		id := strings.TrimSuffix(filepath.Base(path), dataExt)
+		if _, ok := s.entries[id]; !ok {
+			os.Remove(path)
+		}
+	}
+
+	if len(s.entries) > 0 {
+		slog.Info("recovered spooled uploads", "count", len(s.entries), "bytes", s.reserved)
+	}
+	return nil
+}
+
+// Start는 spool된 업로드를 전달하는 백그라운드 루프를 시작합니다.
+func (s *Spool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.RetryInterval)
+		defer ticker.Stop()
+		for {
+			s.flush(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Stop은 전달 루프를 멈춥니다. 남은 업로드는 디스크에 유지됩니다.
+func (s *Spool) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// Pending은 전달 대기 중인 업로드 목록을 반환합니다.
+func (s *Spool) Pending() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+func (s *Spool) flush(ctx context.Context) {
+	now := time.Now()
+	for _, e := range s.Pending() {
+		if ctx.Err() != nil {
+			return
+		}
+		if e.NextAttempt.After(now) {
+			continue
+		}
+		s.deliver(ctx, e)
+	}
+}
+
+func (s *Spool) deliver(ctx context.Context, e Entry) {
+	f, err := os.Open(s.dataPath(e.JobID))
+	if err != nil {
+		slog.Error("dropping spooled upload", "job_id", e.JobID, "error", err)
+		s.remove(e.JobID)
+		return
+	}
+	err = s.forward(ctx, e, f)
+	f.Close()
+
+	if err == nil {
+		slog.Info("forwarded spooled upload", "job_id", e.JobID, "bytes", e.Size, "attempts", e.Attempts+1)
+		s.remove(e.JobID)
+		return
+	}
+	// 종료 중 취소된 전달은 시도 횟수에 포함하지 않고 다음 실행에서 다시 전달
+	if ctx.Err() != nil {
+		return
+	}
+	if !Retryable(err) {
+		slog.Error("internal server rejected spooled upload, moving to dead-letter", "job_id", e.JobID,
+			"attempts", e.Attempts+1, "code", status.Code(err).String(), "error", err)
+		s.bury(e, err)
+		return
+	}
+
+	s.mu.Lock()
+	cur, ok := s.entries[e.JobID]
+	if ok {
+		cur.Attempts++
+		cur.LastError = err.Error()
+		cur.NextAttempt = time.Now().Add(s.backoff(cur.Attempts))
+		e = *cur
+	}
+	s.mu.Unlock()
+	if ok {
+		slog.Warn("failed to forward spooled upload", "job_id", e.JobID, "attempt", e.Attempts,
+			"next_attempt", e.NextAttempt.Format(time.RFC3339), "error", err)
+		if err := s.writeMeta(e); err != nil {
+			slog.Error("failed to persist spool entry", "job_id", e.JobID, "error", err)
+		}
+	}
+}
+
+// Retryable은 전달 오류를 다시 시도할지 판단합니다.
+// gRPC 상태가 아닌 오류(건강한 백엔드 없음, spool 파일 읽기 실패 등)와 Unavailable, ResourceExhausted,
+// Aborted, DeadlineExceeded는 일시적인 오류로 보고, internal 서버가 업로드를 거절한 상태
+// (InvalidArgument, FailedPrecondition, PermissionDenied 등)는 다시 보내도 같은 결과이므로 재시도하지 않습니다.
+func Retryable(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func (s *Spool) backoff(attempts int) time.Duration {
+	d := s.cfg.RetryInterval
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= s.cfg.MaxRetryInterval {
+			return s.cfg.MaxRetryInterval
+		}
+	}
+	return d
+}
+
+func (s *Spool) remove(jobID string) {
+	s.mu.Lock()
+	if e, ok := s.entries[jobID]; ok {
+		s.reserved -= e.Size
+		delete(s.entries, jobID)
+	}
+	s.mu.Unlock()
+
+	os.Remove(s.dataPath(jobID))
+	os.Remove(s.metaPath(jobID))
+}
+
+// bury는 다시 전달하지 않을 업로드를 마지막 오류와 함께 dead 디렉토리로 옮깁니다.
+// 옮기지 못하면 전달 대기열이 막히지 않도록 삭제합니다.
+func (s *Spool) bury(e Entry, cause error) {
+	e.Attempts++
+	e.LastError = cause.Error()
+	dir := filepath.Join(s.cfg.Dir, deadDir)
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		err = os.Rename(s.dataPath(e.JobID), filepath.Join(dir, e.JobID+dataExt))
+	}
+	if err == nil {
+		var raw []byte
+		if raw, err = json.MarshalIndent(e, "", "  "); err == nil {
+			err = os.WriteFile(filepath.Join(dir, e.JobID+metaExt), raw, 0644)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to dead-letter spooled upload, dropping", "job_id", e.JobID, "error", err)
+	}
+	s.remove(e.JobID)
+}
+
+func (s *Spool) dataPath(jobID string) string {
+	return filepath.Join(s.cfg.Dir, jobID+dataExt)
+}
+
+func (s *Spool) metaPath(jobID string) string {
+	return filepath.Join(s.cfg.Dir, jobID+metaExt)
+}
+
+func (s *Spool) writeMeta(e Entry) error {
+	raw, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 원자적으로 교체하여 재시작 시 깨진 메타데이터를 읽지 않도록 함
+	tmp := s.metaPath(e.JobID) + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.metaPath(e.JobID))
+}
+
+// Writer는 spool 중인 업로드 하나입니다.
+type Writer struct {
+	spool   *Spool
+	file    *os.File
+	entry   Entry
+	written int64
+	done    bool
+}
+
+// Create는 새 업로드를 spool하기 위한 Writer를 만듭니다.
+func (s *Spool) Create(jobID string) (*Writer, error) {
+	f, err := os.Create(s.dataPath(jobID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %v", err)
+	}
+	return &Writer{
+		spool: s,
+		file:  f,
+		entry: Entry{
+			JobID:     jobID,
+			CreatedAt: time.Now(),
+		},
+	}, nil
+}
+
+// Write는 업로드 크기 제한과 spool 전체 용량을 확인한 후 데이터를 기록합니다.
+func (w *Writer) Write(p []byte) (int, error) {
+	n := int64(len(p))
+	if max := w.spool.cfg.MaxUploadBytes; max > 0 && w.written+n > max {
+		return 0, ErrTooLarge
+	}
+
+	s := w.spool
+	s.mu.Lock()
+	if s.cfg.MaxTotalBytes > 0 && s.reserved+n > s.cfg.MaxTotalBytes {
+		s.mu.Unlock()
+		return 0, ErrSpoolFull
+	}
+	s.reserved += n
+	s.mu.Unlock()
+
+	written, err := w.file.Write(p)
+	w.written += int64(written)
+	if int64(written) < n {
+		s.mu.Lock()
+		s.reserved -= n - int64(written)
+		s.mu.Unlock()
+	}
+	return written, err
+}
+
+// SetMeta는 첫 청크에서 알게 된 content type과 헤더를 기록합니다.
+func (w *Writer) SetMeta(contentType string, headers map[string]string) {
+	w.entry.ContentType = contentType
+	w.entry.Headers = headers
+}
+
+// SetTraceContext는 업로드 요청의 trace context를 기록합니다.
+func (w *Writer) SetTraceContext(carrier map[string]string) {
+	w.entry.TraceContext = carrier
+}
+
+// SetRequestID는 업로드 요청의 요청 ID를 기록합니다.
+func (w *Writer) SetRequestID(id string) {
+	w.entry.RequestID = id
+}
+
+// SetOwner는 업로드한 tenant와 작업 이벤트를 받을 callback URL을 기록합니다.
+func (w *Writer) SetOwner(tenant, callbackURL string) {
+	w.entry.Tenant = tenant
+	w.entry.CallbackURL = callbackURL
+}
+
+// Commit은 파일을 닫고 업로드를 전달 대기열에 추가합니다.
+func (w *Writer) Commit() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	s := w.spool
+	if err := w.file.Sync(); err != nil {
+		w.discard()
+		return fmt.Errorf("failed to sync spool file: %v", err)
+	}
+	if err := w.file.Close(); err != nil {
+		w.discard()
+		return fmt.Errorf("failed to close spool file: %v", err)
+	}
+
+	w.entry.Size = w.written
+	if err := s.writeMeta(w.entry); err != nil {
+		w.discard()
+		return fmt.Errorf("failed to write spool metadata: %v", err)
+	}
+
+	s.mu.Lock()
+	entry := w.entry
+	s.entries[entry.JobID] = &entry
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Abort는 업로드를 취소하고 spool 파일을 삭제합니다.
+func (w *Writer) Abort() {
+	if w.done {
+		return
+	}
+	w.done = true
+	w.file.Close()
+	w.discard()
+}
+
+func (w *Writer) discard() {
+	s := w.spool
+	s.mu.Lock()
+	s.reserved -= w.written
+	s.mu.Unlock()
+	os.Remove(s.dataPath(w.entry.JobID))
+}
@@ -0,0 +1,179 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	s := &Spool{cfg: Config{RetryInterval: 5 * time.Second, MaxRetryInterval: time.Minute}}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 5 * time.Second},
+		{attempts: 2, want: 10 * time.Second},
+		{attempts: 3, want: 20 * time.Second},
+		{attempts: 4, want: 40 * time.Second},
+		{attempts: 5, want: time.Minute},
+		{attempts: 50, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "no healthy backend", err: errors.New("no healthy internal backend"), want: true},
+		{name: "unavailable", err: status.Error(codes.Unavailable, "connection refused"), want: true},
+		{name: "resource exhausted", err: status.Error(codes.ResourceExhausted, "busy"), want: true},
+		{name: "aborted", err: status.Error(codes.Aborted, "draining"), want: true},
+		{name: "deadline exceeded", err: status.Error(codes.DeadlineExceeded, "timeout"), want: true},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "not a video"), want: false},
+		{name: "failed precondition", err: status.Error(codes.FailedPrecondition, "no video stream"), want: false},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "tenant disabled"), want: false},
+		{name: "internal", err: status.Error(codes.Internal, "bug"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Fatalf("Retryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestSpool은 forward 결과를 바꿀 수 있는 spool과 커밋된 업로드 하나를 만듭니다.
+func newTestSpool(t *testing.T, forward ForwardFunc) (*Spool, Entry) {
+	t.Helper()
+	s, err := New(Config{Dir: t.TempDir(), RetryInterval: time.Second, MaxRetryInterval: time.Minute}, forward)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.Create("stream_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("video data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return s, s.Pending()[0]
+}
+
+func readEntry(t *testing.T, path string) Entry {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e Entry
+	if err := json.Unmarshal(raw, &e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestDeliverAttempts(t *testing.T) {
+	forwardErr := status.Error(codes.Unavailable, "connection refused")
+	s, e := newTestSpool(t, func(ctx context.Context, entry Entry, data io.Reader) error {
+		return forwardErr
+	})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		s.deliver(context.Background(), s.Pending()[0])
+
+		pending := s.Pending()
+		if len(pending) != 1 {
+			t.Fatalf("attempt %d: %d pending uploads, want 1", attempt, len(pending))
+		}
+		got := pending[0]
+		if got.Attempts != attempt {
+			t.Fatalf("Attempts = %d, want %d", got.Attempts, attempt)
+		}
+		if got.LastError != forwardErr.Error() {
+			t.Fatalf("LastError = %q, want %q", got.LastError, forwardErr.Error())
+		}
+		if wait := got.NextAttempt.Sub(before); wait < s.backoff(attempt) {
+			t.Fatalf("attempt %d: next attempt in %v, want at least %v", attempt, wait, s.backoff(attempt))
+		}
+		// 재시작 후에도 시도 횟수가 유지되도록 메타데이터에 기록
+		if persisted := readEntry(t, s.metaPath(e.JobID)); persisted.Attempts != attempt {
+			t.Fatalf("persisted Attempts = %d, want %d", persisted.Attempts, attempt)
+		}
+	}
+}
+
+func TestDeliverResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		cancel       bool
+		wantPending  bool
+		wantAttempts int
+		wantDead     bool
+	}{
+		{name: "forwarded", err: nil},
+		{name: "transient failure", err: status.Error(codes.Unavailable, "connection refused"), wantPending: true, wantAttempts: 1},
+		{name: "rejected by internal server", err: status.Error(codes.InvalidArgument, "not a video"), wantDead: true},
+		{name: "cancelled by shutdown", err: status.Error(codes.Canceled, "context canceled"), cancel: true, wantPending: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, e := newTestSpool(t, func(ctx context.Context, entry Entry, data io.Reader) error {
+				return tt.err
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			s.deliver(ctx, e)
+
+			pending := s.Pending()
+			if (len(pending) == 1) != tt.wantPending {
+				t.Fatalf("pending = %v, want pending %t", pending, tt.wantPending)
+			}
+			if tt.wantPending && pending[0].Attempts != tt.wantAttempts {
+				t.Fatalf("Attempts = %d, want %d", pending[0].Attempts, tt.wantAttempts)
+			}
+			if _, err := os.Stat(s.dataPath(e.JobID)); (err == nil) != tt.wantPending {
+				t.Fatalf("spool data file exists = %t, want %t", err == nil, tt.wantPending)
+			}
+
+			deadMeta := filepath.Join(s.cfg.Dir, deadDir, e.JobID+metaExt)
+			_, err := os.Stat(deadMeta)
+			if (err == nil) != tt.wantDead {
+				t.Fatalf("dead-letter exists = %t, want %t", err == nil, tt.wantDead)
+			}
+			if tt.wantDead {
+				if dead := readEntry(t, deadMeta); dead.Attempts != 1 || dead.LastError != tt.err.Error() {
+					t.Fatalf("dead-letter entry = %+v", dead)
+				}
+				if _, err := os.Stat(filepath.Join(s.cfg.Dir, deadDir, e.JobID+dataExt)); err != nil {
+					t.Fatalf("dead-letter data missing: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+)
+
+// ServerFromEnv는 prefix로 시작하는 환경변수에서 서버 TLS 설정을 만듭니다.
+// <prefix>CERT_FILE이 없으면 nil을 반환합니다. (평문)
+//
+//	<prefix>CERT_FILE            서버 인증서
+//	<prefix>KEY_FILE             서버 개인키
+//	<prefix>CLIENT_CA_FILE       클라이언트 인증서 검증용 CA (선택)
+//	<prefix>REQUIRE_CLIENT_CERT  "true"이면 클라이언트 인증서 필수
+func ServerFromEnv(prefix string) (*tls.Config, error) {
+	files := Files{
+		CertFile: os.Getenv(prefix + "CERT_FILE"),
+		KeyFile:  os.Getenv(prefix + "KEY_FILE"),
+		CAFile:   os.Getenv(prefix + "CLIENT_CA_FILE"),
+	}
+	if files.CertFile == "" {
+		return nil, nil
+	}
+	store, err := NewStore(files)
+	if err != nil {
+		return nil, err
+	}
+	return store.ServerConfig(os.Getenv(prefix+"REQUIRE_CLIENT_CERT") == "true"), nil
+}
+
+// ClientFromEnv는 prefix로 시작하는 환경변수에서 클라이언트 TLS 설정을 만듭니다.
+// <prefix>ENABLED=true 또는 <prefix>CA_FILE이 없으면 nil을 반환합니다. (평문)
+//
+//	<prefix>ENABLED      "true"이면 시스템 CA로 TLS 연결
+//	<prefix>CA_FILE      서버 인증서 검증용 CA
+//	<prefix>CERT_FILE    클라이언트 인증서 (mTLS, 선택)
+//	<prefix>KEY_FILE     클라이언트 개인키 (mTLS, 선택)
+//	<prefix>SERVER_NAME  인증서 검증에 사용할 서버 이름 (선택, 없으면 접속 주소의 호스트 이름)
+func ClientFromEnv(prefix string) (*tls.Config, error) {
+	files := Files{
+		CertFile: os.Getenv(prefix + "CERT_FILE"),
+		KeyFile:  os.Getenv(prefix + "KEY_FILE"),
+		CAFile:   os.Getenv(prefix + "CA_FILE"),
+	}
+	if os.Getenv(prefix+"ENABLED") != "true" && files.CAFile == "" {
+		return nil, nil
+	}
+	store, err := NewStore(files)
+	if err != nil {
+		return nil, err
+	}
+	return store.ClientConfig(os.Getenv(prefix + "SERVER_NAME")), nil
+}
@@ -0,0 +1,205 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadInterval은 인증서 파일 변경을 확인하는 최소 간격입니다.
+// 핸드셰이크 시점에 확인하므로 별도 goroutine 없이 교체된 인증서가 반영됩니다.
+const reloadInterval = 10 * time.Second
+
+// Files는 TLS에 사용할 파일 경로입니다.
+type Files struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string // 서버: 클라이언트 인증서 검증용 CA, 클라이언트: 서버 인증서 검증용 CA
+}
+
+// Store는 인증서와 CA를 보관하고 파일이 바뀌면 다시 읽습니다. (cert-manager 등의 인증서 갱신 대응)
+type Store struct {
+	files Files
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// NewStore는 files를 읽어 Store를 만듭니다. 비어 있는 경로는 사용하지 않습니다.
+func NewStore(files Files) (*Store, error) {
+	if (files.CertFile == "") != (files.KeyFile == "") {
+		return nil, errors.New("certificate and key files must be set together")
+	}
+	s := &Store{files: files}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	var cert *tls.Certificate
+	if s.files.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(s.files.CertFile, s.files.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %v", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if s.files.CAFile != "" {
+		p, err := loadCertPool(s.files.CAFile)
+		if err != nil {
+			return err
+		}
+		pool = p
+	}
+
+	s.mu.Lock()
+	s.cert = cert
+	s.pool = pool
+	s.modTimes = s.currentModTimes()
+	s.lastCheck = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Store) currentModTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, path := range []string{s.files.CertFile, s.files.KeyFile, s.files.CAFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// reloadIfChanged는 reloadInterval마다 파일 수정 시각을 확인하고 바뀌었으면 다시 읽습니다.
+// 읽기에 실패하면(인증서와 키가 교체되는 도중 등) 기존 값을 계속 사용합니다.
+func (s *Store) reloadIfChanged() {
+	s.mu.Lock()
+	if time.Since(s.lastCheck) < reloadInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCheck = time.Now()
+	changed := false
+	for path, t := range s.currentModTimes() {
+		if !t.Equal(s.modTimes[path]) {
+			changed = true
+		}
+	}
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := s.load(); err != nil {
+		slog.Warn("failed to reload TLS files, keeping previous", "cert", s.files.CertFile, "ca", s.files.CAFile, "error", err)
+		return
+	}
+	slog.Info("reloaded TLS files", "cert", s.files.CertFile, "ca", s.files.CAFile)
+}
+
+func (s *Store) certificate() (*tls.Certificate, *x509.CertPool) {
+	s.reloadIfChanged()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, s.pool
+}
+
+// ServerConfig는 서버용 TLS 설정을 반환합니다.
+// CA 파일이 있으면 클라이언트 인증서를 요청하고 검증합니다.
+// requireClientCert가 false이면 인증서 없는 클라이언트도 허용합니다. (API 키, JWT 인증과 함께 사용)
+func (s *Store) ServerConfig(requireClientCert bool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// 핸드셰이크마다 최신 인증서와 CA로 설정을 구성
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := s.certificate()
+			if cert == nil {
+				return nil, errors.New("no server certificate")
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+			if pool != nil {
+				cfg.ClientCAs = pool
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				if requireClientCert {
+					cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig는 클라이언트용 TLS 설정을 반환합니다.
+// CA 파일이 없으면 시스템 CA를 사용하고, 인증서가 있으면 클라이언트 인증서(mTLS)로 보냅니다.
+// serverName이 있으면 주소 대신 해당 이름으로 서버 인증서를 검증합니다. (DNS 탐색으로 pod IP에 연결하는 경우)
+// 검증할 이름이 없으면 연결을 거부합니다.
+func (s *Store) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := s.certificate()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+		// RootCAs는 고정 값이므로 CA 교체를 반영하기 위해 직접 검증
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool := s.certificate()
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			// 설정한 이름을 우선 사용하고, 없으면 dial 주소에서 정해진 이름을 사용
+			name := serverName
+			if name == "" {
+				name = cs.ServerName
+			}
+			if name == "" {
+				return errors.New("no server name to verify the certificate against")
+			}
+			opts := x509.VerifyOptions{
+				Roots:         pool, // nil이면 시스템 CA
+				DNSName:       name,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, errors.New("no certificates found in CA file " + path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const instrumentationName = "github.com/ket0825/grpc-streaming"
+
+// Setup은 전역 TracerProvider와 W3C trace context propagator를 설정합니다.
+//
+// OTEL_EXPORTER_OTLP_ENDPOINT(또는 OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)가 설정되어 있으면
+// OTLP/gRPC로 span을 내보냅니다. (예: http://otel-collector:4317, OTEL_EXPORTER_OTLP_INSECURE=true)
+// 설정되어 있지 않으면 span은 기록하지 않고 trace context 전파만 합니다.
+// 반환된 함수는 종료 시 남은 span을 flush합니다.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	slog.Info("tracing enabled", "service", serviceName)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer는 이 프로젝트의 span을 만드는 tracer를 반환합니다.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start는 새 span을 시작합니다.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End는 err를 span 상태에 반영한 뒤 span을 종료합니다.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID는 ctx의 trace ID를 반환합니다. 로그에서 여러 서비스의 기록을 연결하는 데 사용합니다.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// health check는 span을 만들지 않습니다.
+// message event는 WithMessageEvents로 켜지 않는 한 기록되지 않으므로 (청크마다 기록하면 span이 너무 커짐) 켜지 않습니다.
+func handlerOptions() []otelgrpc.Option {
+	return []otelgrpc.Option{
+		otelgrpc.WithFilter(filters.Not(filters.HealthCheck())),
+	}
+}
+
+// ServerOption은 들어오는 RPC의 trace context를 추출하고 server span을 만드는 grpc.ServerOption을 반환합니다.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler(handlerOptions()...))
+}
+
+// DialOption은 나가는 RPC에 trace context를 주입하는 grpc.DialOption을 반환합니다.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler(handlerOptions()...))
+}
+
+// Inject는 ctx의 trace context를 map으로 직렬화합니다. (spool 등 비동기 처리에 전달할 때 사용)
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract는 Inject로 저장한 trace context를 ctx에 복원합니다.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
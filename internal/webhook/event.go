@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// 이벤트 종류
+const (
+	JobAccepted        = "job.accepted"        // 업로드 수신 완료, 변환 대기
+	RenditionCompleted = "rendition.completed" // 화질 하나 변환 완료
+	JobCompleted       = "job.completed"       // 변환 종료 (일부 화질만 성공한 경우 포함)
+	JobFailed          = "job.failed"          // 모든 화질 실패 또는 취소
+	LiveStarted        = "live.started"        // live 업로드 HLS 변환 시작
+	LiveEnded          = "live.ended"          // live 업로드 종료, VOD 재생목록 완성
+)
+
+// gRPC metadata 키
+const (
+	// CallbackURLHeader는 업로드별 callback URL을 지정하는 metadata 키입니다. 없으면 WEBHOOK_URL을 사용합니다.
+	CallbackURLHeader = "x-callback-url"
+	// JobIDHeader는 gateway가 응답한 job ID를 internal 서버로 전달하는 metadata 키입니다.
+	JobIDHeader = "x-job-id"
+)
+
+// Event는 callback URL로 POST되는 JSON 본문입니다.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	JobID     string    `json:"job_id"`     // gateway job ID (없으면 session ID)
+	SessionID string    `json:"session_id"` // internal 서버의 작업 ID
+	RequestID string    `json:"request_id,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data,omitempty"`
+}
+
+func newEventID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
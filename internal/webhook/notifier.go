@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ket0825/grpc-streaming/internal/env"
+)
+
+// ErrURLNotAllowed는 callback URL이 http(s)가 아니거나 허용된 호스트가 아닐 때 반환됩니다.
+var ErrURLNotAllowed = errors.New("callback URL not allowed")
+
+// errAddressNotAllowed는 업로드별 callback이 loopback, 사설, link-local 주소로 연결하려 할 때 반환됩니다.
+var errAddressNotAllowed = errors.New("callback address not allowed")
+
+// 전달 결과 (ResultFunc의 result 값)
+const (
+	ResultDelivered  = "delivered"
+	ResultRetry      = "retry"
+	ResultDeadLetter = "dead_letter"
+)
+
+// ResultFunc는 전달 시도마다 호출됩니다. 메트릭 기록에 사용합니다.
+type ResultFunc func(eventType, result string)
+
+// Config는 webhook 설정입니다.
+type Config struct {
+	URL              string   // 업로드에 callback URL이 없을 때 사용하는 전역 URL
+	Secret           []byte   // 비어 있으면 서명하지 않음
+	AllowedHosts     []string // 비어 있으면 업로드별 callback URL을 받지 않음
+	AllowPrivate     bool     // 업로드별 callback이 loopback, 사설, link-local 주소로 연결하는 것을 허용
+	Timeout          time.Duration
+	MaxAttempts      int
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	DeadLetterFile   string // 재시도를 모두 실패한 이벤트를 JSON lines로 기록
+	Workers          int
+	QueueSize        int
+}
+
+// ConfigFromEnv는 환경변수에서 webhook 설정을 읽습니다.
+//
+//	WEBHOOK_URL                 전역 callback URL (업로드별 x-callback-url metadata가 우선)
+//	WEBHOOK_SECRET              HMAC-SHA256 서명 키 (WEBHOOK_SECRET_FILE로 파일에서 읽을 수도 있음)
+//	WEBHOOK_ALLOWED_HOSTS       업로드별 callback URL에 허용할 호스트 목록 (쉼표 구분, 비우면 업로드별 URL 거부)
+//	WEBHOOK_ALLOW_PRIVATE       "true"이면 업로드별 callback이 사설/loopback 주소로 연결하는 것을 허용 (로컬 개발용)
+//	WEBHOOK_TIMEOUT             요청 1회 timeout (기본 10s)
+//	WEBHOOK_MAX_ATTEMPTS        최대 시도 횟수 (기본 5)
+//	WEBHOOK_RETRY_INTERVAL      첫 재시도 대기 시간 (기본 2s, 실패할 때마다 2배)
+//	WEBHOOK_MAX_RETRY_INTERVAL  재시도 대기 시간 상한 (기본 1m)
+//	WEBHOOK_DEAD_LETTER_FILE    전달 실패 이벤트 기록 파일
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		URL:              os.Getenv("WEBHOOK_URL"),
+		Secret:           []byte(os.Getenv("WEBHOOK_SECRET")),
+		Timeout:          env.Duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:      env.Int("WEBHOOK_MAX_ATTEMPTS", 5),
+		RetryInterval:    env.Duration("WEBHOOK_RETRY_INTERVAL", 2*time.Second),
+		MaxRetryInterval: env.Duration("WEBHOOK_MAX_RETRY_INTERVAL", time.Minute),
+		DeadLetterFile:   os.Getenv("WEBHOOK_DEAD_LETTER_FILE"),
+		AllowPrivate:     os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true",
+		Workers:          4,
+		QueueSize:        1000,
+	}
+	if path := os.Getenv("WEBHOOK_SECRET_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read webhook secret: %v", err)
+		}
+		cfg.Secret = bytes.TrimSpace(raw)
+	}
+	for _, host := range strings.Split(os.Getenv("WEBHOOK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.AllowedHosts = append(cfg.AllowedHosts, strings.ToLower(host))
+		}
+	}
+	if cfg.URL != "" {
+		if _, err := parseURL(cfg.URL); err != nil {
+			return cfg, fmt.Errorf("invalid WEBHOOK_URL: %v", err)
+		}
+	}
+	return cfg, nil
+}
+
+// CheckURL은 업로드별 callback URL이 http(s)이고 허용된 호스트인지 확인합니다.
+// 호출자가 보낸 URL이므로 WEBHOOK_ALLOWED_HOSTS가 비어 있으면 거부합니다. (운영자가 정한 WEBHOOK_URL은 검사하지 않음)
+func (c Config) CheckURL(raw string) error {
+	u, err := parseURL(raw)
+	if err != nil {
+		return err
+	}
+	if len(c.AllowedHosts) == 0 {
+		return fmt.Errorf("%w: per-upload callback URLs are disabled (WEBHOOK_ALLOWED_HOSTS is empty)", ErrURLNotAllowed)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range c.AllowedHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %q", ErrURLNotAllowed, host)
+}
+
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrURLNotAllowed, raw)
+	}
+	return u, nil
+}
+
+// callbackClient는 업로드별 callback URL 전송용 HTTP 클라이언트입니다.
+// 허용된 호스트라도 DNS가 내부 주소를 가리킬 수 있으므로(DNS rebinding) 실제 연결 주소를 검사합니다.
+func callbackClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// Control은 이름 해석이 끝난 실제 연결 주소로 호출됨
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addr) {
+				return fmt.Errorf("%w: %s", errAddressNotAllowed, addr)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// 프록시를 거치면 연결 주소 검사가 프록시에만 적용되므로 사용하지 않음
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// publicAddr는 loopback, 사설, link-local, 미지정 주소가 아닌지 반환합니다.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !(addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsUnspecified())
+}
+
+type delivery struct {
+	url      string
+	callback bool // 업로드별 callback URL (주소 제한 적용)
+	event    Event
+}
+
+// deadLetter는 dead-letter 파일에 기록되는 한 줄입니다.
+type deadLetter struct {
+	URL      string    `json:"url"`
+	Event    Event     `json:"event"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Notifier는 이벤트를 비동기로 서명하여 POST하고, 실패하면 backoff 후 재시도합니다.
+// 이벤트는 여러 worker가 전달하므로 순서가 보장되지 않습니다. 수신 측은 time과 type으로 판단해야 합니다.
+// nil Notifier의 메서드는 아무 동작도 하지 않습니다.
+type Notifier struct {
+	cfg      Config
+	client   *http.Client // 전역 WEBHOOK_URL용
+	callback *http.Client // 업로드별 callback URL용
+	onResult ResultFunc
+
+	mu      sync.Mutex
+	closed  bool
+	queue   chan delivery
+	dlqMu   sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// New는 Notifier를 만듭니다. onResult는 nil이어도 됩니다.
+func New(cfg Config, onResult ResultFunc) *Notifier {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if onResult == nil {
+		onResult = func(string, string) {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	callback := callbackClient(cfg.Timeout)
+	if cfg.AllowPrivate {
+		callback = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &Notifier{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		callback: callback,
+		onResult: onResult,
+		queue:    make(chan delivery, cfg.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// CheckURL은 업로드별 callback URL을 사용할 수 있는지 확인합니다.
+func (n *Notifier) CheckURL(raw string) error {
+	if n == nil {
+		return nil
+	}
+	return n.cfg.CheckURL(raw)
+}
+
+// Notify는 이벤트를 전달 큐에 넣습니다. callbackURL이 비어 있으면 전역 URL로 보내고, 둘 다 없으면 버립니다.
+func (n *Notifier) Notify(callbackURL string, event Event) {
+	if n == nil {
+		return
+	}
+	d := delivery{url: callbackURL, callback: callbackURL != "", event: event}
+	if d.url == "" {
+		d.url = n.cfg.URL
+	}
+	if d.url == "" {
+		return
+	}
+	if d.event.ID == "" {
+		d.event.ID = newEventID()
+	}
+	if d.event.Time.IsZero() {
+		d.event.Time = time.Now().UTC()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		n.deadLetter(d, 0, errors.New("notifier stopped"))
+		return
+	}
+	select {
+	case n.queue <- d:
+	default:
+		n.deadLetter(d, 0, errors.New("webhook queue full"))
+	}
+}
+
+// Start는 전달 worker를 시작합니다.
+func (n *Notifier) Start() {
+	if n == nil {
+		return
+	}
+	for i := 0; i < n.cfg.Workers; i++ {
+		n.workers.Add(1)
+		go func() {
+			defer n.workers.Done()
+			for d := range n.queue {
+				n.deliver(d)
+			}
+		}()
+	}
+}
+
+// Stop은 새 이벤트를 받지 않고 큐에 남은 이벤트를 ctx가 끝날 때까지 전달합니다.
+// ctx가 끝나면 남은 재시도를 중단하고 dead-letter 파일에 기록합니다.
+func (n *Notifier) Stop(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n.cancel()
+		<-done
+	}
+	n.cancel()
+}
+
+func (n *Notifier) deliver(d delivery) {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		n.deadLetter(d, 0, err)
+		return
+	}
+	logger := slog.With("event_id", d.event.ID, "event", d.event.Type, "job_id", d.event.JobID, "url", d.url)
+
+	backoff := n.cfg.RetryInterval
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(d, body)
+		if err == nil {
+			n.onResult(d.event.Type, ResultDelivered)
+			logger.Debug("webhook delivered", "attempt", attempt)
+			return
+		}
+		if !retry || attempt >= n.cfg.MaxAttempts || n.ctx.Err() != nil {
+			n.onResult(d.event.Type, ResultDeadLetter)
+			n.deadLetter(d, attempt, err)
+			return
+		}
+		n.onResult(d.event.Type, ResultRetry)
+		logger.Warn("webhook delivery failed, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+		}
+		backoff *= 2
+		if n.cfg.MaxRetryInterval > 0 && backoff > n.cfg.MaxRetryInterval {
+			backoff = n.cfg.MaxRetryInterval
+		}
+	}
+}
+
+// post는 이벤트를 한 번 전송합니다. retry는 다시 시도할 만한 오류인지 여부입니다.
+func (n *Notifier) post(d delivery, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, d.event.ID)
+	req.Header.Set(EventHeader, d.event.Type)
+	if len(n.cfg.Secret) > 0 {
+		ts := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, ts, body))
+	}
+
+	client := n.client
+	if d.callback {
+		client = n.callback
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// 금지된 주소는 다시 시도해도 같은 결과
+		return !errors.Is(err, errAddressNotAllowed), err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("callback returned %s", resp.Status)
+	default:
+		// 그 외 4xx는 다시 보내도 같은 결과이므로 바로 dead-letter로 보냄
+		return false, fmt.Errorf("callback returned %s", resp.Status)
+	}
+}
+
+// deadLetter는 전달하지 못한 이벤트를 로그와 dead-letter 파일에 남깁니다.
+func (n *Notifier) deadLetter(d delivery, attempts int, err error) {
+	slog.Error("webhook delivery failed, giving up", "event_id", d.event.ID, "event", d.event.Type,
+		"job_id", d.event.JobID, "url", d.url, "attempts", attempts, "error", err)
+	if n.cfg.DeadLetterFile == "" {
+		return
+	}
+
+	line, merr := json.Marshal(deadLetter{URL: d.url, Event: d.event, Attempts: attempts, Error: err.Error(), FailedAt: time.Now().UTC()})
+	if merr != nil {
+		return
+	}
+	n.dlqMu.Lock()
+	defer n.dlqMu.Unlock()
+	f, ferr := os.OpenFile(n.cfg.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if ferr != nil {
+		slog.Error("failed to open webhook dead-letter file", "path", n.cfg.DeadLetterFile, "error", ferr)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
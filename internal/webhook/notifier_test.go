@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	allowed := Config{AllowedHosts: []string{"hooks.example.com", ".partner.example"}}
+	tests := []struct {
+		name    string
+		cfg     Config
+		url     string
+		wantErr bool
+	}{
+		{name: "allowed host", cfg: allowed, url: "https://hooks.example.com/video"},
+		{name: "allowed host with port", cfg: allowed, url: "http://hooks.example.com:8080/video"},
+		{name: "host is case-insensitive", cfg: allowed, url: "https://HOOKS.Example.com/video"},
+		{name: "subdomain of allowed suffix", cfg: allowed, url: "https://a.b.partner.example/cb"},
+		{name: "subdomain of exact host", cfg: allowed, url: "https://evil.hooks.example.com/", wantErr: true},
+		{name: "suffix without dot boundary", cfg: allowed, url: "https://evilpartner.example/", wantErr: true},
+		{name: "bare suffix domain", cfg: allowed, url: "https://partner.example/", wantErr: true},
+		{name: "other host", cfg: allowed, url: "https://attacker.test/", wantErr: true},
+		{name: "allowed name in userinfo", cfg: allowed, url: "https://hooks.example.com@attacker.test/", wantErr: true},
+		{name: "non-http scheme", cfg: allowed, url: "file:///etc/passwd", wantErr: true},
+		{name: "missing host", cfg: allowed, url: "https:///video", wantErr: true},
+		{name: "not a url", cfg: allowed, url: "://", wantErr: true},
+		{name: "per-upload callbacks disabled", cfg: Config{}, url: "https://hooks.example.com/video", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.CheckURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckURL(%q) = %v, want error %t", tt.url, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrURLNotAllowed) {
+				t.Fatalf("CheckURL(%q) = %v, want ErrURLNotAllowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.0.0.5"},
+		{addr: "172.16.3.4"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"}, // cloud metadata
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::ffff:127.0.0.1"}, // IPv4-mapped loopback
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 요청 헤더
+const (
+	IDHeader        = "X-Webhook-Id"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp" // unix seconds
+	SignatureHeader = "X-Webhook-Signature" // sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>
+)
+
+// ErrInvalidSignature는 서명이 없거나 맞지 않을 때 반환됩니다.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrExpired는 timestamp가 허용 범위를 벗어났을 때 반환됩니다 (재전송 공격 방지).
+var ErrExpired = errors.New("webhook timestamp outside tolerance")
+
+// Sign은 timestamp와 본문에 대한 서명 헤더 값을 반환합니다.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify는 수신 측에서 서명을 확인합니다. tolerance가 0이면 timestamp를 확인하지 않습니다.
+func Verify(secret []byte, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrExpired
+		}
+	}
+	return nil
+}
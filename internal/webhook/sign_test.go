@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"type":"job.completed"}`)
+	now := time.Now().Unix()
+	ts := strconv.FormatInt(now, 10)
+	sig := Sign(secret, now, body)
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		timestamp string
+		signature string
+		body      []byte
+		tolerance time.Duration
+		wantErr   error
+	}{
+		{name: "valid", secret: secret, timestamp: ts, signature: sig, body: body, tolerance: 5 * time.Minute},
+		{name: "wrong secret", secret: []byte("other"), timestamp: ts, signature: sig, body: body, wantErr: ErrInvalidSignature},
+		{name: "tampered body", secret: secret, timestamp: ts, signature: sig, body: []byte(`{"type":"job.failed"}`), wantErr: ErrInvalidSignature},
+		{name: "timestamp not signed", secret: secret, timestamp: strconv.FormatInt(now+1, 10), signature: sig, body: body, wantErr: ErrInvalidSignature},
+		{name: "missing prefix", secret: secret, timestamp: ts, signature: sig[len("sha256="):], body: body, wantErr: ErrInvalidSignature},
+		{name: "empty signature", secret: secret, timestamp: ts, body: body, wantErr: ErrInvalidSignature},
+		{name: "malformed timestamp", secret: secret, timestamp: "yesterday", signature: sig, body: body, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, tt.tolerance); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte("{}")
+	tests := []struct {
+		name      string
+		age       time.Duration
+		tolerance time.Duration
+		wantErr   error
+	}{
+		{name: "within tolerance", age: time.Minute, tolerance: 5 * time.Minute},
+		{name: "too old", age: 10 * time.Minute, tolerance: 5 * time.Minute, wantErr: ErrExpired},
+		{name: "too far in the future", age: -10 * time.Minute, tolerance: 5 * time.Minute, wantErr: ErrExpired},
+		{name: "tolerance disabled", age: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := time.Now().Add(-tt.age).Unix()
+			err := Verify(secret, strconv.FormatInt(ts, 10), Sign(secret, ts, body), body, tt.tolerance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}